@@ -0,0 +1,49 @@
+package main
+
+import (
+	"arbiter/game"
+	"ayu"
+	"errors"
+	"strconv"
+)
+
+func init() {
+	game.Register("ayu", parseAyuParams)
+}
+
+// Size of the Ayu board as played in the CodeCup.
+const ayuDefaultSize = 11
+
+type AyuGame struct {
+	Size int // board size (number of rows and columns)
+}
+
+// parseAyuParams creates an Ayu game from the board size parameter, e.g.
+// "ayu:9". The size must be odd for the starting position to be symmetric,
+// and at most 25 so that columns can be named by letters.
+func parseAyuParams(params string) (game.Game, error) {
+	if params == "" {
+		return AyuGame{Size: ayuDefaultSize}, nil
+	}
+	size, err := strconv.Atoi(params)
+	if err != nil || size < 5 || size > 25 || size%2 == 0 {
+		return nil, errors.New("invalid board size: " + params)
+	}
+	return AyuGame{Size: size}, nil
+}
+
+// Settings announces the board size to players if it isn't the default.
+func (ag AyuGame) Settings() []game.Setting {
+	if ag.Size == ayuDefaultSize {
+		return nil
+	}
+	return []game.Setting{{Name: "size", Value: strconv.Itoa(ag.Size)}}
+}
+
+func (ag AyuGame) CreateState() game.GameState {
+	return ayu.CreateState(ag.Size)
+}
+
+func (ag AyuGame) ParseMove(s string) (interface{}, bool) {
+	return ayu.ParseMove(s)
+}
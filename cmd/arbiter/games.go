@@ -0,0 +1,13 @@
+package main
+
+// Games built into the arbiter, which register themselves with game.Register.
+// Ayu is registered in ayu.go.
+import (
+	_ "arbiter/game/breakthrough"
+	_ "arbiter/game/connect4"
+	_ "arbiter/game/gomoku"
+	_ "arbiter/game/hex"
+	_ "arbiter/game/othello"
+	_ "arbiter/game/polyy"
+	_ "arbiter/game/tron"
+)
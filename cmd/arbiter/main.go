@@ -0,0 +1,633 @@
+package main
+
+import (
+	"arbiter/game"
+	"arbiter/match"
+	"arbiter/spectator"
+	"arbiter/tournament"
+	"arbiter/tui"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			os.Exit(verifyMain(os.Args[2:]))
+		case "replay":
+			os.Exit(replayMain(os.Args[2:]))
+		case "render":
+			os.Exit(renderMain(os.Args[2:]))
+		case "openings":
+			os.Exit(openingsMain(os.Args[2:]))
+		}
+	}
+
+	// When interrupted, kill player processes and stop the tournament. A
+	// second signal exits immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		slog.Warn("interrupted, stopping tournament")
+		cancel()
+		<-signals
+		os.Exit(1)
+	}()
+
+	opts := tournament.Options{StartFailure: "play", Match: match.Options{
+		Adjudication:  "scores",
+		FailurePolicy: "random-moves",
+		IllegalMoves:  "fail",
+		OpeningLength: 4,
+		Analysis:      match.AnalysisOptions{Threshold: 200},
+		Container:     match.ContainerOptions{Runtime: "docker"},
+	}}
+	rounds := 1
+	single := false
+	protocolName := "codecup"
+	gameName := "ayu"
+	cpuprofile := ""
+	workerURL := ""
+	var arbiterCPUs []int
+	serveAddr := ""
+	useTUI := false
+	spectateAddr := ""
+	spectateDelay := time.Duration(0)
+	persistent := false
+	glicko := false
+	trueSkill := false
+	seed := int64(0)
+	handicap := game.Handicap{}
+	position := ""
+	var playerEnv, playerDir, playerTime, playerNice, playerIONice []string
+	eventsPath := ""
+	outDir := ""
+	uploadDest := ""
+	pgnPath := ""
+	fixturesPath := ""
+	positionsPath := ""
+	rerunPath := ""
+	resumePath := ""
+	markdownPath := ""
+	pairingsPath := ""
+	verbosity := 0
+	diagFormat := "text"
+	color := "auto"
+	format := ""
+	flag.BoolVar(&opts.Quiet, "quiet", opts.Quiet, "print only plain-text results")
+	flag.StringVar(&format, "format", format, "results printed with -quiet: columns ("+tournament.FormatColumns()+") separated by commas, or a template")
+	flag.IntVar(&verbosity, "v", verbosity, "verbosity of diagnostics: 0 for warnings and errors, 1 to add progress, 2 to add moves")
+	flag.StringVar(&diagFormat, "diagnostics", diagFormat, "format of diagnostics written to stderr (text or json)")
+	flag.StringVar(&color, "color", color, "highlight game results with colors (auto, always or never)")
+	flag.BoolVar(&opts.Progress, "progress", opts.Progress, "print the number of games played and the estimated time left to stderr")
+	flag.BoolVar(&glicko, "glicko", glicko, "print Glicko-2 ratings with the standings")
+	flag.BoolVar(&trueSkill, "trueskill", trueSkill, "print TrueSkill ratings with the standings")
+	flag.BoolVar(&useTUI, "tui", useTUI, "show games in progress in a terminal UI")
+	flag.StringVar(&spectateAddr, "spectate", spectateAddr, "address to stream games in progress to spectators on, e.g. \":7000\"")
+	flag.DurationVar(&spectateDelay, "spectate-delay", spectateDelay, "time by which the spectator stream lags behind the games")
+	flag.BoolVar(&single, "single", single, "play only a single game")
+	flag.IntVar(&rounds, "rounds", rounds, "number of rounds to play")
+	flag.BoolVar(&opts.Arena, "arena", opts.Arena, "keep playing games between the pairs of players with the fewest games until stopped, instead of a fixed number of rounds")
+	flag.DurationVar(&opts.ArenaTime, "arena-time", opts.ArenaTime, "stop scheduling games in arena mode after this time (0 for no limit)")
+	flag.BoolVar(&opts.Adaptive, "adaptive", opts.Adaptive, "play more games between players whose relative strength is still unclear, instead of all pairings equally often")
+	flag.Float64Var(&opts.EarlyStop.Confidence, "stop-confidence", opts.EarlyStop.Confidence, "stop early when the leader's likelihood of superiority over the player ranked second reaches this level, e.g. 0.95")
+	flag.IntVar(&opts.EarlyStop.Contender, "stop-contender", opts.EarlyStop.Contender, "stop early when the player with this number can no longer finish first")
+	flag.StringVar(&rerunPath, "rerun", rerunPath, "play the game recorded in a game log again, with the same players, sides and seed")
+	flag.StringVar(&resumePath, "resume", resumePath, "continue the unfinished game recorded in a game log")
+	flag.Func("only", "play only the games matching a pairing pattern, e.g. \"champ:*\" (may be repeated)", func(s string) error {
+		opts.Pairings.Only = append(opts.Pairings.Only, s)
+		return nil
+	})
+	flag.Func("exclude", "don't play the games matching a pairing pattern, e.g. \"old1:old2\" (may be repeated)", func(s string) error {
+		opts.Pairings.Exclude = append(opts.Pairings.Exclude, s)
+		return nil
+	})
+	flag.StringVar(&fixturesPath, "fixtures", fixturesPath, "file listing the games to play, as \"<player> <player> [seed]\" per line, instead of all pairings")
+	flag.IntVar(&opts.Match.LogBoards, "log-boards", opts.Match.LogBoards, "draw the board in game logs every this many moves and at the end of the game, or 0 to not draw it")
+	flag.BoolVar(&opts.LogJSON, "log-json", opts.LogJSON, "also write the result of each game as JSON next to its game log")
+	flag.StringVar(&opts.MsgPath, "msg", opts.MsgPath, "path to player message log files")
+	flag.BoolVar(&opts.Match.MsgTimestamps, "msg-timestamps", opts.Match.MsgTimestamps, "prefix each line in player message log files with the time it was written")
+	flag.StringVar(&opts.DebugPath, "debug", opts.DebugPath, "path to debug log files, which interleave the messages exchanged with both players and written to stderr")
+	flag.StringVar(&opts.LogPath, "log", opts.LogPath, "path to game log files")
+	flag.StringVar(&outDir, "out", outDir, "directory to create for the logs and results of this run; may contain {date}, {time}, {run}, {seed} and {game}")
+	flag.StringVar(&uploadDest, "upload", uploadDest, "upload the -out directory to object storage when the run is finished, e.g. s3://bucket/runs or gs://bucket/runs")
+	flag.StringVar(&pgnPath, "pgn", pgnPath, "path to write the results to as PGN, for rating tools such as Ordo and BayesElo")
+	flag.StringVar(&markdownPath, "markdown", markdownPath, "path to write the standings and win/loss matrix to as Markdown tables")
+	flag.StringVar(&pairingsPath, "pairings", pairingsPath, "path to write the results of each pair of players to as CSV")
+	flag.BoolVar(&opts.Compress, "gzip", opts.Compress, "compress game and message log files with gzip")
+	flag.StringVar(&cpuprofile, "cpuprofile", cpuprofile, "path to cpu profile")
+	flag.StringVar(&opts.Coordinator, "coordinator", opts.Coordinator, "address to listen on for workers")
+	flag.StringVar(&workerURL, "worker", workerURL, "URL of coordinator to run games for")
+	flag.StringVar(&serveAddr, "serve", serveAddr, "address to serve the tournament REST API on (localhost only unless a host is given)")
+	flag.StringVar(&opts.Token, "token", opts.Token, "token that workers send to the coordinator, and that requests to the REST API other than GET must carry (default $ARBITER_TOKEN, or a random token that is logged)")
+	flag.StringVar(&opts.Match.Container.Image, "container", opts.Match.Container.Image, "container image to run players in")
+	flag.StringVar(&opts.Match.Container.Runtime, "container-runtime", opts.Match.Container.Runtime, "container runtime (docker or podman)")
+	flag.StringVar(&opts.Match.Container.CPUs, "container-cpus", opts.Match.Container.CPUs, "CPU limit for player containers")
+	flag.StringVar(&opts.Match.Container.Memory, "container-memory", opts.Match.Container.Memory, "memory limit for player containers")
+	flag.Func("affinity", "CPU cores to pin the first or second player's processes to, as <n>:<cpus> where n is 1 or 2, e.g. 1:2-3 (may be repeated)", func(s string) error {
+		n, cpus, _ := strings.Cut(s, ":")
+		if n != "1" && n != "2" {
+			return errors.New("expected <n>:<cpus> where n is 1 or 2")
+		}
+		if _, err := match.ParseCPUList(cpus); err != nil {
+			return err
+		}
+		opts.Match.Affinity[n[0]-'1'] = cpus
+		return nil
+	})
+	flag.Func("arbiter-affinity", "CPU cores to pin the arbiter itself to, e.g. 0-1", func(s string) error {
+		var err error
+		arbiterCPUs, err = match.ParseCPUList(s)
+		return err
+	})
+	flag.StringVar(&opts.Match.User, "user", opts.Match.User, "user name or id to run player processes as (requires root)")
+	flag.StringVar(&opts.Match.Cgroup.Parent, "cgroup", opts.Match.Cgroup.Parent, "parent cgroup to create player cgroups in")
+	flag.Float64Var(&opts.Match.Cgroup.CPUs, "cgroup-cpus", opts.Match.Cgroup.CPUs, "CPU limit for player cgroups")
+	flag.StringVar(&opts.Match.Cgroup.Memory, "cgroup-memory", opts.Match.Cgroup.Memory, "memory limit for player cgroups")
+	flag.BoolVar(&opts.Match.AnnounceGames, "announce", opts.Match.AnnounceGames, "tell players the game number, the number of games and which player they are at the start of each game")
+	flag.BoolVar(&persistent, "persistent", persistent, "keep player processes alive between games, instead of starting them for each game")
+	flag.IntVar(&opts.Match.MaxRestarts, "restarts", opts.Match.MaxRestarts, "number of times a crashed player may be restarted per game")
+	flag.IntVar(&opts.Match.MaxMoves, "maxmoves", opts.Match.MaxMoves, "maximum number of moves per game (0 for no limit)")
+	flag.IntVar(&opts.Match.OpeningLength, "opening", opts.Match.OpeningLength, "number of moves at the start of each game reported as its opening")
+	flag.StringVar(&opts.Match.FailurePolicy, "on-failure", opts.Match.FailurePolicy, "what happens when a player fails during a game ("+match.FailurePolicyNames()+")")
+	flag.StringVar(&opts.StartFailure, "on-start-failure", opts.StartFailure, "what happens when a player fails to start ("+strings.Join(tournament.StartFailurePolicies, ", ")+")")
+	flag.StringVar(&opts.Match.IllegalMoves, "illegal-moves", opts.Match.IllegalMoves, "what happens when a player makes an illegal move ("+match.IllegalMovePolicyNames()+")")
+	flag.StringVar(&opts.Match.Adjudication, "adjudicate", opts.Match.Adjudication, "adjudication method for games reaching the move limit ("+match.AdjudicationNames()+")")
+	flag.IntVar(&handicap.Komi, "komi", handicap.Komi, "points added to the second player's score")
+	flag.IntVar(&handicap.Stones, "handicap", handicap.Stones, "number of extra moves the first player makes at the start")
+	flag.StringVar(&position, "position", position, "position to start games from, in the game's position notation")
+	flag.StringVar(&positionsPath, "positions", positionsPath, "file listing positions to start games from, one per line; all games are played from each")
+	flag.BoolVar(&opts.Match.SkipBlankLines, "skip-blank", opts.Match.SkipBlankLines, "ignore empty lines written by players")
+	flag.BoolVar(&opts.Match.Swap, "swap", opts.Match.Swap, "let the second player swap sides after the first move")
+	flag.BoolVar(&opts.Match.Solve, "solve", opts.Match.Solve, "end games early once their outcome is determined, for games that can solve endgames")
+	flag.DurationVar(&opts.Match.ReadTimeout, "read-timeout", opts.Match.ReadTimeout, "time limit for reading a line from a player, after which it is considered hung (0 for no limit)")
+	flag.DurationVar(&opts.Match.TimeLimit, "time", opts.Match.TimeLimit, "total time each player may use per game (0 for no limit)")
+	flag.DurationVar(&opts.Match.Increment, "increment", opts.Match.Increment, "time added to the clock of a player with a time limit after each of its moves")
+	flag.Func("byoyomi", "byo-yomi periods after the main time of -time, as <n>x<duration>, e.g. 5x10s", func(s string) error {
+		n, d, _ := strings.Cut(s, "x")
+		periods, err := strconv.Atoi(n)
+		if err != nil || periods < 1 {
+			return errors.New("expected <n>x<duration>, e.g. 5x10s")
+		}
+		if opts.Match.ByoYomiTime, err = time.ParseDuration(d); err != nil || opts.Match.ByoYomiTime <= 0 {
+			return errors.New("expected <n>x<duration>, e.g. 5x10s")
+		}
+		opts.Match.ByoYomiPeriods = periods
+		return nil
+	})
+	flag.BoolVar(&opts.Match.SendClock, "send-clock", opts.Match.SendClock, "tell players with a time limit how much time both players have left before each move")
+	flag.DurationVar(&opts.Match.TurnTime, "turntime", opts.Match.TurnTime, "time limit per turn in games where both players move at once (0 for no limit)")
+	flag.StringVar(&opts.Match.Analysis.Engine, "analyze", opts.Match.Analysis.Engine, "reference engine (using UGI) to analyze finished games with, to find blunders")
+	flag.StringVar(&opts.Match.Analysis.Go, "analyze-go", opts.Match.Analysis.Go, "arguments of the go command sent to the reference engine, e.g. \"movetime 1000\"")
+	flag.IntVar(&opts.Match.Analysis.Threshold, "blunder", opts.Match.Analysis.Threshold, "drop in the reference engine's evaluation that makes a move a blunder")
+	flag.StringVar(&eventsPath, "events", eventsPath, "path to JSON event stream (or - for stdout)")
+	flag.StringVar(&opts.Webhook, "webhook", opts.Webhook, "URL to post game and tournament results to")
+	flag.Func("notify", "file configuring Discord or Slack webhooks to post round summaries, upsets and standings to, and mail servers to mail a summary to", func(path string) error {
+		notifiers, err := tournament.LoadNotifiers(path)
+		opts.Notifiers = append(opts.Notifiers, notifiers...)
+		return err
+	})
+	flag.Func("engines", "file with engine definitions (may be repeated)", opts.Match.LoadEngines)
+	flag.Func("env", "environment variable for one player, as <n>:KEY=value where n is the 1-based player number (may be repeated)", func(s string) error {
+		playerEnv = append(playerEnv, s)
+		return nil
+	})
+	flag.Func("dir", "working directory for one player, as <n>:path where n is the 1-based player number (may be repeated)", func(s string) error {
+		playerDir = append(playerDir, s)
+		return nil
+	})
+	flag.Func("player-time", "time limit per game for one player, as <n>:duration where n is the 1-based player number (may be repeated)", func(s string) error {
+		playerTime = append(playerTime, s)
+		return nil
+	})
+	flag.Func("nice", "nice level for one player, as <n>:level where n is the 1-based player number (may be repeated)", func(s string) error {
+		playerNice = append(playerNice, s)
+		return nil
+	})
+	flag.Func("ionice", "I/O scheduling class for one player, as <n>:class[:level] where n is the 1-based player number, e.g. 2:idle (may be repeated)", func(s string) error {
+		playerIONice = append(playerIONice, s)
+		return nil
+	})
+	flag.Int64Var(&seed, "seed", seed, "random seed (0 to seed from the clock)")
+	flag.StringVar(&gameName, "game", gameName, "game to play ("+game.Names()+")")
+	flag.StringVar(&protocolName, "protocol", protocolName, "player protocol ("+match.ProtocolNames()+")")
+	flag.Parse()
+	if err := setupLogging(verbosity, diagFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	match.Seed(seed)
+	opts.Match.Seed = seed
+	if opts.Token == "" {
+		opts.Token = os.Getenv("ARBITER_TOKEN")
+	}
+	if eventsPath == "-" {
+		opts.Match.Events = match.NewEventLog(os.Stdout)
+	} else if eventsPath != "" {
+		if f, err := os.Create(eventsPath); err != nil {
+			slog.Error("couldn't create event stream", "error", err)
+		} else {
+			defer f.Close()
+			opts.Match.Events = match.NewEventLog(f)
+		}
+	}
+	if persistent {
+		opts.Match.Sessions = match.NewSessionPool()
+		defer opts.Match.Sessions.Close()
+	}
+	playerErr := setPlayerOptions(&opts.Match, flag.Args(), playerEnv, playerDir, playerTime, playerNice, playerIONice)
+	var gameErr error
+	opts.Match.Game, gameErr = game.Lookup(gameName)
+	opts.Match.GameName = gameName
+	if gameErr == nil {
+		opts.Match.Game, gameErr = game.WithHandicap(opts.Match.Game, handicap)
+	}
+	if gameErr == nil {
+		if opts.Match.Game, gameErr = game.WithPosition(opts.Match.Game, position); gameErr != nil {
+			gameErr = fmt.Errorf("-position: %s: %w", gameName, gameErr)
+		}
+	}
+	opts.Match.Protocol = match.Protocols[protocolName]
+	var brief *tournament.Format
+	var formatErr error
+	if format != "" {
+		brief, formatErr = tournament.ParseFormat(format)
+	}
+	// pinArbiter pins the arbiter to the cores given by -arbiter-affinity, if
+	// any, once the options are known to be valid. Returns whether it
+	// succeeded.
+	pinArbiter := func() bool {
+		if arbiterCPUs == nil {
+			return true
+		}
+		if err := match.PinArbiter(arbiterCPUs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		return true
+	}
+	if playerErr != nil {
+		fmt.Fprintln(os.Stderr, playerErr)
+	} else if gameErr != nil {
+		fmt.Fprintln(os.Stderr, gameErr)
+	} else if formatErr != nil {
+		fmt.Fprintln(os.Stderr, "Invalid format: "+formatErr.Error())
+	} else if opts.Match.Protocol == nil {
+		fmt.Fprintln(os.Stderr, "Unknown protocol: "+protocolName)
+	} else if color != "auto" && color != "always" && color != "never" {
+		fmt.Fprintln(os.Stderr, "Unknown color mode: "+color)
+	} else if !match.ValidAdjudication(opts.Match.Adjudication) {
+		fmt.Fprintln(os.Stderr, "Unknown adjudication method: "+opts.Match.Adjudication)
+	} else if !match.ValidFailurePolicy(opts.Match.FailurePolicy) {
+		fmt.Fprintln(os.Stderr, "Unknown failure policy: "+opts.Match.FailurePolicy)
+	} else if !tournament.ValidStartFailurePolicy(opts.StartFailure) {
+		fmt.Fprintln(os.Stderr, "Unknown start failure policy: "+opts.StartFailure)
+	} else if !match.ValidIllegalMovePolicy(opts.Match.IllegalMoves) {
+		fmt.Fprintln(os.Stderr, "Unknown illegal move policy: "+opts.Match.IllegalMoves)
+	} else if workerURL != "" {
+		if !pinArbiter() {
+			return
+		}
+		if err := tournament.RunWorker(ctx, &opts, workerURL); err != nil {
+			slog.Error("worker failed", "error", err)
+		}
+	} else if serveAddr != "" {
+		if !pinArbiter() {
+			return
+		}
+		if err := tournament.Serve(ctx, &opts, serveAddr); err != nil {
+			slog.Error("server failed", "error", err)
+		}
+	} else if rerunPath != "" && resumePath != "" {
+		fmt.Fprintln(os.Stderr, "Can't combine -rerun with -resume!")
+	} else if (rerunPath != "" || resumePath != "") && (flag.NArg() > 0 || single || fixturesPath != "" || opts.Arena || opts.Adaptive) {
+		fmt.Fprintln(os.Stderr, "Can't combine -rerun or -resume with players, -single, -fixtures, -arena or -adaptive!")
+	} else if resumePath != "" && rounds > 1 {
+		fmt.Fprintln(os.Stderr, "Can't resume a game for more than one round!")
+	} else if rerunPath == "" && resumePath == "" && flag.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "Too few player commands passed!")
+		fmt.Fprintln(os.Stderr, "Additional options:")
+		flag.PrintDefaults()
+	} else if rounds < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid number of rounds passed!")
+	} else if single && (flag.NArg() > 2 || rounds > 1) {
+		fmt.Fprintln(os.Stderr, "Single game requires two players and one round!")
+	} else if outDir != "" && (opts.LogPath != "" || opts.MsgPath != "") {
+		fmt.Fprintln(os.Stderr, "Can't combine -out with -log or -msg!")
+	} else if uploadDest != "" && outDir == "" {
+		fmt.Fprintln(os.Stderr, "-upload requires -out!")
+	} else if single && fixturesPath != "" {
+		fmt.Fprintln(os.Stderr, "Can't combine -single with -fixtures!")
+	} else if opts.EarlyStop.Contender < 0 || opts.EarlyStop.Contender > flag.NArg() {
+		fmt.Fprintln(os.Stderr, "Invalid contender passed to -stop-contender!")
+	} else if opts.EarlyStop != (tournament.EarlyStop{}) && opts.Coordinator != "" {
+		fmt.Fprintln(os.Stderr, "Can't combine -stop-confidence or -stop-contender with -coordinator!")
+	} else if opts.StartFailure != "play" && opts.Coordinator != "" {
+		fmt.Fprintln(os.Stderr, "Can't combine -on-start-failure with -coordinator!")
+	} else if (opts.Arena || opts.Adaptive) && (single || fixturesPath != "" || opts.Coordinator != "") {
+		fmt.Fprintln(os.Stderr, "Can't combine -arena or -adaptive with -single, -fixtures or -coordinator!")
+	} else if positionsPath != "" && (position != "" || rerunPath != "" || resumePath != "" || opts.Arena || opts.Adaptive) {
+		fmt.Fprintln(os.Stderr, "Can't combine -positions with -position, -rerun, -resume, -arena or -adaptive!")
+	} else {
+		if !pinArbiter() {
+			return
+		}
+		if cpuprofile != "" {
+			if f, err := os.Create(cpuprofile); err != nil {
+				slog.Error("couldn't create CPU profile", "error", err)
+			} else {
+				pprof.StartCPUProfile(f)
+				defer pprof.StopCPUProfile()
+			}
+		}
+		players := flag.Args()
+		if rerunPath != "" {
+			var err error
+			if players, err = loadRerun(&opts, rerunPath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		if resumePath != "" {
+			var err error
+			if players, err = loadResume(&opts, resumePath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		if fixturesPath != "" {
+			var err error
+			if opts.Fixtures, err = tournament.LoadFixtures(fixturesPath, players); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		if positionsPath != "" {
+			var err error
+			if opts.Positions, err = tournament.LoadPositions(positionsPath, opts.Match.Game); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		opts.Color = color == "always" || (color == "auto" && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "")
+		opts.RunId = tournament.NewRunId()
+		var uploader *tournament.Uploader
+		if uploadDest != "" {
+			var err error
+			if uploader, err = tournament.NewUploader(uploadDest); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		}
+		var runDir *tournament.RunDir
+		if outDir != "" {
+			var err error
+			runDir, err = tournament.NewRunDir(outDir, tournament.Manifest{
+				RunId: opts.RunId, Args: os.Args[1:], Game: gameName, Players: players,
+				Rounds: rounds, Seed: seed, Started: time.Now()})
+			if err != nil {
+				slog.Error("couldn't create output directory", "error", err)
+				return
+			}
+			runDir.Configure(&opts)
+		}
+		var spectators *spectator.Server
+		if spectateAddr != "" {
+			l, err := net.Listen("tcp", spectateAddr)
+			if err != nil {
+				slog.Error("couldn't listen for spectators", "error", err)
+				return
+			}
+			spectators = spectator.New(opts.Match.Game, spectateDelay)
+			opts.Match.Events = opts.Match.Events.AddHandler(spectators.Handle)
+			go func() {
+				if err := spectators.Serve(ctx, l); err != nil {
+					slog.Error("spectator stream failed", "error", err)
+				}
+			}()
+		}
+		quiet := opts.Quiet
+		var results []match.Result
+		if useTUI {
+			// Individual results are shown by the viewer instead.
+			opts.Quiet = true
+			viewer := tui.New(os.Stdout, opts.Match.Game)
+			opts.Match.Events = opts.Match.Events.AddHandler(viewer.Handle)
+			viewerCtx, stopViewer := context.WithCancel(ctx)
+			viewerDone := make(chan struct{})
+			go func() {
+				viewer.Run(viewerCtx)
+				close(viewerDone)
+			}()
+			results = tournament.Run(ctx, &opts, players, rounds, single)
+			stopViewer()
+			<-viewerDone
+		} else {
+			results = tournament.Run(ctx, &opts, players, rounds, single)
+		}
+		if spectators != nil {
+			spectators.Flush(ctx)
+		}
+		stats := tournament.ComputeStats(players, results)
+		if runDir != nil {
+			if err := runDir.Finish(stats); err != nil {
+				slog.Error("couldn't write results", "error", err)
+			}
+			if uploader != nil {
+				// Upload even if the run was interrupted, so that the games
+				// played so far aren't lost.
+				if err := uploader.UploadDir(context.Background(), runDir.Path); err != nil {
+					slog.Error("couldn't upload run directory", "error", err)
+				}
+			}
+		}
+		if pgnPath != "" {
+			err := writeFile(pgnPath, func(w io.Writer) error {
+				return tournament.WritePGN(w, gameName, players, results)
+			})
+			if err != nil {
+				slog.Error("couldn't write PGN", "error", err)
+			}
+		}
+		if markdownPath != "" {
+			if err := writeFile(markdownPath, stats.WriteMarkdown); err != nil {
+				slog.Error("couldn't write Markdown", "error", err)
+			}
+		}
+		if pairingsPath != "" {
+			if err := writeFile(pairingsPath, stats.WritePairingsCSV); err != nil {
+				slog.Error("couldn't write pairings", "error", err)
+			}
+		}
+
+		if quiet && brief != nil {
+			if err := stats.PrintFormat(os.Stdout, brief); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else if quiet { // Brief results
+			stats.PrintBrief(os.Stdout)
+
+		} else { // Verbose results
+			fmt.Println()
+			stats.PrintStandings(os.Stdout)
+			if stats.HasFailures() {
+				fmt.Println()
+				stats.PrintFailures(os.Stdout)
+			}
+
+			if !single {
+				fmt.Println()
+				stats.PrintSides(os.Stdout)
+				fmt.Println()
+				stats.PrintLengths(os.Stdout)
+				fmt.Println()
+				stats.PrintMoveTimes(os.Stdout)
+				fmt.Println()
+				stats.PrintDuplicates(os.Stdout)
+				if len(stats.Openings) > 0 {
+					fmt.Println()
+					stats.PrintOpenings(os.Stdout)
+				}
+				if len(stats.Positions) > 0 {
+					fmt.Println()
+					stats.PrintPositions(os.Stdout)
+				}
+			}
+
+			if glicko {
+				fmt.Println()
+				stats.PrintGlicko(os.Stdout)
+			}
+			if trueSkill {
+				fmt.Println()
+				stats.PrintTrueSkill(os.Stdout)
+			}
+
+			if len(players) > 2 {
+				fmt.Println()
+				stats.PrintWinLoss(os.Stdout)
+			}
+
+			// Print average difference in points for player against each opponent:
+			if !single {
+				fmt.Println()
+				stats.PrintScoreDifference(os.Stdout)
+			}
+		}
+	}
+}
+
+// writeFile creates the file at path, and writes its contents with write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// isTerminal returns whether f is a terminal, rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// setupLogging makes diagnostics of the given verbosity be written to stderr
+// in the given format: "text" or "json".
+func setupLogging(verbosity int, format string) error {
+	opts := &slog.HandlerOptions{Level: slog.LevelWarn - slog.Level(4*verbosity)}
+	switch format {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+	default:
+		return errors.New("Unknown diagnostics format: " + format)
+	}
+	return nil
+}
+
+// setPlayerOptions applies the -env, -dir, -player-time, -nice and -ionice
+// options to the engines of the given players.
+func setPlayerOptions(opts *match.Options, players []string, env, dir, timeLimit, nice, ionice []string) error {
+	player := func(flag, s string) (string, string, error) {
+		var n int
+		index, value, _ := strings.Cut(s, ":")
+		if _, err := fmt.Sscan(index, &n); err != nil || n < 1 || n > len(players) {
+			return "", "", fmt.Errorf("Invalid player number in -%s %s", flag, s)
+		}
+		return players[n-1], value, nil
+	}
+	for _, s := range env {
+		name, kv, err := player("env", s)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("Expected KEY=value in -env %s", s)
+		}
+		opts.AddEnv(name, kv)
+	}
+	for _, s := range dir {
+		name, path, err := player("dir", s)
+		if err != nil {
+			return err
+		}
+		opts.SetDir(name, path)
+	}
+	for _, s := range timeLimit {
+		name, value, err := player("player-time", s)
+		if err != nil {
+			return err
+		}
+		limit, err := time.ParseDuration(value)
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("Expected a duration in -player-time %s", s)
+		}
+		opts.SetTimeLimit(name, limit)
+	}
+	for _, s := range nice {
+		name, value, err := player("nice", s)
+		if err != nil {
+			return err
+		}
+		level, err := strconv.Atoi(value)
+		if err != nil || level < -20 || level > 19 {
+			return fmt.Errorf("Expected a level from -20 to 19 in -nice %s", s)
+		}
+		opts.SetNice(name, level)
+	}
+	for _, s := range ionice {
+		name, value, err := player("ionice", s)
+		if err != nil {
+			return err
+		}
+		if _, _, err := match.ParseIONice(value); err != nil {
+			return fmt.Errorf("Invalid -ionice %s: %s", s, err)
+		}
+		opts.SetIONice(name, value)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"arbiter/game"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// openingsMain implements "arbiter openings -game <game> [-moves n]", which
+// lists the positions reached after the given number of moves from the start
+// position, counting positions that are the same up to symmetry once (see
+// game.Symmetric), for use with -positions. Returns the exit status.
+func openingsMain(args []string) int {
+	fs := flag.NewFlagSet("openings", flag.ExitOnError)
+	gameName := fs.String("game", "", "game to list openings of ("+game.Names()+")")
+	moves := fs.Int("moves", 2, "number of moves of each opening")
+	count := fs.Int("count", 0, "maximum number of positions to list, chosen at random, or 0 to list all")
+	seed := fs.Int64("seed", 1, "random seed used to choose positions with -count")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: arbiter openings -game <game> [-moves <n>] [-count <n>] > positions.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || *gameName == "" || *moves < 1 || *count < 0 {
+		fs.Usage()
+		return 1
+	}
+	g, err := game.Lookup(*gameName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, ok := g.(game.Positioner); !ok {
+		fmt.Fprintf(os.Stderr, "%s doesn't support positions\n", *gameName)
+		return 1
+	}
+	positions, err := openings(g, *moves)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	total := len(positions)
+	if *count > 0 && *count < total {
+		rng := rand.New(rand.NewSource(*seed))
+		rng.Shuffle(total, func(i, j int) { positions[i], positions[j] = positions[j], positions[i] })
+		positions = positions[:*count]
+		sort.Strings(positions)
+	}
+	fmt.Printf("# %s openings of %d moves: %d of %d distinct positions\n", *gameName, *moves, len(positions), total)
+	for _, position := range positions {
+		fmt.Println(position)
+	}
+	fmt.Fprintf(os.Stderr, "Listed %d of %d distinct positions after %d moves (%.1f%% coverage).\n",
+		len(positions), total, *moves, 100*float64(len(positions))/float64(total))
+	return 0
+}
+
+// openings returns the canonical positions (see game.CanonicalPosition) of g
+// reached after the given number of moves in which the game is not over yet,
+// in sorted order. Positions are deduplicated after each move, so that
+// transpositions and symmetric lines are only expanded once.
+func openings(g game.Game, moves int) ([]string, error) {
+	start := g.CreateState()
+	if game.IsSimultaneous(start) {
+		return nil, fmt.Errorf("openings of games with simultaneous moves can't be listed")
+	}
+	level := []string{game.CanonicalPosition(g, start)}
+	for n := 0; n < moves; n++ {
+		seen := map[string]bool{}
+		for _, position := range level {
+			pg, err := game.WithPosition(g, position)
+			if err != nil {
+				return nil, err
+			}
+			for _, move := range pg.CreateState().ListMoves() {
+				state := pg.CreateState()
+				if !state.Execute(move) || state.Over() {
+					continue
+				}
+				seen[game.CanonicalPosition(pg, state)] = true
+			}
+		}
+		level = level[:0]
+		for position := range seen {
+			level = append(level, position)
+		}
+		if len(level) == 0 {
+			return nil, fmt.Errorf("no positions after %d moves", n+1)
+		}
+	}
+	sort.Strings(level)
+	return level, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"arbiter/match"
+	"flag"
+	"fmt"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renderMain implements "arbiter render <logfile> -o <file>.gif", which draws
+// each position of a recorded game as a frame of an animated GIF (see
+// game.RenderImage). Returns the exit status.
+func renderMain(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	gameName := fs.String("game", "", "game played ("+game.Names()+"), if not recorded in the log")
+	output := fs.String("o", "", "path of the animated GIF to write")
+	delay := fs.Float64("delay", 0.5, "time each position is shown, in seconds")
+	finalDelay := fs.Float64("final-delay", 3, "time the final position is shown before the animation repeats, in seconds")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: arbiter render [options] <logfile> -o <file>.gif")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	// Allow options after the log file too.
+	if fs.NArg() > 0 {
+		path := fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+		args = append([]string{path}, fs.Args()...)
+	} else {
+		args = nil
+	}
+	if len(args) != 1 || *output == "" || *delay <= 0 || *finalDelay <= 0 {
+		fs.Usage()
+		return 1
+	}
+	if ext := strings.ToLower(filepath.Ext(*output)); ext != ".gif" {
+		fmt.Fprintf(os.Stderr, "Unsupported output format: %s (only .gif is supported)\n", ext)
+		return 1
+	}
+	var g game.Game
+	if *gameName != "" {
+		var err error
+		if g, err = game.Lookup(*gameName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	f, err := gamelog.OpenLog(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	gl, err := readLog(g, f)
+	f.Close()
+	if err == nil {
+		g, err = logGame(g, gl)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	anim, err := animate(g, gl.Moves, *delay, *finalDelay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = gif.EncodeAll(out, anim)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// animate returns an animation with a frame for the initial position and for
+// the position after each move of a game of g. In turns where both players
+// move at once, there is only a frame after the second move. Delays are given
+// in seconds.
+func animate(g game.Game, moves []string, delay, finalDelay float64) (*gif.GIF, error) {
+	anim := &gif.GIF{}
+	for i := 0; i <= len(moves); i++ {
+		state, err := match.Replay(g, moves[:i])
+		if err != nil {
+			if i < len(moves) && game.IsSimultaneous(state) {
+				continue // first move of a simultaneous turn
+			}
+			return nil, err
+		}
+		img, ok := game.RenderImage(state)
+		if !ok {
+			return nil, fmt.Errorf("images are not supported for this game")
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, int(delay*100+0.5))
+	}
+	anim.Delay[len(anim.Delay)-1] = int(finalDelay*100 + 0.5)
+	anim.Config.Width, anim.Config.Height = anim.Image[0].Bounds().Dx(), anim.Image[0].Bounds().Dy()
+	anim.Config.ColorModel = game.Palette
+	return anim, nil
+}
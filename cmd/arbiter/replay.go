@@ -0,0 +1,148 @@
+package main
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// replayMain implements "arbiter replay <logfile>", which prints the board
+// after each move of a recorded game, or draws one position as an SVG image.
+// Returns the exit status.
+func replayMain(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	gameName := fs.String("game", "", "game played ("+game.Names()+"), if not recorded in the log")
+	moveNo := fs.Int("move", 0, "print only the position after this move")
+	final := fs.Bool("final", false, "print only the final position")
+	step := fs.Bool("step", false, "wait for Enter after each move")
+	svgPath := fs.String("svg", "", "write the final position, or the one after -move, as an SVG image to this file instead")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: arbiter replay [options] <logfile>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	var g game.Game
+	if *gameName != "" {
+		var err error
+		if g, err = game.Lookup(*gameName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	f, err := gamelog.OpenLog(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	gl, err := readLog(g, f)
+	f.Close()
+	if err == nil {
+		g, err = logGame(g, gl)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *moveNo < 0 || *moveNo > len(gl.Moves) {
+		fmt.Fprintf(os.Stderr, "Invalid move number: %d (game has %d moves)\n", *moveNo, len(gl.Moves))
+		return 1
+	}
+
+	if *svgPath != "" && *moveNo == 0 {
+		*final = true
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	state := g.CreateState()
+	mover := 0 // player who made the last move, or -1 if both moved at once
+	show := func(i int) bool {
+		if *svgPath != "" {
+			if err := writeSVG(*svgPath, state); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return false
+			}
+			return true
+		}
+		if i == 0 {
+			fmt.Println("Initial position:")
+		} else if mover < 0 {
+			fmt.Printf("After moves %d-%d (players 1 and 2: %s, %s):\n", i-1, i, gl.Moves[i-2], gl.Moves[i-1])
+		} else if len(gl.Times) == len(gl.Moves) {
+			fmt.Printf("After move %d (player %d: %s in %.3fs):\n", i, mover+1, gl.Moves[i-1], gl.Times[i-1])
+		} else {
+			fmt.Printf("After move %d (player %d: %s):\n", i, mover+1, gl.Moves[i-1])
+		}
+		if !game.RenderBoard(os.Stdout, state) {
+			fmt.Println("(board display not supported for this game)")
+		}
+		fmt.Println()
+		return true
+	}
+	if !*final && *moveNo == 0 {
+		show(0)
+	}
+	var pending []interface{} // first player's move in a simultaneous turn
+	for i, s := range gl.Moves {
+		move, ok := g.ParseMove(s)
+		if !ok || state.Over() {
+			fmt.Fprintf(os.Stderr, "Invalid move %d: %s\n", i+1, s)
+			return 1
+		}
+		if ss, ok := state.(game.SimultaneousState); ok && ss.Simultaneous() {
+			// Both players' moves are recorded, the first player's first.
+			if pending = append(pending, move); len(pending) < 2 {
+				if *moveNo == i+1 {
+					*moveNo = i + 2
+				}
+				continue
+			}
+			mover = -1
+			valid := ss.ExecuteBoth([2]interface{}{pending[0], pending[1]})
+			pending = nil
+			if !valid[0] || !valid[1] {
+				fmt.Fprintf(os.Stderr, "Invalid moves %d-%d: %s, %s\n", i, i+1, gl.Moves[i-1], s)
+				return 1
+			}
+		} else if mover = state.Next(); !state.Execute(move) {
+			fmt.Fprintf(os.Stderr, "Invalid move %d: %s\n", i+1, s)
+			return 1
+		}
+		if *final || (*moveNo != 0 && i+1 != *moveNo) {
+			continue
+		}
+		if !show(i + 1) {
+			return 1
+		}
+		if *moveNo != 0 {
+			return 0
+		}
+		if *step && i+1 < len(gl.Moves) {
+			stdin.ReadString('\n')
+		}
+	}
+	if *final && !show(len(gl.Moves)) {
+		return 1
+	}
+	if gl.HasScore && *svgPath == "" {
+		fmt.Printf("Score: %d - %d\n", gl.Score[0], gl.Score[1])
+	}
+	return 0
+}
+
+// writeSVG draws the board of state to an SVG file (see game.RenderSVG).
+func writeSVG(path string, state game.GameState) error {
+	var b bytes.Buffer
+	if !game.RenderSVG(&b, state) {
+		return errors.New("SVG images are not supported for this game")
+	}
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"arbiter/match"
+	"arbiter/tournament"
+	"errors"
+	"fmt"
+)
+
+// loadRerun sets up opts to play the game recorded in the given game log
+// again, with the same players on the same sides, game seed and identifier,
+// and returns the players.
+func loadRerun(opts *tournament.Options, path string) ([]string, error) {
+	players, _, err := loadGame(opts, path)
+	return players, err
+}
+
+// loadResume sets up opts to continue the unfinished game recorded in the
+// given game log (see match.Options.Resume), and returns the players.
+func loadResume(opts *tournament.Options, path string) ([]string, error) {
+	players, gl, err := loadGame(opts, path)
+	if err != nil {
+		return nil, err
+	}
+	if gl.HasScore && !gl.Interrupted {
+		return nil, errors.New(path + ": game already finished")
+	}
+	if len(gl.Movers) != len(gl.Moves) || len(gl.Times) != len(gl.Moves) {
+		return nil, errors.New(path + ": players and times of moves not recorded")
+	}
+	opts.Match.Resume = &match.Resumption{Moves: gl.Moves, Movers: gl.Movers, Times: gl.Times, Swapped: gl.Swapped}
+	return players, nil
+}
+
+// loadGame sets up opts like loadRerun, and returns the players and the log.
+func loadGame(opts *tournament.Options, path string) ([]string, *gamelog.GameLog, error) {
+	f, err := gamelog.OpenLog(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	gl, err := gamelog.ReadLog(opts.Match.Game, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gl.Game != "" && gl.Game != opts.Match.GameName {
+		return nil, nil, fmt.Errorf("%s: game played was %s, not %s", path, gl.Game, opts.Match.GameName)
+	}
+	if g := opts.Match.Game; gl.Position != game.Position(g, g.CreateState()) {
+		// The game started from another position.
+		if opts.Match.Game, err = game.WithPosition(g, gl.Position); err != nil {
+			return nil, nil, err
+		}
+	}
+	players := gl.Players
+	if players[0] == "" || players[1] == "" {
+		return nil, nil, errors.New(path + ": players not recorded")
+	}
+	if gl.Swapped {
+		// The log refers to the sides after the swap.
+		players[0], players[1] = players[1], players[0]
+	}
+	opts.Match.Seed = gl.Seed
+	opts.Fixtures = []tournament.Fixture{{Players: [2]int{0, 1}, Seed: &gl.GameSeed, GameId: gl.GameId}}
+	return players[:], gl, nil
+}
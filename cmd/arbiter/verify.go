@@ -0,0 +1,91 @@
+package main
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"arbiter/match"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// verifyMain implements "arbiter verify <logfile>...", which replays game logs
+// and reports the first invalid move or score mismatch in each. Returns the
+// exit status: 0 if all logs are valid, or 1 otherwise.
+func verifyMain(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	gameName := fs.String("game", "", "game played ("+game.Names()+"), if not recorded in the log")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: arbiter verify <logfile>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	var g game.Game
+	if *gameName != "" {
+		var err error
+		if g, err = game.Lookup(*gameName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	status := 0
+	for _, path := range fs.Args() {
+		if err := verifyLog(g, path); err != nil {
+			fmt.Printf("%s: %s\n", path, err)
+			status = 1
+		} else {
+			fmt.Printf("%s: OK\n", path)
+		}
+	}
+	return status
+}
+
+// readLog parses a game log for the given game, or the game recorded in the
+// log if g is nil (see gamelog.ReadLog).
+func readLog(g game.Game, r io.Reader) (*gamelog.GameLog, error) {
+	gl, err := gamelog.ReadLog(g, r)
+	if err == gamelog.ErrNoGame {
+		err = errors.New(err.Error() + "; use -game")
+	}
+	return gl, err
+}
+
+func verifyLog(g game.Game, path string) error {
+	f, err := gamelog.OpenLog(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gl, err := readLog(g, f)
+	if err != nil {
+		return err
+	}
+	if g, err = logGame(g, gl); err != nil {
+		return err
+	}
+	return match.Verify(g, gl)
+}
+
+// logGame returns the game played in a game log, with its handicap, starting
+// position and deal: g if not nil, or else the game recorded in the log.
+func logGame(g game.Game, gl *gamelog.GameLog) (game.Game, error) {
+	var err error
+	if g == nil {
+		if g, err = game.Lookup(gl.Game); err != nil {
+			return nil, err
+		}
+	}
+	if g, err = game.WithHandicap(g, gl.Handicap); err != nil {
+		return nil, err
+	}
+	if g, err = game.WithPosition(g, gl.Position); err != nil {
+		return nil, err
+	}
+	return game.WithDeal(g, gl.Deal), nil
+}
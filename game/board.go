@@ -0,0 +1,132 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Board describes a board of rows by cols cells, each of which is empty or
+// holds a piece of one of the players, so that it can be drawn as an image.
+type Board struct {
+	Rows, Cols int
+
+	// Horizontal offset of each row relative to the row above it, in cells,
+	// e.g. 0.5 for the rhombus of a Hex board.
+	Shift float64
+
+	// Owner returns the player whose piece is on the cell at 0-based row r
+	// and column c, counted from the top left: 1 for the first player (drawn
+	// black), 2 for the second player (drawn white), or 0 if the cell is
+	// empty.
+	Owner func(r, c int) int
+}
+
+// Drawable may be implemented by game states whose board is a grid of pieces
+// (see Board), so that it can be drawn as an image.
+type Drawable interface {
+	Board() Board
+}
+
+// SVGRenderer may be implemented by game states that draw the board as an SVG
+// image themselves, instead of through Drawable.
+type SVGRenderer interface {
+	RenderSVG(w io.Writer)
+}
+
+// RenderSVG draws the board of state to w as an SVG image, using its
+// RenderSVG method, or its Board method if it doesn't implement SVGRenderer.
+// It returns false if the state supports neither.
+func RenderSVG(w io.Writer, state GameState) bool {
+	if r, ok := state.(SVGRenderer); ok {
+		r.RenderSVG(w)
+	} else if d, ok := state.(Drawable); ok {
+		d.Board().WriteSVG(w)
+	} else {
+		return false
+	}
+	return true
+}
+
+// RenderImage draws the board of state as an image with the colors of
+// Palette, and returns false if state doesn't implement Drawable.
+func RenderImage(state GameState) (*image.Paletted, bool) {
+	if d, ok := state.(Drawable); ok {
+		return d.Board().Image(), true
+	}
+	return nil, false
+}
+
+// Size of a cell of a drawn board, in pixels.
+const cellSize = 40
+
+// Radius of the pieces and of the outlines of empty cells, in pixels.
+const pieceRadius = cellSize * 2 / 5
+
+// Palette holds the colors of boards drawn by Board.Image: the background,
+// the outlines of empty cells, and the pieces of the first and second player.
+var Palette = color.Palette{
+	color.RGBA{0xd9, 0xb3, 0x6c, 0xff},
+	color.RGBA{0x7a, 0x5c, 0x2e, 0xff},
+	color.Black,
+	color.White,
+}
+
+// size returns the width and height of the drawn board in pixels.
+func (b Board) size() (width, height int) {
+	return int((float64(b.Cols) + b.Shift*float64(b.Rows-1) + 1) * cellSize), (b.Rows + 1) * cellSize
+}
+
+// center returns the position of the center of a cell in pixels.
+func (b Board) center(r, c int) (x, y float64) {
+	return (float64(c) + b.Shift*float64(r) + 1) * cellSize, float64(r+1) * cellSize
+}
+
+// WriteSVG draws the board as an SVG image.
+func (b Board) WriteSVG(w io.Writer) {
+	width, height := b.size()
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(w, "<rect width=\"%d\" height=\"%d\" fill=\"#d9b36c\"/>\n", width, height)
+	fills := []string{"none", "#000", "#fff"}
+	for r := 0; r < b.Rows; r++ {
+		for c := 0; c < b.Cols; c++ {
+			x, y := b.center(r, c)
+			fmt.Fprintf(w, "<circle cx=\"%g\" cy=\"%g\" r=\"%d\" fill=\"none\" stroke=\"#7a5c2e\"/>\n", x, y, pieceRadius)
+			if p := b.Owner(r, c); p > 0 && p < len(fills) {
+				fmt.Fprintf(w, "<circle cx=\"%g\" cy=\"%g\" r=\"%d\" fill=\"%s\" stroke=\"#000\"/>\n", x, y, pieceRadius, fills[p])
+			}
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// Image draws the board as an image with the colors of Palette.
+func (b Board) Image() *image.Paletted {
+	width, height := b.size()
+	img := image.NewPaletted(image.Rect(0, 0, width, height), Palette)
+	for r := 0; r < b.Rows; r++ {
+		for c := 0; c < b.Cols; c++ {
+			cx, cy := b.center(r, c)
+			p := b.Owner(r, c)
+			for y := int(cy) - pieceRadius - 1; y <= int(cy)+pieceRadius+1; y++ {
+				for x := int(cx) - pieceRadius - 1; x <= int(cx)+pieceRadius+1; x++ {
+					dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+					d := dx*dx + dy*dy
+					outline := d >= (pieceRadius-1)*(pieceRadius-1) && d <= (pieceRadius+1)*(pieceRadius+1)
+					switch {
+					case p == 0 && outline:
+						img.SetColorIndex(x, y, 1)
+					case p == 1 && d <= (pieceRadius+1)*(pieceRadius+1):
+						img.SetColorIndex(x, y, 2)
+					case p == 2 && outline:
+						img.SetColorIndex(x, y, 2)
+					case p == 2 && d < (pieceRadius-1)*(pieceRadius-1):
+						img.SetColorIndex(x, y, 3)
+					}
+				}
+			}
+		}
+	}
+	return img
+}
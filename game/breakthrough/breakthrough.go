@@ -0,0 +1,330 @@
+// Package breakthrough implements Breakthrough. Each player starts with two
+// rows of pieces on their side of the board. Pieces move one square forward,
+// straight or diagonally, to an empty square, and capture diagonally forward.
+// A player wins by reaching the opponent's home row, or when the opponent has
+// no pieces or no moves left. The winner scores 1 against 0.
+//
+// The first player starts on rows 1 and 2 and moves up; the second player
+// starts on the top two rows and moves down. Moves are written as the squares
+// moved from and to, e.g. "b2-b3"; "x" is accepted instead of "-" for
+// captures.
+//
+// Positions are written as the rows from the top row down, separated by '/',
+// with 'x' and 'o' for the pieces of the first and second player and '.' for
+// empty squares, followed by ':' and the player to move, e.g. ":x" if the
+// first player moves next.
+package breakthrough
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("breakthrough", parseParams)
+}
+
+// Game is a Breakthrough variant with a given board size.
+type Game struct {
+	Cols, Rows int
+	Start      string // position the game starts from, or "" for the usual one
+}
+
+// parseParams creates a game from the board size parameter, given as "n" for
+// a square board or "<cols>x<rows>". The default board is 8x8.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Cols: 8, Rows: 8}
+	if params == "" {
+		return g, nil
+	}
+	cols, rows, found := strings.Cut(params, "x")
+	if !found {
+		rows = cols
+	}
+	var err1, err2 error
+	g.Cols, err1 = strconv.Atoi(cols)
+	g.Rows, err2 = strconv.Atoi(rows)
+	if err1 != nil || err2 != nil || g.Cols < 2 || g.Cols > 26 || g.Rows < 5 || g.Rows > 99 {
+		return nil, errors.New("invalid board size: " + params)
+	}
+	return g, nil
+}
+
+type move struct {
+	cols     int
+	from, to int
+}
+
+func (m move) square(i int) string {
+	return fmt.Sprintf("%c%d", 'a'+i%m.cols, i/m.cols+1)
+}
+
+func (m move) String() string {
+	return m.square(m.from) + "-" + m.square(m.to)
+}
+
+func (g Game) parseSquare(s string) (int, bool) {
+	if len(s) < 2 || s[0] < 'a' || int(s[0]-'a') >= g.Cols {
+		return 0, false
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > g.Rows {
+		return 0, false
+	}
+	return (row-1)*g.Cols + int(s[0]-'a'), true
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		from, to, ok = strings.Cut(strings.TrimSpace(s), "x")
+	}
+	if !ok {
+		return nil, false
+	}
+	f, ok1 := g.parseSquare(from)
+	t, ok2 := g.parseSquare(to)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	return move{g.Cols, f, t}, true
+}
+
+// State is the state of a game of Breakthrough.
+type State struct {
+	cols, rows int
+	owner      []int // 0 for empty squares, or 1 + the player that occupies it
+	pieces     [2]int
+	next       int
+	moves      []string
+	winner     int // -1 while the game is in progress
+}
+
+// Settings announces the board size to players if it isn't 8x8, and the
+// position the game starts from if it isn't the usual one.
+func (g Game) Settings() []game.Setting {
+	var settings []game.Setting
+	if g.Cols != 8 || g.Rows != 8 {
+		settings = append(settings, game.Setting{Name: "size", Value: fmt.Sprintf("%dx%d", g.Cols, g.Rows)})
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	s := &State{cols: g.Cols, rows: g.Rows, owner: make([]int, g.Cols*g.Rows), winner: -1}
+	for c := 0; c < g.Cols; c++ {
+		for _, r := range []int{0, 1} {
+			s.owner[r*g.Cols+c] = 1
+			s.owner[(g.Rows-1-r)*g.Cols+c] = 2
+		}
+	}
+	s.pieces = [2]int{2 * g.Cols, 2 * g.Cols}
+	return s
+}
+
+// Position writes the board of state and the player to move (see the package
+// comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.rows)
+	for r := range rows {
+		row := make([]byte, s.cols)
+		for c := range row {
+			row[c] = ".xo"[s.owner[(s.rows-1-r)*s.cols+c]]
+		}
+		rows[r] = string(row)
+	}
+	return strings.Join(rows, "/") + ":" + "xo"[s.next:s.next+1]
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position. Positions in which a
+// player already reached the opponent's home row are rejected.
+func (g Game) parsePosition(position string) (*State, error) {
+	board, next, _ := strings.Cut(position, ":")
+	rows := strings.Split(board, "/")
+	if len(rows) != g.Rows {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Rows)
+	}
+	s := &State{cols: g.Cols, rows: g.Rows, owner: make([]int, g.Cols*g.Rows), winner: -1}
+	for i, row := range rows {
+		if len(row) != g.Cols {
+			return nil, fmt.Errorf("invalid position: expected %d squares per row", g.Cols)
+		}
+		r := g.Rows - 1 - i
+		for c := range row {
+			owner := strings.IndexByte(".xo", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid square %q", row[c])
+			}
+			if (owner == 1 && r == g.Rows-1) || (owner == 2 && r == 0) {
+				return nil, errors.New("invalid position: game already won")
+			}
+			s.owner[r*g.Cols+c] = owner
+			if owner != 0 {
+				s.pieces[owner-1]++
+			}
+		}
+	}
+	switch next {
+	case "x":
+	case "o":
+		s.next = 1
+	default:
+		return nil, errors.New("invalid position: player to move must be x or o")
+	}
+	if s.pieces[0] == 0 || s.pieces[1] == 0 {
+		return nil, errors.New("invalid position: game already won")
+	}
+	if len(s.ListMoves()) == 0 {
+		s.winner = 1 - s.next
+	}
+	return s, nil
+}
+
+func (s *State) Over() bool {
+	return s.winner >= 0
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+// forward returns the row direction in which the given player moves.
+func forward(player int) int {
+	return 1 - 2*player
+}
+
+func (s *State) valid(m move) bool {
+	if m.cols != s.cols || m.from < 0 || m.from >= len(s.owner) || m.to < 0 || m.to >= len(s.owner) {
+		return false
+	}
+	if s.owner[m.from] != s.next+1 {
+		return false
+	}
+	dr := m.to/s.cols - m.from/s.cols
+	dc := m.to%s.cols - m.from%s.cols
+	if dr != forward(s.next) || dc < -1 || dc > 1 {
+		return false
+	}
+	if dc == 0 {
+		return s.owner[m.to] == 0
+	}
+	return s.owner[m.to] != s.next+1
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	if s.Over() {
+		return moves
+	}
+	for i, owner := range s.owner {
+		if owner != s.next+1 {
+			continue
+		}
+		r, c := i/s.cols, i%s.cols
+		rr := r + forward(s.next)
+		for dc := -1; dc <= 1; dc++ {
+			if cc := c + dc; cc >= 0 && cc < s.cols {
+				if m := (move{s.cols, i, rr*s.cols + cc}); s.valid(m) {
+					moves = append(moves, m)
+				}
+			}
+		}
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || s.Over() || !s.valid(m) {
+		return false
+	}
+	if s.owner[m.to] != 0 {
+		s.pieces[1-s.next]--
+	}
+	s.owner[m.to] = s.owner[m.from]
+	s.owner[m.from] = 0
+	s.moves = append(s.moves, m.String())
+	if r := m.to / s.cols; r == 0 || r == s.rows-1 || s.pieces[1-s.next] == 0 {
+		s.winner = s.next
+	}
+	s.next = 1 - s.next
+	if !s.Over() && len(s.ListMoves()) == 0 {
+		s.winner = 1 - s.next
+	}
+	return true
+}
+
+func (s *State) Scores() (int, int) {
+	switch s.winner {
+	case 0:
+		return 1, 0
+	case 1:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board with the first player's pieces as 'X' and the second
+// player's as 'O', and row 1 at the bottom.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "X", "O"}
+	for r := s.rows - 1; r >= 0; r-- {
+		fmt.Fprintf(w, "%2d", r+1)
+		for c := 0; c < s.cols; c++ {
+			fmt.Fprint(w, " "+symbols[s.owner[r*s.cols+c]])
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprint(w, "  ")
+	for c := 0; c < s.cols; c++ {
+		fmt.Fprintf(w, " %c", 'a'+c)
+	}
+	fmt.Fprintln(w)
+}
+
+// Board returns the board for drawing, with row 1 at the bottom.
+func (s *State) Board() game.Board {
+	return game.Board{Rows: s.rows, Cols: s.cols, Owner: func(r, c int) int {
+		return s.owner[(s.rows-1-r)*s.cols+c]
+	}}
+}
@@ -0,0 +1,112 @@
+package breakthrough
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	std := Game{Cols: 8, Rows: 8}
+	// The first player's piece on a4 is one step from the top row.
+	race := "..o/x../.../.../...:x"
+	// The second player's last piece can be captured from a2.
+	last := ".../.../.o./x../...:x"
+	tests := []struct {
+		name   string
+		g      Game
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"straight", std, []string{"a2-a3"}, true, false, [2]int{0, 0}},
+		{"diagonal", std, []string{"b2-c3"}, true, false, [2]int{0, 0}},
+		{"capture notation", std, []string{"a2xa3"}, true, false, [2]int{0, 0}},
+		{"two squares", std, []string{"a2-a4"}, false, false, [2]int{0, 0}},
+		{"sideways", std, []string{"a2-b2"}, false, false, [2]int{0, 0}},
+		{"onto own piece", std, []string{"a1-a2"}, false, false, [2]int{0, 0}},
+		{"opponent's piece", std, []string{"a7-a6"}, false, false, [2]int{0, 0}},
+		{"outside the board", std, []string{"h2-i3"}, false, false, [2]int{0, 0}},
+		{"not a move", std, []string{"a2"}, false, false, [2]int{0, 0}},
+		{"reply", std, []string{"a2-a3", "a7-a6"}, true, false, [2]int{0, 0}},
+		{"backwards", std, []string{"a2-a3", "a7-a6", "a3-a2"}, false, false, [2]int{0, 0}},
+		{"blocked", std, []string{"a2-a3", "a7-a6", "a3-a4", "a6-a5", "a4-a5"}, false, false, [2]int{0, 0}},
+		{"capture", std, []string{"a2-a3", "b7-b6", "a3-a4", "b6-b5", "a4xb5"}, true, false, [2]int{0, 0}},
+		{"home row", Game{Cols: 3, Rows: 5, Start: race}, []string{"a4-a5"}, true, true, [2]int{1, 0}},
+		{"after the end", Game{Cols: 3, Rows: 5, Start: race}, []string{"a4-a5", "c5-c4"}, false, true, [2]int{1, 0}},
+		{"last piece", Game{Cols: 3, Rows: 5, Start: last}, []string{"a2xb3"}, true, true, [2]int{1, 0}},
+		{"second player wins", Game{Cols: 3, Rows: 5, Start: ".../.../x../..o/...:o"}, []string{"c2-c1"}, true, true, [2]int{0, 1}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, tt.g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := Game{Cols: 3, Rows: 5}
+	tests := []struct {
+		moves []string
+		want  string
+	}{
+		{nil, "ooo/ooo/.../xxx/xxx:x"},
+		{[]string{"b2-b3"}, "ooo/ooo/.x./x.x/xxx:o"},
+		{[]string{"b2-b3", "a4xb3"}, "ooo/.oo/.o./x.x/xxx:x"},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, g, tt.moves)
+		if got := g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := g.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		if got := g.Position(pg.CreateState()); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Cols: 3, Rows: 5}
+	for _, position := range []string{
+		"ooo/.../xxx/xxx:x",     // too few rows
+		"ooo/ooo/../xxx/xxx:x",  // short row
+		"ooo/ooo/.z./xxx/xxx:x", // invalid square
+		"ooo/ooo/.../xxx/xxx:y", // invalid player
+		"x../ooo/.../.../xxx:o", // first player on the top row
+		"ooo/.../.../.../o..:x", // second player on the bottom row
+		".../.../.../xxx/xxx:o", // second player has no pieces
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+}
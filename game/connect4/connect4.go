@@ -0,0 +1,359 @@
+// Package connect4 implements Connect Four. Players take turns dropping a
+// disc into one of the columns of an upright board, where it falls to the
+// lowest empty row. The first player to get four discs in a row,
+// horizontally, vertically or diagonally, wins, with a score of 1 against 0.
+// If the board fills up first, the game is a draw.
+//
+// Moves are written as the 1-based column number, e.g. "4" for the middle
+// column of the standard 7x6 board. Positions are written as the rows from
+// the top down, separated by '/', with 'x' for the first player's discs, 'o'
+// for the second player's and '.' for empty cells.
+package connect4
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("connect4", parseParams)
+}
+
+// Game is a Connect Four variant with a given board size.
+type Game struct {
+	Cols, Rows int
+	Start      string // position the game starts from, or "" for an empty board
+}
+
+// parseParams creates a game from the board size parameter, given as
+// "<cols>x<rows>". The default board is 7x6.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Cols: 7, Rows: 6}
+	if params == "" {
+		return g, nil
+	}
+	cols, rows, _ := strings.Cut(params, "x")
+	var err1, err2 error
+	g.Cols, err1 = strconv.Atoi(cols)
+	g.Rows, err2 = strconv.Atoi(rows)
+	if err1 != nil || err2 != nil || g.Cols < 1 || g.Cols > 99 || g.Rows < 1 || g.Rows > 99 {
+		return nil, errors.New("invalid board size: " + params)
+	}
+	return g, nil
+}
+
+// move drops a disc in a 0-based column.
+type move int
+
+func (m move) String() string {
+	return strconv.Itoa(int(m) + 1)
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	col, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || col < 1 || col > g.Cols {
+		return nil, false
+	}
+	return move(col - 1), true
+}
+
+// State is the state of a game of Connect Four.
+type State struct {
+	cols, rows int
+	owner      []int // 0 for empty cells, or 1 + the player that occupies it
+	height     []int // number of discs in each column
+	next       int
+	moves      []string
+	discs      int // number of discs on the board
+	winner     int // -1 while the game is in progress or drawn
+	over       bool
+}
+
+// Settings announces the board size to players if it isn't 7x6, and the
+// position the game starts from if it isn't an empty board.
+func (g Game) Settings() []game.Setting {
+	var settings []game.Setting
+	if g.Cols != 7 || g.Rows != 6 {
+		settings = append(settings, game.Setting{Name: "size", Value: fmt.Sprintf("%dx%d", g.Cols, g.Rows)})
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	return g.emptyState()
+}
+
+// emptyState returns the state of a game on an empty board.
+func (g Game) emptyState() *State {
+	return &State{cols: g.Cols, rows: g.Rows, owner: make([]int, g.Cols*g.Rows),
+		height: make([]int, g.Cols), winner: -1}
+}
+
+// Position writes the board of state (see the package comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.rows)
+	for r := range rows {
+		row := make([]byte, s.cols)
+		for c := range row {
+			row[c] = ".xo"[s.owner[(s.rows-1-r)*s.cols+c]]
+		}
+		rows[r] = string(row)
+	}
+	return strings.Join(rows, "/")
+}
+
+// Symmetries returns the position of state and its mirror image.
+func (g Game) Symmetries(state game.GameState) []string {
+	position := g.Position(state)
+	rows := strings.Split(position, "/")
+	for i, row := range rows {
+		b := []byte(row)
+		for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+			b[l], b[r] = b[r], b[l]
+		}
+		rows[i] = string(b)
+	}
+	return []string{position, strings.Join(rows, "/")}
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+// The player to move follows from the number of discs of each player.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state with the board given by position.
+func (g Game) parsePosition(position string) (*State, error) {
+	rows := strings.Split(position, "/")
+	if len(rows) != g.Rows {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Rows)
+	}
+	s := g.emptyState()
+	var count [3]int
+	for i, row := range rows {
+		if len(row) != g.Cols {
+			return nil, fmt.Errorf("invalid position: expected %d cells per row", g.Cols)
+		}
+		for c := range row {
+			owner := strings.IndexByte(".xo", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid cell %q", row[c])
+			}
+			s.owner[(g.Rows-1-i)*g.Cols+c] = owner
+			count[owner]++
+		}
+	}
+	for c := range s.height {
+		for s.height[c] < s.rows && s.owner[s.height[c]*s.cols+c] != 0 {
+			s.height[c]++
+		}
+		for r := s.height[c]; r < s.rows; r++ {
+			if s.owner[r*s.cols+c] != 0 {
+				return nil, fmt.Errorf("invalid position: floating disc in column %d", c+1)
+			}
+		}
+	}
+	if count[1] != count[2] && count[1] != count[2]+1 {
+		return nil, errors.New("invalid position: wrong number of discs")
+	}
+	for i, owner := range s.owner {
+		if owner != 0 && s.fourInRow(i/s.cols, i%s.cols) {
+			return nil, errors.New("invalid position: game already won")
+		}
+	}
+	s.next = count[1] - count[2]
+	s.discs = count[1] + count[2]
+	s.over = s.discs == len(s.owner)
+	return s, nil
+}
+
+func (s *State) Over() bool {
+	return s.over
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	if s.over {
+		return moves
+	}
+	for c, h := range s.height {
+		if h < s.rows {
+			moves = append(moves, move(c))
+		}
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || s.over || int(m) < 0 || int(m) >= s.cols || s.height[m] == s.rows {
+		return false
+	}
+	c, r := int(m), s.height[m]
+	s.owner[r*s.cols+c] = s.next + 1
+	s.height[m]++
+	s.moves = append(s.moves, m.String())
+	s.discs++
+	if s.fourInRow(r, c) {
+		s.winner = s.next
+		s.over = true
+	} else if s.discs == len(s.owner) {
+		s.over = true
+	}
+	s.next = 1 - s.next
+	return true
+}
+
+// fourInRow returns whether the disc at row r, column c is part of a line of
+// at least four discs of the same player.
+func (s *State) fourInRow(r, c int) bool {
+	player := s.owner[r*s.cols+c]
+	count := func(dr, dc int) int {
+		n := 0
+		for rr, cc := r+dr, c+dc; rr >= 0 && rr < s.rows && cc >= 0 && cc < s.cols &&
+			s.owner[rr*s.cols+cc] == player; rr, cc = rr+dr, cc+dc {
+			n++
+		}
+		return n
+	}
+	for _, d := range [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		if 1+count(d[0], d[1])+count(-d[0], -d[1]) >= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) Scores() (int, int) {
+	switch s.winner {
+	case 0:
+		return 1, 0
+	case 1:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Limits on solving positions: the number of empty cells left, and the number
+// of positions searched.
+const (
+	solveEmpty = 16
+	solveNodes = 1000000
+)
+
+// Solve searches the game to the end if few enough cells are empty.
+func (s *State) Solve() (int, int, bool) {
+	empty := len(s.owner) - s.discs
+	if s.over || empty > solveEmpty {
+		a, b := s.Scores()
+		return a, b, s.over
+	}
+	c := s.Clone().(*State)
+	nodes := 0
+
+	// search returns 1 if player wins with perfect play, -1 if it loses, or 0
+	// for a draw, and false if the search took too long.
+	var search func(player, empty, alpha, beta int) (int, bool)
+	search = func(player, empty, alpha, beta int) (int, bool) {
+		if nodes++; nodes > solveNodes {
+			return 0, false
+		}
+		if empty == 0 {
+			return 0, true
+		}
+		best := -1
+		for col, r := range c.height {
+			if r == c.rows {
+				continue
+			}
+			c.owner[r*c.cols+col] = player + 1
+			c.height[col]++
+			v, ok := 1, true
+			if !c.fourInRow(r, col) {
+				v, ok = search(1-player, empty-1, -beta, -alpha)
+				v = -v
+			}
+			c.owner[r*c.cols+col] = 0
+			c.height[col]--
+			if !ok {
+				return 0, false
+			}
+			best = max(best, v)
+			if alpha = max(alpha, best); alpha >= beta {
+				break
+			}
+		}
+		return best, true
+	}
+	v, ok := search(s.next, empty, -1, 1)
+	switch {
+	case !ok || v == 0:
+		return 0, 0, ok
+	case (v > 0) == (s.next == 0):
+		return 1, 0, true
+	}
+	return 0, 1, true
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.height = append([]int(nil), s.height...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board upright, with the first player's discs as 'X' and
+// the second player's as 'O'.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "X", "O"}
+	for r := s.rows - 1; r >= 0; r-- {
+		for c := 0; c < s.cols; c++ {
+			fmt.Fprintf(w, "%3s", symbols[s.owner[r*s.cols+c]])
+		}
+		fmt.Fprintln(w)
+	}
+	for c := 0; c < s.cols; c++ {
+		fmt.Fprintf(w, "%3d", c+1)
+	}
+	fmt.Fprintln(w)
+}
+
+// Board returns the board for drawing, with the top row first.
+func (s *State) Board() game.Board {
+	return game.Board{Rows: s.rows, Cols: s.cols, Owner: func(r, c int) int {
+		return s.owner[(s.rows-1-r)*s.cols+c]
+	}}
+}
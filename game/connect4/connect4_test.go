@@ -0,0 +1,102 @@
+package connect4
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	tests := []struct {
+		name   string
+		g      Game
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"first move", Game{Cols: 7, Rows: 6}, []string{"4"}, true, false, [2]int{0, 0}},
+		{"column 0", Game{Cols: 7, Rows: 6}, []string{"0"}, false, false, [2]int{0, 0}},
+		{"column 8", Game{Cols: 7, Rows: 6}, []string{"8"}, false, false, [2]int{0, 0}},
+		{"not a column", Game{Cols: 7, Rows: 6}, []string{"a"}, false, false, [2]int{0, 0}},
+		{"full column", Game{Cols: 7, Rows: 6}, []string{"1", "1", "1", "1", "1", "1", "1"}, false, false, [2]int{0, 0}},
+		{"vertical", Game{Cols: 7, Rows: 6}, []string{"1", "2", "1", "2", "1", "2", "1"}, true, true, [2]int{1, 0}},
+		{"horizontal", Game{Cols: 7, Rows: 6}, []string{"1", "2", "1", "3", "6", "4", "6", "5"}, true, true, [2]int{0, 1}},
+		{"diagonal", Game{Cols: 7, Rows: 6}, []string{"1", "2", "2", "3", "4", "3", "3", "4", "7", "4", "4"}, true, true, [2]int{1, 0}},
+		{"after the end", Game{Cols: 7, Rows: 6}, []string{"1", "2", "1", "2", "1", "2", "1", "2"}, false, true, [2]int{1, 0}},
+		{"full board", Game{Cols: 2, Rows: 2}, []string{"1", "1", "2", "2"}, true, true, [2]int{0, 0}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, tt.g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := Game{Cols: 7, Rows: 6}
+	tests := []struct {
+		moves []string
+		want  string
+		next  int
+	}{
+		{nil, "......./......./......./......./......./.......", 0},
+		{[]string{"4"}, "......./......./......./......./......./...x...", 1},
+		{[]string{"4", "4", "5"}, "......./......./......./......./...o.../...xx..", 1},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, g, append([]string{}, tt.moves...))
+		if got := g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := g.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		ps := pg.CreateState().(*State)
+		if got := g.Position(ps); got != tt.want || ps.Next() != tt.next || ps.Over() {
+			t.Errorf("%s: read as %s with player %d to move", tt.want, got, ps.Next())
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Cols: 7, Rows: 6}
+	for _, position := range []string{
+		"......./......./......./......./.......",         // too few rows
+		"......./......./......./......./......./......",  // short row
+		"......./......./......./......./......./...z...", // invalid cell
+		"......./......./......./...x.../......./.......", // floating disc
+		"......./......./......./......./......./...xx..", // too many discs of the first player
+		"......./......./......./......./......./...o...", // too many discs of the second player
+		"......./......./x....../xo...../xo...../xo.....", // four in a row
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Package game defines the interfaces that games must implement to be played
+// by the arbiter.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// GameState is the state of a game in progress.
+type GameState interface {
+	// Over returns whether the game has ended.
+	Over() bool
+	// Next returns the 0-based index of the player to move.
+	Next() int
+	// ListMoves returns all valid moves for the player to move.
+	ListMoves() []interface{}
+	// Execute plays a move, and returns whether it was valid.
+	Execute(arg interface{}) bool
+	// Scores returns the scores of both players.
+	Scores() (int, int)
+	// WriteLog writes a record of the game to w.
+	WriteLog(w io.Writer)
+}
+
+// Game creates game states and parses moves. Moves are values accepted by
+// GameState.Execute that implement fmt.Stringer.
+type Game interface {
+	CreateState() GameState
+	ParseMove(s string) (interface{}, bool)
+}
+
+// SimultaneousState may be implemented by game states of games in which both
+// players move at the same time, without seeing each other's move. While
+// Simultaneous returns true, both players' moves are played at once with
+// ExecuteBoth, and Next, ListMoves and Execute are not used.
+type SimultaneousState interface {
+	GameState
+	// Simultaneous returns whether both players move next.
+	Simultaneous() bool
+	// PlayerMoves returns all valid moves for the given player.
+	PlayerMoves(player int) []interface{}
+	// ExecuteBoth plays a move for each player, and returns whether each move
+	// was valid. If either move is invalid, the state is left unchanged.
+	ExecuteBoth(moves [2]interface{}) [2]bool
+}
+
+// IsSimultaneous returns whether both players move next in state.
+func IsSimultaneous(state GameState) bool {
+	ss, ok := state.(SimultaneousState)
+	return ok && ss.Simultaneous()
+}
+
+// Viewer may be implemented by games with hidden information, such as card
+// games or games with fog of war. Players of these games are not told their
+// opponent's moves; instead, they are sent their own view of the game state
+// before each move they make. The arbiter keeps the full state.
+type Viewer interface {
+	// PlayerView returns the part of state that the given player may know
+	// about, as a single line of text.
+	PlayerView(state GameState, player int) string
+}
+
+// Dealer may be implemented by games whose initial state is random, such as
+// games in which tiles are dealt to the players or obstacles are placed at
+// random. The arbiter picks a seed for each game, which is recorded in the
+// game log, and tells each player its setup before the first move.
+type Dealer interface {
+	// WithDeal returns a copy of the game whose CreateState deals the initial
+	// state using the given seed. The same seed must always give the same
+	// initial state.
+	WithDeal(seed int64) Game
+	// Setup returns what the given player may know about the initial state,
+	// as settings that are announced after those of the game.
+	Setup(state GameState, player int) []Setting
+}
+
+// WithDeal returns g with its initial state dealt using seed, or g itself if
+// it doesn't implement Dealer.
+func WithDeal(g Game, seed int64) Game {
+	if d, ok := g.(Dealer); ok {
+		return d.WithDeal(seed)
+	}
+	return g
+}
+
+// Cloner may be implemented by game states that can be copied, so that tools
+// can explore different continuations from the same position.
+type Cloner interface {
+	// Clone returns a copy of the state that can be changed independently.
+	Clone() GameState
+}
+
+// Recorder may be implemented by game states that keep track of the moves
+// played so far.
+type Recorder interface {
+	// History returns the moves played so far, in the form accepted by
+	// Game.ParseMove. In turns where both players move at once, both moves
+	// are listed, the first player's first.
+	History() []string
+}
+
+// History returns the moves played in state, and whether it implements
+// Recorder.
+func History(state GameState) ([]string, bool) {
+	if r, ok := state.(Recorder); ok {
+		return r.History(), true
+	}
+	return nil, false
+}
+
+// Adjudicator may be implemented by game states that can determine a
+// reasonable final score for a game that has not finished yet.
+type Adjudicator interface {
+	Adjudicate() (int, int)
+}
+
+// Solver may be implemented by game states whose outcome can be determined
+// before the game is over, such as endgames small enough to search to the
+// end. The arbiter may then end the game early.
+type Solver interface {
+	// Solve returns the final scores with perfect play from the current
+	// position, and whether they could be determined. It must not change
+	// the state.
+	Solve() (score1, score2 int, ok bool)
+}
+
+// Scorer may be implemented by games to define how the final scores of a game
+// translate into competition points. Games that don't implement it use
+// DefaultPoints.
+type Scorer interface {
+	// Points returns each player's competition points, given the final scores
+	// and whether each player failed during the game.
+	Points(score [2]int, failed [2]bool) [2]int
+}
+
+// DefaultPoints implements the CodeCup rule: a player that doesn't fail gets
+// 1 point, plus 1 more if it won the game.
+func DefaultPoints(score [2]int, failed [2]bool) [2]int {
+	var points [2]int
+	for i := range points {
+		if !failed[i] {
+			points[i] = 1
+			if score[i] > score[1-i] {
+				points[i] += 1
+			}
+		}
+	}
+	return points
+}
+
+// Points returns the competition points of the players of g, given the final
+// scores and whether each player failed, using DefaultPoints if g doesn't
+// implement Scorer.
+func Points(g Game, score [2]int, failed [2]bool) [2]int {
+	if s, ok := g.(Scorer); ok {
+		return s.Points(score, failed)
+	}
+	return DefaultPoints(score, failed)
+}
+
+// Setting is a game setting that players are told about at the start of a
+// game, e.g. {"size", "9"}.
+type Setting struct {
+	Name, Value string
+}
+
+// Announcer may be implemented by games with settings that players must know
+// before the game starts, such as a non-standard board size.
+type Announcer interface {
+	Settings() []Setting
+}
+
+// Settings returns the settings announced by g, or nil if it doesn't
+// implement Announcer.
+func Settings(g Game) []Setting {
+	if a, ok := g.(Announcer); ok {
+		return a.Settings()
+	}
+	return nil
+}
+
+// Renderer may be implemented by game states that can draw the board as text,
+// for display to humans.
+type Renderer interface {
+	Render(w io.Writer)
+}
+
+// RenderBoard draws the board of state to w, using its Render method, or its
+// String method if it doesn't implement Renderer. It returns false if the
+// state supports neither.
+func RenderBoard(w io.Writer, state GameState) bool {
+	if r, ok := state.(Renderer); ok {
+		r.Render(w)
+	} else if s, ok := state.(fmt.Stringer); ok {
+		fmt.Fprintln(w, s.String())
+	} else {
+		return false
+	}
+	return true
+}
+
+// Handicap makes a game between players of different strength more even.
+type Handicap struct {
+	Komi   int // points added to the second player's score
+	Stones int // number of extra moves the first player makes at the start
+}
+
+// Handicapper may be implemented by games that support handicaps. Games
+// announce a non-zero handicap through their settings.
+type Handicapper interface {
+	// WithHandicap returns a copy of the game with the given handicap, or an
+	// error if the game doesn't support it.
+	WithHandicap(h Handicap) (Game, error)
+	// Handicap returns the handicap of the game.
+	Handicap() Handicap
+}
+
+// WithHandicap returns g with the given handicap. It returns g itself if the
+// handicap is zero, and an error if g doesn't implement Handicapper.
+func WithHandicap(g Game, h Handicap) (Game, error) {
+	if h == (Handicap{}) {
+		return g, nil
+	}
+	if hg, ok := g.(Handicapper); ok {
+		return hg.WithHandicap(h)
+	}
+	return nil, errors.New("game doesn't support handicaps")
+}
+
+// HandicapOf returns the handicap of g, which is zero if g doesn't implement
+// Handicapper.
+func HandicapOf(g Game) Handicap {
+	if hg, ok := g.(Handicapper); ok {
+		return hg.Handicap()
+	}
+	return Handicap{}
+}
+
+// Positioner may be implemented by games whose positions can be written in a
+// compact notation, like FEN in chess, so that games can start from any
+// position and positions can be analyzed by other tools. Games that start
+// from a position set with WithPosition announce it through their settings,
+// as the setting "position".
+type Positioner interface {
+	// Position returns the notation of the position of state, as a single
+	// word.
+	Position(state GameState) string
+	// WithPosition returns a copy of the game that starts from the given
+	// position, or an error if the position is invalid.
+	WithPosition(position string) (Game, error)
+}
+
+// WithPosition returns g starting from the given position. It returns g itself
+// if position is empty, and an error if g doesn't implement Positioner.
+func WithPosition(g Game, position string) (Game, error) {
+	if position == "" {
+		return g, nil
+	}
+	if p, ok := g.(Positioner); ok {
+		return p.WithPosition(position)
+	}
+	return nil, errors.New("game doesn't support starting from a position")
+}
+
+// Position returns the notation of the position of state, a state of g, or ""
+// if g doesn't implement Positioner.
+func Position(g Game, state GameState) string {
+	if p, ok := g.(Positioner); ok {
+		return p.Position(state)
+	}
+	return ""
+}
+
+// Symmetric may be implemented by games with positions (see Positioner) whose
+// rules don't change under some symmetries of the board, such as reflections
+// and rotations, so that positions mapped onto each other by them are
+// effectively the same.
+type Symmetric interface {
+	// Symmetries returns the notations of all positions equivalent to the
+	// position of state, including its own.
+	Symmetries(state GameState) []string
+}
+
+// CanonicalPosition returns a notation of the position of state, a state of g,
+// that is the same for all equivalent positions: the smallest of its
+// symmetries, or its own notation if g doesn't implement Symmetric. It returns
+// "" if g doesn't implement Positioner.
+func CanonicalPosition(g Game, state GameState) string {
+	position := Position(g, state)
+	if s, ok := g.(Symmetric); ok {
+		for _, p := range s.Symmetries(state) {
+			if p < position {
+				position = p
+			}
+		}
+	}
+	return position
+}
+
+// Settings returns the settings for a non-zero handicap, for use in
+// Announcer implementations.
+func (h Handicap) Settings() []Setting {
+	var settings []Setting
+	if h.Komi != 0 {
+		settings = append(settings, Setting{Name: "komi", Value: strconv.Itoa(h.Komi)})
+	}
+	if h.Stones != 0 {
+		settings = append(settings, Setting{Name: "handicap", Value: strconv.Itoa(h.Stones)})
+	}
+	return settings
+}
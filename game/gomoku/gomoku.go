@@ -0,0 +1,352 @@
+// Package gomoku implements freestyle Gomoku. Players take turns placing a
+// stone on an empty intersection, and the first player to get five or more
+// stones in a row, horizontally, vertically or diagonally, wins, with a score
+// of 1 against 0. If the board fills up first, the game is a draw.
+//
+// Intersections are written as a column letter followed by a row number, e.g.
+// "h8" for the center of the standard 15x15 board.
+//
+// Optional opening restrictions are supported: with the "pro" rule, the first
+// stone must be placed in the center, and the first player's second stone at
+// least 3 intersections away from it ("longpro": at least 4). With "exact",
+// only rows of exactly five stones win, as in standard Gomoku.
+//
+// A handicap of n stones lets the first player make n extra moves at the start
+// of the game. Handicaps can't be combined with the opening rules.
+//
+// Positions are written as the rows from the top row down, separated by '/',
+// with 'x' and 'o' for the stones of the first and second player and '.' for
+// empty intersections, followed by ':' and the player to move, e.g. ":x" if
+// the first player moves next. The opening rules don't apply to games that
+// start from a position.
+package gomoku
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("gomoku", parseParams)
+}
+
+// Game is a Gomoku variant.
+type Game struct {
+	Size     int    // number of rows and columns
+	Distance int    // minimum distance of the third stone from the center, or 0 for no opening rule
+	Exact    bool   // whether overlines (six or more in a row) don't win
+	Stones   int    // number of extra moves of the first player at the start
+	Start    string // position the game starts from, or "" for an empty board
+}
+
+// parseParams creates a game from parameters of the form
+// "[size][,pro|,longpro][,exact]", e.g. "19" or "15,pro". The default is a
+// 15x15 board without restrictions.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Size: 15}
+	if params == "" {
+		return g, nil
+	}
+	for _, param := range strings.Split(params, ",") {
+		switch param {
+		case "pro":
+			g.Distance = 3
+		case "longpro":
+			g.Distance = 4
+		case "exact":
+			g.Exact = true
+		default:
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 5 || n > 26 {
+				return nil, errors.New("invalid parameter: " + param)
+			}
+			g.Size = n
+		}
+	}
+	return g, nil
+}
+
+type move struct {
+	size  int
+	point int
+}
+
+func (m move) String() string {
+	return fmt.Sprintf("%c%d", 'a'+m.point%m.size, m.point/m.size+1)
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] < 'a' || int(s[0]-'a') >= g.Size {
+		return nil, false
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > g.Size {
+		return nil, false
+	}
+	return move{g.Size, (row-1)*g.Size + int(s[0]-'a')}, true
+}
+
+// State is the state of a game of Gomoku.
+type State struct {
+	Game
+	owner  []int // 0 for empty points, or 1 + the player that occupies it
+	filled int   // number of occupied points
+	next   int
+	moves  []string
+	winner int // -1 while the game is in progress or drawn
+	over   bool
+}
+
+// Settings announces the board size to players if it isn't 15, the handicap
+// if there is one, and the position the game starts from if it isn't an empty
+// board.
+func (g Game) Settings() []game.Setting {
+	settings := g.Handicap().Settings()
+	if g.Size != 15 {
+		settings = append([]game.Setting{{Name: "size", Value: strconv.Itoa(g.Size)}}, settings...)
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+// WithHandicap supports handicap stones, but not komi.
+func (g Game) WithHandicap(h game.Handicap) (game.Game, error) {
+	if h.Komi != 0 {
+		return nil, errors.New("komi is not supported")
+	}
+	if h.Stones < 0 || h.Stones >= g.Size*g.Size {
+		return nil, errors.New("invalid handicap: " + strconv.Itoa(h.Stones))
+	}
+	if h.Stones > 0 && g.Distance > 0 {
+		return nil, errors.New("handicap can't be combined with an opening rule")
+	}
+	g.Stones = h.Stones
+	return g, nil
+}
+
+func (g Game) Handicap() game.Handicap {
+	return game.Handicap{Stones: g.Stones}
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	return &State{Game: g, owner: make([]int, g.Size*g.Size), winner: -1}
+}
+
+// Position writes the board of state and the player to move (see the package
+// comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.Size)
+	for r := range rows {
+		row := make([]byte, s.Size)
+		for c := range row {
+			row[c] = ".xo"[s.owner[(s.Size-1-r)*s.Size+c]]
+		}
+		rows[r] = string(row)
+	}
+	return strings.Join(rows, "/") + ":" + "xo"[s.next:s.next+1]
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+// Positions can't be combined with handicap stones.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if g.Stones != 0 {
+		return nil, errors.New("a position can't be combined with handicap stones")
+	}
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position.
+func (g Game) parsePosition(position string) (*State, error) {
+	board, next, _ := strings.Cut(position, ":")
+	rows := strings.Split(board, "/")
+	if len(rows) != g.Size {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Size)
+	}
+	s := &State{Game: g, owner: make([]int, g.Size*g.Size), winner: -1}
+	s.Distance = 0
+	for r, row := range rows {
+		if len(row) != g.Size {
+			return nil, fmt.Errorf("invalid position: expected %d intersections per row", g.Size)
+		}
+		for c := range row {
+			owner := strings.IndexByte(".xo", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid intersection %q", row[c])
+			}
+			s.owner[(g.Size-1-r)*g.Size+c] = owner
+			if owner != 0 {
+				s.filled++
+			}
+		}
+	}
+	switch next {
+	case "x":
+	case "o":
+		s.next = 1
+	default:
+		return nil, errors.New("invalid position: player to move must be x or o")
+	}
+	for i, owner := range s.owner {
+		if owner != 0 && s.fiveInRow(i) {
+			return nil, errors.New("invalid position: game already won")
+		}
+	}
+	s.over = s.filled == len(s.owner)
+	return s, nil
+}
+
+func (s *State) Over() bool {
+	return s.over
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+// allowed returns whether the opening rule allows a stone on point i.
+func (s *State) allowed(i int) bool {
+	if s.Distance == 0 {
+		return true
+	}
+	center := s.Size / 2
+	dr, dc := i/s.Size-center, i%s.Size-center
+	switch len(s.moves) {
+	case 0:
+		return dr == 0 && dc == 0
+	case 2:
+		return abs(dr) >= s.Distance || abs(dc) >= s.Distance
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	if s.over {
+		return moves
+	}
+	for i, owner := range s.owner {
+		if owner == 0 && s.allowed(i) {
+			moves = append(moves, move{s.Size, i})
+		}
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || m.size != s.Size || s.over || m.point < 0 || m.point >= len(s.owner) ||
+		s.owner[m.point] != 0 || !s.allowed(m.point) {
+		return false
+	}
+	s.owner[m.point] = s.next + 1
+	s.filled++
+	s.moves = append(s.moves, m.String())
+	if s.fiveInRow(m.point) {
+		s.winner = s.next
+		s.over = true
+	} else if s.filled == len(s.owner) {
+		s.over = true
+	}
+	if len(s.moves) > s.Stones {
+		s.next = 1 - s.next
+	}
+	return true
+}
+
+// fiveInRow returns whether the stone on point i completes a winning row.
+func (s *State) fiveInRow(i int) bool {
+	player := s.owner[i]
+	r, c := i/s.Size, i%s.Size
+	count := func(dr, dc int) int {
+		n := 0
+		for rr, cc := r+dr, c+dc; rr >= 0 && rr < s.Size && cc >= 0 && cc < s.Size &&
+			s.owner[rr*s.Size+cc] == player; rr, cc = rr+dr, cc+dc {
+			n++
+		}
+		return n
+	}
+	for _, d := range [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		n := 1 + count(d[0], d[1]) + count(-d[0], -d[1])
+		if n == 5 || (n > 5 && !s.Exact) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) Scores() (int, int) {
+	switch s.winner {
+	case 0:
+		return 1, 0
+	case 1:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board with the first player's stones as 'X' and the second
+// player's as 'O'.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "X", "O"}
+	fmt.Fprint(w, "  ")
+	for c := 0; c < s.Size; c++ {
+		fmt.Fprintf(w, " %c", 'a'+c)
+	}
+	fmt.Fprintln(w)
+	for r := s.Size - 1; r >= 0; r-- {
+		fmt.Fprintf(w, "%2d", r+1)
+		for c := 0; c < s.Size; c++ {
+			fmt.Fprint(w, " "+symbols[s.owner[r*s.Size+c]])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Board returns the board for drawing, with row 1 at the bottom.
+func (s *State) Board() game.Board {
+	return game.Board{Rows: s.Size, Cols: s.Size, Owner: func(r, c int) int {
+		return s.owner[(s.Size-1-r)*s.Size+c]
+	}}
+}
@@ -0,0 +1,115 @@
+package gomoku
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	free := Game{Size: 15}
+	five := []string{"a1", "a2", "b1", "b2", "c1", "c2", "d1", "d2", "e1"}
+	// The first player's last move makes six in a row.
+	six := []string{"a1", "a2", "b1", "b2", "c1", "c2", "d1", "d3", "f1", "a3", "e1"}
+	// The first player fills the board without a row of five.
+	full := "xxoo./ooxxo/xxoox/ooxxo/xxoox:x"
+	tests := []struct {
+		name   string
+		g      Game
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"first move", free, []string{"h8"}, true, false, [2]int{0, 0}},
+		{"column outside the board", free, []string{"p1"}, false, false, [2]int{0, 0}},
+		{"row outside the board", free, []string{"a16"}, false, false, [2]int{0, 0}},
+		{"occupied", free, []string{"h8", "h8"}, false, false, [2]int{0, 0}},
+		{"five in a row", free, five, true, true, [2]int{1, 0}},
+		{"after the end", free, append(five[:len(five):len(five)], "e2"), false, true, [2]int{1, 0}},
+		{"second player wins", free, []string{"a1", "b1", "a2", "b2", "a3", "b3", "a4", "b4", "c1", "b5"}, true, true, [2]int{0, 1}},
+		{"diagonal", free, []string{"a1", "a2", "b2", "a3", "c3", "a4", "d4", "a5", "e5"}, true, true, [2]int{1, 0}},
+		{"overline", free, six, true, true, [2]int{1, 0}},
+		{"exact overline", Game{Size: 15, Exact: true}, six, true, false, [2]int{0, 0}},
+		{"full board", Game{Size: 5, Start: full}, []string{"e5"}, true, true, [2]int{0, 0}},
+		{"pro center", Game{Size: 15, Distance: 3}, []string{"h8"}, true, false, [2]int{0, 0}},
+		{"pro off center", Game{Size: 15, Distance: 3}, []string{"a1"}, false, false, [2]int{0, 0}},
+		{"pro third stone near", Game{Size: 15, Distance: 3}, []string{"h8", "a1", "h10"}, false, false, [2]int{0, 0}},
+		{"pro third stone far", Game{Size: 15, Distance: 3}, []string{"h8", "a1", "h11"}, true, false, [2]int{0, 0}},
+		{"longpro third stone", Game{Size: 15, Distance: 4}, []string{"h8", "a1", "h11"}, false, false, [2]int{0, 0}},
+		{"handicap", Game{Size: 15, Stones: 1}, []string{"h8", "h9", "a1"}, true, false, [2]int{0, 0}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, tt.g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		g     Game
+		moves []string
+		want  string
+	}{
+		{Game{Size: 5}, nil, "...../...../...../...../.....:x"},
+		{Game{Size: 5}, []string{"c3"}, "...../...../..x../...../.....:o"},
+		{Game{Size: 5}, []string{"c3", "a1", "e5"}, "....x/...../..x../...../o....:o"},
+		{Game{Size: 5, Stones: 1}, []string{"c3", "c4"}, "...../..x../..x../...../.....:o"},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, tt.g, tt.moves)
+		if got := tt.g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := Game{Size: 5}.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		if got := tt.g.Position(pg.CreateState()); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Size: 5}
+	for _, position := range []string{
+		"...../...../...../.....:x",       // too few rows
+		"...../...../...../...../....:x",  // short row
+		"...../...../..z../...../.....:x", // invalid intersection
+		"...../...../...../...../.....:y", // invalid player
+		"...../...../...../...../xxxxx:o", // five in a row
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+	if _, err := (Game{Size: 5, Stones: 1}).WithPosition("...../...../...../...../.....:x"); err == nil {
+		t.Error("no error for a position with handicap stones")
+	}
+}
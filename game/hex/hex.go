@@ -0,0 +1,410 @@
+// Package hex implements Hex, played on a rhombic board of hexagonal cells.
+// The first player tries to connect the top and bottom rows of the board, the
+// second player the left and right columns. The player who connects their
+// sides wins, with a score of 1 against 0.
+//
+// Cells are written as a column letter followed by a row number, e.g. "a1" is
+// the top left corner. With the swap rule, the second player may answer the
+// first move with "swap", which replaces the first player's stone by one of
+// their own, mirrored in the board's long diagonal. The first player then
+// moves again.
+//
+// A handicap of n stones lets the first player make n extra moves at the start
+// of the game. The swap rule doesn't apply to handicap games.
+//
+// Positions are written as the rows from row 1 down, separated by '/', with
+// 'x' and 'o' for the stones of the first and second player and '.' for
+// empty cells, followed by ':' and the player to move, e.g. ":x" if the
+// first player moves next. The swap rule doesn't apply to games that start
+// from a position.
+package hex
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("hex", parseParams)
+}
+
+// DefaultSize is the size of the standard board.
+const DefaultSize = 11
+
+// swapToken is the move that invokes the swap rule.
+const swapToken = "swap"
+
+// Game is a Hex variant.
+type Game struct {
+	Size   int    // number of rows and columns
+	Swap   bool   // whether the swap rule is used
+	Stones int    // number of extra moves of the first player at the start
+	Start  string // position the game starts from, or "" for an empty board
+}
+
+// parseParams creates a game from parameters of the form "[size][,noswap]",
+// e.g. "13" or "9,noswap". The swap rule is enabled by default.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Size: DefaultSize, Swap: true}
+	if params == "" {
+		return g, nil
+	}
+	for _, param := range strings.Split(params, ",") {
+		switch param {
+		case "swap":
+			g.Swap = true
+		case "noswap":
+			g.Swap = false
+		default:
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 || n > 26 {
+				return nil, errors.New("invalid parameter: " + param)
+			}
+			g.Size = n
+		}
+	}
+	return g, nil
+}
+
+// move places a stone on a cell, or swaps if cell is negative.
+type move struct {
+	size int
+	cell int
+}
+
+func (m move) String() string {
+	if m.cell < 0 {
+		return swapToken
+	}
+	return fmt.Sprintf("%c%d", 'a'+m.cell%m.size, m.cell/m.size+1)
+}
+
+// Settings announces the board size to players if it isn't the standard size,
+// the handicap if there is one, and the position the game starts from if it
+// isn't an empty board.
+func (g Game) Settings() []game.Setting {
+	settings := g.Handicap().Settings()
+	if g.Size != DefaultSize {
+		settings = append([]game.Setting{{Name: "size", Value: strconv.Itoa(g.Size)}}, settings...)
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+// WithHandicap supports handicap stones, but not komi.
+func (g Game) WithHandicap(h game.Handicap) (game.Game, error) {
+	if h.Komi != 0 {
+		return nil, errors.New("komi is not supported")
+	}
+	if h.Stones < 0 || h.Stones >= g.Size*g.Size {
+		return nil, errors.New("invalid handicap: " + strconv.Itoa(h.Stones))
+	}
+	g.Stones = h.Stones
+	return g, nil
+}
+
+func (g Game) Handicap() game.Handicap {
+	return game.Handicap{Stones: g.Stones}
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	return &State{size: g.Size, swap: g.Swap && g.Stones == 0, stones: g.Stones,
+		owner: make([]int, g.Size*g.Size), winner: -1}
+}
+
+// Position writes the board of state and the player to move (see the package
+// comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.size)
+	for r := range rows {
+		row := make([]byte, s.size)
+		for c := range row {
+			row[c] = ".xo"[s.owner[r*s.size+c]]
+		}
+		rows[r] = string(row)
+	}
+	return strings.Join(rows, "/") + ":" + "xo"[s.next:s.next+1]
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+// Positions can't be combined with handicap stones.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if g.Stones != 0 {
+		return nil, errors.New("a position can't be combined with handicap stones")
+	}
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position.
+func (g Game) parsePosition(position string) (*State, error) {
+	board, next, _ := strings.Cut(position, ":")
+	rows := strings.Split(board, "/")
+	if len(rows) != g.Size {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Size)
+	}
+	s := &State{size: g.Size, owner: make([]int, g.Size*g.Size), winner: -1}
+	for r, row := range rows {
+		if len(row) != g.Size {
+			return nil, fmt.Errorf("invalid position: expected %d cells per row", g.Size)
+		}
+		for c := range row {
+			owner := strings.IndexByte(".xo", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid cell %q", row[c])
+			}
+			s.owner[r*g.Size+c] = owner
+		}
+	}
+	switch next {
+	case "x":
+	case "o":
+		s.next = 1
+	default:
+		return nil, errors.New("invalid position: player to move must be x or o")
+	}
+	for i, owner := range s.owner {
+		if owner != 0 && s.connects(i) {
+			return nil, errors.New("invalid position: game already won")
+		}
+	}
+	return s, nil
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if s == swapToken {
+		return move{g.Size, -1}, true
+	}
+	if len(s) < 2 || s[0] < 'a' || int(s[0]-'a') >= g.Size {
+		return nil, false
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > g.Size {
+		return nil, false
+	}
+	return move{g.Size, (row-1)*g.Size + int(s[0]-'a')}, true
+}
+
+// State is the state of a game of Hex.
+type State struct {
+	size   int
+	swap   bool
+	stones int   // number of extra moves of the first player
+	owner  []int // 0 for empty cells, or 1 + the player that occupies it
+	next   int
+	moves  []string
+	winner int // -1 while the game is in progress
+}
+
+func (s *State) Over() bool {
+	return s.winner >= 0
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+// canSwap returns whether the player to move can invoke the swap rule.
+func (s *State) canSwap() bool {
+	return s.swap && len(s.moves) == 1
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	if s.Over() {
+		return moves
+	}
+	if s.canSwap() {
+		moves = append(moves, move{s.size, -1})
+	}
+	for i, owner := range s.owner {
+		if owner == 0 {
+			moves = append(moves, move{s.size, i})
+		}
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || m.size != s.size || s.Over() {
+		return false
+	}
+	if m.cell < 0 {
+		if !s.canSwap() {
+			return false
+		}
+		for i, owner := range s.owner {
+			if owner != 0 {
+				r, c := i/s.size, i%s.size
+				s.owner[i] = 0
+				s.owner[c*s.size+r] = s.next + 1
+				break
+			}
+		}
+	} else {
+		if m.cell >= len(s.owner) || s.owner[m.cell] != 0 {
+			return false
+		}
+		s.owner[m.cell] = s.next + 1
+		if s.connects(m.cell) {
+			s.winner = s.next
+		}
+	}
+	s.moves = append(s.moves, m.String())
+	if len(s.moves) > s.stones {
+		s.next = 1 - s.next
+	}
+	return true
+}
+
+// neighbors returns the cells adjacent to cell i.
+func (s *State) neighbors(i int) []int {
+	var result []int
+	r, c := i/s.size, i%s.size
+	for _, d := range [6][2]int{{-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}} {
+		if rr, cc := r+d[0], c+d[1]; rr >= 0 && rr < s.size && cc >= 0 && cc < s.size {
+			result = append(result, rr*s.size+cc)
+		}
+	}
+	return result
+}
+
+// connects returns whether the group containing cell start connects its
+// owner's sides of the board.
+func (s *State) connects(start int) bool {
+	player := s.owner[start]
+	var lo, hi bool
+	visited := map[int]bool{start: true}
+	queue := []int{start}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		pos := i / s.size // row for the first player
+		if player == 2 {
+			pos = i % s.size // column for the second player
+		}
+		lo = lo || pos == 0
+		hi = hi || pos == s.size-1
+		for _, j := range s.neighbors(i) {
+			if !visited[j] && s.owner[j] == player {
+				visited[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	return lo && hi
+}
+
+// Solve determines the winner if it's decided by the next move: the player to
+// move wins if they can complete a chain right away, and the other player wins
+// if they have two ways to complete one, since only one can be blocked. It
+// doesn't search, so games are only solved at the very end.
+func (s *State) Solve() (int, int, bool) {
+	if s.Over() || s.canSwap() || len(s.moves) < s.stones {
+		a, b := s.Scores()
+		return a, b, s.Over()
+	}
+	winner := -1
+	switch {
+	case s.winningCells(s.next) > 0:
+		winner = s.next
+	case s.winningCells(1-s.next) > 1:
+		winner = 1 - s.next
+	default:
+		return 0, 0, false
+	}
+	if winner == 0 {
+		return 1, 0, true
+	}
+	return 0, 1, true
+}
+
+// winningCells returns the number of empty cells that would connect player's
+// sides of the board if they placed a stone there, counting at most two.
+func (s *State) winningCells(player int) int {
+	n := 0
+	for i := range s.owner {
+		if s.owner[i] != 0 {
+			continue
+		}
+		s.owner[i] = player + 1
+		if s.connects(i) {
+			n++
+		}
+		s.owner[i] = 0
+		if n == 2 {
+			break
+		}
+	}
+	return n
+}
+
+func (s *State) Scores() (int, int) {
+	switch s.winner {
+	case 0:
+		return 1, 0
+	case 1:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board as a rhombus. Stones of the first player are shown
+// as 'X', those of the second player as 'O'.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "X", "O"}
+	fmt.Fprint(w, "   ")
+	for c := 0; c < s.size; c++ {
+		fmt.Fprintf(w, " %c", 'a'+c)
+	}
+	fmt.Fprintln(w)
+	for r := 0; r < s.size; r++ {
+		fmt.Fprintf(w, "%s%2d ", strings.Repeat(" ", r), r+1)
+		for c := 0; c < s.size; c++ {
+			fmt.Fprint(w, " "+symbols[s.owner[r*s.size+c]])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Board returns the board for drawing as a rhombus, like Render.
+func (s *State) Board() game.Board {
+	return game.Board{Rows: s.size, Cols: s.size, Shift: 0.5, Owner: func(r, c int) int {
+		return s.owner[r*s.size+c]
+	}}
+}
@@ -0,0 +1,152 @@
+package hex
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	swap := Game{Size: 3, Swap: true}
+	noswap := Game{Size: 3}
+	tests := []struct {
+		name   string
+		g      Game
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"first move", swap, []string{"b2"}, true, false, [2]int{0, 0}},
+		{"column outside the board", swap, []string{"d1"}, false, false, [2]int{0, 0}},
+		{"row outside the board", swap, []string{"a4"}, false, false, [2]int{0, 0}},
+		{"occupied", swap, []string{"b2", "b2"}, false, false, [2]int{0, 0}},
+		{"swap", swap, []string{"b1", "swap"}, true, false, [2]int{0, 0}},
+		{"swap as first move", swap, []string{"swap"}, false, false, [2]int{0, 0}},
+		{"late swap", swap, []string{"b1", "a1", "swap"}, false, false, [2]int{0, 0}},
+		{"swap disabled", noswap, []string{"b1", "swap"}, false, false, [2]int{0, 0}},
+		{"first player connects", noswap, []string{"a1", "b1", "a2", "c1", "a3"}, true, true, [2]int{1, 0}},
+		{"second player connects", noswap, []string{"a1", "a3", "b1", "b3", "a2", "c3"}, true, true, [2]int{0, 1}},
+		{"after the end", noswap, []string{"a1", "b1", "a2", "c1", "a3", "b2"}, false, true, [2]int{1, 0}},
+		{"bent chain", noswap, []string{"c1", "a1", "b2", "b1", "a3"}, true, true, [2]int{1, 0}},
+		{"handicap", Game{Size: 3, Stones: 1}, []string{"a1", "a2", "b2"}, true, false, [2]int{0, 0}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, tt.g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		g     Game
+		moves []string
+		want  string
+	}{
+		{Game{Size: 3, Swap: true}, nil, ".../.../...:x"},
+		{Game{Size: 3, Swap: true}, []string{"b1"}, ".x./.../...:o"},
+		{Game{Size: 3, Swap: true}, []string{"b1", "swap"}, ".../o../...:x"},
+		{Game{Size: 3}, []string{"a1", "c3"}, "x../.../..o:x"},
+		{Game{Size: 3, Stones: 1}, []string{"a1", "a2"}, "x../x../...:o"},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, tt.g, tt.moves)
+		if got := tt.g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := Game{Size: 3, Swap: true}.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		ps := pg.CreateState().(*State)
+		if got := tt.g.Position(ps); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+		if ps.canSwap() {
+			t.Errorf("%s: swap allowed from a position", tt.want)
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Size: 3}
+	for _, position := range []string{
+		".../...:x",     // too few rows
+		".../../...:x",  // short row
+		".../.z./...:x", // invalid cell
+		".../.../...:y", // invalid player
+		"x../x../x..:o", // the first player connected
+		".../ooo/...:x", // the second player connected
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+	if _, err := (Game{Size: 3, Stones: 1}).WithPosition(".../.../...:x"); err == nil {
+		t.Error("no error for a position with handicap stones")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		position       string
+		score1, score2 int
+		ok             bool
+	}{
+		{"x../x../...:o", 0, 0, false}, // only one way to complete the chain
+		{".x./.x./...:x", 1, 0, true},  // player to move completes the chain
+		{".x./.x./...:o", 1, 0, true},  // two ways to complete the chain
+		{".../oo./...:o", 0, 1, true},
+		{".../oo./...:x", 0, 1, true},
+		{".../.o./...:x", 0, 0, false},
+	}
+	for _, tt := range tests {
+		g, err := Game{Size: 3}.WithPosition(tt.position)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.position, err)
+		}
+		s := g.CreateState().(*State)
+		score1, score2, ok := s.Solve()
+		if score1 != tt.score1 || score2 != tt.score2 || ok != tt.ok {
+			t.Errorf("%s: got %d, %d, %v, want %d, %d, %v", tt.position, score1, score2, ok, tt.score1, tt.score2, tt.ok)
+		}
+		if p := g.(Game).Position(s); p != tt.position {
+			t.Errorf("%s: position changed to %s", tt.position, p)
+		}
+	}
+}
+
+func TestSolveSwap(t *testing.T) {
+	g := Game{Size: 3, Swap: true}
+	s := g.CreateState().(*State)
+	m, _ := g.ParseMove("b2")
+	s.Execute(m)
+	if _, _, ok := s.Solve(); ok {
+		t.Error("solved while the swap rule may be invoked")
+	}
+}
@@ -0,0 +1,430 @@
+// Package othello implements Othello (Reversi). Players take turns placing a
+// disc of their color so that it flanks one or more lines of the opponent's
+// discs, which are then flipped. A player who cannot flank any discs must
+// pass, by playing the move "pass"; passing is not allowed otherwise. The
+// game ends when neither player can move, and each player's score is the
+// number of discs of their color on the board.
+//
+// The first player plays black. Squares are written as a column letter
+// followed by a row number, e.g. "d3". Komi is added to white's disc count.
+// Positions are written as the rows from row 1 down, separated by '/', with
+// 'x' for black discs, 'o' for white discs and '.' for empty squares,
+// followed by ':' and the color to move, e.g. ":x" if black moves next.
+package othello
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("othello", parseParams)
+}
+
+// passToken is the move played by a player without valid moves.
+const passToken = "pass"
+
+// Game is an Othello variant with a given board size.
+type Game struct {
+	Size  int
+	Komi  int    // added to the second player's score
+	Start string // position the game starts from, or "" for the usual one
+}
+
+// parseParams creates a game from the board size parameter, which must be an
+// even number. The default board is 8x8.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Size: 8}
+	if params != "" {
+		n, err := strconv.Atoi(params)
+		if err != nil || n < 4 || n > 26 || n%2 != 0 {
+			return nil, errors.New("invalid board size: " + params)
+		}
+		g.Size = n
+	}
+	return g, nil
+}
+
+// move places a disc on a square, or passes if square is negative.
+type move struct {
+	size   int
+	square int
+}
+
+func (m move) String() string {
+	if m.square < 0 {
+		return passToken
+	}
+	return fmt.Sprintf("%c%d", 'a'+m.square%m.size, m.square/m.size+1)
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if s == passToken {
+		return move{g.Size, -1}, true
+	}
+	if len(s) < 2 || s[0] < 'a' || int(s[0]-'a') >= g.Size {
+		return nil, false
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > g.Size {
+		return nil, false
+	}
+	return move{g.Size, (row-1)*g.Size + int(s[0]-'a')}, true
+}
+
+// State is the state of a game of Othello.
+type State struct {
+	size  int
+	komi  int
+	owner []int // 0 for empty squares, or 1 + the player that occupies it
+	next  int
+	moves []string
+	over  bool
+}
+
+// Settings announces the board size to players if it isn't 8, the komi if
+// there is one, and the position the game starts from if it isn't the usual
+// one.
+func (g Game) Settings() []game.Setting {
+	settings := g.Handicap().Settings()
+	if g.Size != 8 {
+		settings = append([]game.Setting{{Name: "size", Value: strconv.Itoa(g.Size)}}, settings...)
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+// WithHandicap supports komi, but not handicap stones.
+func (g Game) WithHandicap(h game.Handicap) (game.Game, error) {
+	if h.Stones != 0 {
+		return nil, errors.New("handicap stones are not supported")
+	}
+	g.Komi = h.Komi
+	return g, nil
+}
+
+func (g Game) Handicap() game.Handicap {
+	return game.Handicap{Komi: g.Komi}
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	s := &State{size: g.Size, komi: g.Komi, owner: make([]int, g.Size*g.Size)}
+	h := g.Size / 2
+	s.owner[(h-1)*g.Size+h-1] = 2
+	s.owner[(h-1)*g.Size+h] = 1
+	s.owner[h*g.Size+h-1] = 1
+	s.owner[h*g.Size+h] = 2
+	return s
+}
+
+// Position writes the board of state and the color to move (see the package
+// comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.size)
+	for r := range rows {
+		row := make([]byte, s.size)
+		for c := range row {
+			row[c] = ".xo"[s.owner[r*s.size+c]]
+		}
+		rows[r] = string(row)
+	}
+	return strings.Join(rows, "/") + ":" + "xo"[s.next:s.next+1]
+}
+
+// Symmetries returns the positions obtained by rotating and reflecting the
+// board of state, with the same color to move.
+func (g Game) Symmetries(state game.GameState) []string {
+	s := state.(*State)
+	n := s.size - 1
+	var positions []string
+	for t := 0; t < 8; t++ {
+		rows := make([]string, s.size)
+		for r := range rows {
+			row := make([]byte, s.size)
+			for c := range row {
+				// Square (r, c) of the transformed board is taken from
+				// square (y, x) of the original board.
+				y, x := r, c
+				if t&1 != 0 {
+					x = n - x
+				}
+				if t&2 != 0 {
+					y = n - y
+				}
+				if t&4 != 0 {
+					x, y = y, x
+				}
+				row[c] = ".xo"[s.owner[y*s.size+x]]
+			}
+			rows[r] = string(row)
+		}
+		positions = append(positions, strings.Join(rows, "/")+":"+"xo"[s.next:s.next+1])
+	}
+	return positions
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position.
+func (g Game) parsePosition(position string) (*State, error) {
+	board, next, _ := strings.Cut(position, ":")
+	rows := strings.Split(board, "/")
+	if len(rows) != g.Size {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Size)
+	}
+	s := &State{size: g.Size, komi: g.Komi, owner: make([]int, g.Size*g.Size)}
+	for r, row := range rows {
+		if len(row) != g.Size {
+			return nil, fmt.Errorf("invalid position: expected %d squares per row", g.Size)
+		}
+		for c := range row {
+			owner := strings.IndexByte(".xo", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid square %q", row[c])
+			}
+			s.owner[r*g.Size+c] = owner
+		}
+	}
+	switch next {
+	case "x":
+	case "o":
+		s.next = 1
+	default:
+		return nil, errors.New("invalid position: color to move must be x or o")
+	}
+	s.over = !s.canMove(0) && !s.canMove(1)
+	return s, nil
+}
+
+func (s *State) Over() bool {
+	return s.over
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+var directions = [8][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+
+// flips returns the squares that would be flipped if player placed a disc on
+// square i.
+func (s *State) flips(player, i int) []int {
+	if s.owner[i] != 0 {
+		return nil
+	}
+	var result []int
+	r, c := i/s.size, i%s.size
+	for _, d := range directions {
+		var line []int
+		rr, cc := r+d[0], c+d[1]
+		for rr >= 0 && rr < s.size && cc >= 0 && cc < s.size && s.owner[rr*s.size+cc] == 2-player {
+			line = append(line, rr*s.size+cc)
+			rr, cc = rr+d[0], cc+d[1]
+		}
+		if len(line) > 0 && rr >= 0 && rr < s.size && cc >= 0 && cc < s.size && s.owner[rr*s.size+cc] == player+1 {
+			result = append(result, line...)
+		}
+	}
+	return result
+}
+
+// canMove returns whether player has a move other than passing.
+func (s *State) canMove(player int) bool {
+	for i := range s.owner {
+		if len(s.flips(player, i)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	if s.over {
+		return moves
+	}
+	for i := range s.owner {
+		if len(s.flips(s.next, i)) > 0 {
+			moves = append(moves, move{s.size, i})
+		}
+	}
+	if len(moves) == 0 {
+		moves = append(moves, move{s.size, -1})
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || m.size != s.size || s.over || m.square >= len(s.owner) {
+		return false
+	}
+	if m.square < 0 {
+		if s.canMove(s.next) {
+			return false
+		}
+	} else {
+		flips := s.flips(s.next, m.square)
+		if len(flips) == 0 {
+			return false
+		}
+		s.owner[m.square] = s.next + 1
+		for _, i := range flips {
+			s.owner[i] = s.next + 1
+		}
+	}
+	s.moves = append(s.moves, m.String())
+	s.next = 1 - s.next
+	if !s.canMove(0) && !s.canMove(1) {
+		s.over = true
+	}
+	return true
+}
+
+func (s *State) Scores() (int, int) {
+	var score [2]int
+	for _, owner := range s.owner {
+		if owner != 0 {
+			score[owner-1]++
+		}
+	}
+	return score[0], score[1] + s.komi
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Limits on solving positions: the number of empty squares left, and the
+// number of positions searched.
+const (
+	solveEmpty = 12
+	solveNodes = 1000000
+)
+
+// Solve searches the game to the end if few enough squares are empty. With
+// perfect play, each player maximizes its score minus its opponent's.
+func (s *State) Solve() (int, int, bool) {
+	empty := 0
+	for _, owner := range s.owner {
+		if owner == 0 {
+			empty++
+		}
+	}
+	if s.over || empty > solveEmpty {
+		a, b := s.Scores()
+		return a, b, s.over
+	}
+	c := s.Clone().(*State)
+	nodes := 0
+
+	// search returns the final score of player minus its opponent's with
+	// perfect play, the final scores, and false if the search took too long.
+	var search func(player int, passed bool, alpha, beta int) (int, [2]int, bool)
+	search = func(player int, passed bool, alpha, beta int) (int, [2]int, bool) {
+		if nodes++; nodes > solveNodes {
+			return 0, [2]int{}, false
+		}
+		best, bestScore, moved := 0, [2]int{}, false
+		for i := range c.owner {
+			flips := c.flips(player, i)
+			if len(flips) == 0 {
+				continue
+			}
+			c.owner[i] = player + 1
+			for _, j := range flips {
+				c.owner[j] = player + 1
+			}
+			v, score, ok := search(1-player, false, -beta, -alpha)
+			c.owner[i] = 0
+			for _, j := range flips {
+				c.owner[j] = 2 - player
+			}
+			if !ok {
+				return 0, [2]int{}, false
+			}
+			if v = -v; !moved || v > best {
+				best, bestScore = v, score
+			}
+			moved = true
+			if alpha = max(alpha, best); alpha >= beta {
+				break
+			}
+		}
+		if moved {
+			return best, bestScore, true
+		}
+		if passed {
+			// Neither player can move, so the game is over.
+			a, b := c.Scores()
+			if player == 1 {
+				return b - a, [2]int{a, b}, true
+			}
+			return a - b, [2]int{a, b}, true
+		}
+		v, score, ok := search(1-player, true, -beta, -alpha)
+		return -v, score, ok
+	}
+	_, score, ok := search(s.next, false, -math.MaxInt, math.MaxInt)
+	return score[0], score[1], ok
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board with black discs (the first player's) as 'X' and
+// white discs as 'O'.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "X", "O"}
+	fmt.Fprint(w, "  ")
+	for c := 0; c < s.size; c++ {
+		fmt.Fprintf(w, " %c", 'a'+c)
+	}
+	fmt.Fprintln(w)
+	for r := 0; r < s.size; r++ {
+		fmt.Fprintf(w, "%2d", r+1)
+		for c := 0; c < s.size; c++ {
+			fmt.Fprint(w, " "+symbols[s.owner[r*s.size+c]])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Board returns the board for drawing, with row 1 at the top.
+func (s *State) Board() game.Board {
+	return game.Board{Rows: s.size, Cols: s.size, Owner: func(r, c int) int {
+		return s.owner[r*s.size+c]
+	}}
+}
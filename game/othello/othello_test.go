@@ -0,0 +1,104 @@
+package othello
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	// Black fills the board with its move.
+	last := "xxxx/xxxx/xxxo/xxx.:x"
+	// Black can't move, and passes; white then takes all discs.
+	stuck := "oooo/oooo/ooox/oo..:x"
+	tests := []struct {
+		name   string
+		g      Game
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"first move", Game{Size: 8}, []string{"d3"}, true, false, [2]int{4, 1}},
+		{"other first move", Game{Size: 8}, []string{"f5"}, true, false, [2]int{4, 1}},
+		{"no flips", Game{Size: 8}, []string{"a1"}, false, false, [2]int{2, 2}},
+		{"occupied", Game{Size: 8}, []string{"d4"}, false, false, [2]int{2, 2}},
+		{"outside the board", Game{Size: 8}, []string{"i1"}, false, false, [2]int{2, 2}},
+		{"pass with moves left", Game{Size: 8}, []string{"pass"}, false, false, [2]int{2, 2}},
+		{"reply", Game{Size: 8}, []string{"d3", "c3"}, true, false, [2]int{3, 3}},
+		{"last move", Game{Size: 4, Start: last}, []string{"d4"}, true, true, [2]int{16, 0}},
+		{"komi", Game{Size: 4, Komi: 2, Start: last}, []string{"d4"}, true, true, [2]int{16, 2}},
+		{"pass", Game{Size: 4, Start: stuck}, []string{"pass"}, true, false, [2]int{1, 13}},
+		{"no move to pass", Game{Size: 4, Start: stuck}, []string{"d4"}, false, false, [2]int{1, 13}},
+		{"wipeout", Game{Size: 4, Start: stuck}, []string{"pass", "d4"}, true, true, [2]int{0, 15}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, tt.g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := Game{Size: 8}
+	tests := []struct {
+		moves []string
+		want  string
+	}{
+		{nil, "......../......../......../...ox.../...xo.../......../......../........:x"},
+		{[]string{"d3"}, "......../......../...x..../...xx.../...xo.../......../......../........:o"},
+		{[]string{"d3", "c3"}, "......../......../..ox..../...ox.../...xo.../......../......../........:x"},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, g, tt.moves)
+		if got := g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := g.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		if got := g.Position(pg.CreateState()); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Size: 4}
+	for _, position := range []string{
+		".ox./.xo./....:x",      // too few rows
+		"..../.ox/.xo./....:x",  // short row
+		"..../.oz./.xo./....:x", // invalid square
+		"..../.ox./.xo./....:y", // invalid color
+		"..../.ox./.xo./....",   // no color
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+}
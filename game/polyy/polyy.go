@@ -0,0 +1,368 @@
+// Package polyy implements Poly-Y, a connection game played on a pentagonal
+// board. Players take turns placing stones on empty cells. A player owns a
+// corner of the board when one of their groups touches both sides adjacent to
+// the corner and at least one other side. The game ends when a player owns a
+// majority of the corners, or when the board is full. The score of each
+// player is the number of corners they own.
+//
+// Positions are written as the rings from the central cell outward, separated
+// by '/', with 'x' and 'o' for the stones of the first and second player and
+// '.' for empty cells, in the order of their cell numbers, followed by ':'
+// and the player to move, e.g. ":x" if the first player moves next.
+package polyy
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func init() {
+	game.Register("polyy", func(params string) (game.Game, error) {
+		g := Game{Rings: DefaultRings}
+		if params != "" {
+			if n, err := strconv.Atoi(params); err != nil || n < 2 || n > 25 {
+				return nil, errors.New("invalid number of rings: " + params)
+			} else {
+				g.Rings = n
+			}
+		}
+		return g, nil
+	})
+}
+
+// Number of sides (and corners) of the board.
+const Sides = 5
+
+// DefaultRings is the size of the standard board.
+const DefaultRings = 7
+
+// Game is a Poly-Y variant of a given board size.
+type Game struct {
+	Rings int    // number of rings around the central cell
+	Start string // position the game starts from, or "" for an empty board
+}
+
+// Board describes the cells of a board and how they are connected. The board
+// consists of a central cell, surrounded by rings of cells; ring k (for k ≥ 1)
+// has Sides*k cells, with a corner cell at the start of each of its sides.
+// Cells on the outer ring lie on the sides of the board.
+type Board struct {
+	rings     int
+	names     []string
+	index     map[string]int
+	neighbors [][]int
+	sides     []uint // bit i is set if the cell lies on side i
+}
+
+// cell returns the index of cell p (0 ≤ p < Sides*k) of ring k.
+func cell(k, p int) int {
+	if k == 0 {
+		return 0
+	}
+	return 1 + Sides*k*(k-1)/2 + p
+}
+
+// NewBoard generates the board with the given number of rings.
+func NewBoard(rings int) *Board {
+	n := cell(rings+1, 0)
+	b := &Board{rings: rings, names: make([]string, n), index: map[string]int{},
+		neighbors: make([][]int, n), sides: make([]uint, n)}
+	connect := func(i, j int) {
+		b.neighbors[i] = append(b.neighbors[i], j)
+		b.neighbors[j] = append(b.neighbors[j], i)
+	}
+	b.names[0] = "a1"
+	for k := 1; k <= rings; k++ {
+		size := Sides * k
+		for p := 0; p < size; p++ {
+			i := cell(k, p)
+			b.names[i] = fmt.Sprintf("%c%d", 'a'+k, p+1)
+			connect(i, cell(k, (p+1)%size))
+			s, t := p/k, p%k
+			if k == 1 {
+				connect(i, 0)
+			} else if t == 0 {
+				connect(i, cell(k-1, s*(k-1)))
+			} else {
+				connect(i, cell(k-1, s*(k-1)+t-1))
+				connect(i, cell(k-1, (s*(k-1)+t)%(Sides*(k-1))))
+			}
+			if k == rings {
+				b.sides[i] |= 1 << uint(s)
+				if t == 0 {
+					b.sides[i] |= 1 << uint((s+Sides-1)%Sides)
+				}
+			}
+		}
+	}
+	for i, name := range b.names {
+		b.index[name] = i
+	}
+	return b
+}
+
+// Size returns the number of cells on the board.
+func (b *Board) Size() int {
+	return len(b.names)
+}
+
+// move places a stone on a cell. Moves are written as the cell name, which
+// consists of a letter identifying the ring (from "a" for the central cell
+// outward) and the 1-based number of the cell in the ring.
+type move struct {
+	board *Board
+	cell  int
+}
+
+func (m move) String() string {
+	return m.board.names[m.cell]
+}
+
+// State is the state of a game of Poly-Y.
+type State struct {
+	board   *Board
+	owner   []int // 0 for empty cells, or 1 + the player that occupies it
+	next    int
+	moves   []string
+	corners [Sides]int // 0 if not owned, or 1 + the player that owns it
+	filled  int
+}
+
+func (g Game) rings() int {
+	if g.Rings < 1 {
+		return DefaultRings
+	}
+	return g.Rings
+}
+
+// boards caches generated boards by number of rings.
+var boards sync.Map
+
+func (g Game) board() *Board {
+	if b, ok := boards.Load(g.rings()); ok {
+		return b.(*Board)
+	}
+	b, _ := boards.LoadOrStore(g.rings(), NewBoard(g.rings()))
+	return b.(*Board)
+}
+
+// Settings announces the number of rings to players if it isn't the standard
+// number, and the position the game starts from if it isn't an empty board.
+func (g Game) Settings() []game.Setting {
+	var settings []game.Setting
+	if g.rings() != DefaultRings {
+		settings = append(settings, game.Setting{Name: "rings", Value: strconv.Itoa(g.rings())})
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	b := g.board()
+	return &State{board: b, owner: make([]int, b.Size())}
+}
+
+// Position writes the board of state and the player to move (see the package
+// comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rings := make([]string, s.board.rings+1)
+	for k := range rings {
+		ring := make([]byte, max(Sides*k, 1))
+		for p := range ring {
+			ring[p] = ".xo"[s.owner[cell(k, p)]]
+		}
+		rings[k] = string(ring)
+	}
+	return strings.Join(rings, "/") + ":" + "xo"[s.next:s.next+1]
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position.
+func (g Game) parsePosition(position string) (*State, error) {
+	board, next, _ := strings.Cut(position, ":")
+	rings := strings.Split(board, "/")
+	if len(rings) != g.rings()+1 {
+		return nil, fmt.Errorf("invalid position: expected %d rings", g.rings()+1)
+	}
+	b := g.board()
+	s := &State{board: b, owner: make([]int, b.Size())}
+	for k, ring := range rings {
+		if len(ring) != max(Sides*k, 1) {
+			return nil, fmt.Errorf("invalid position: expected %d cells in ring %c", max(Sides*k, 1), 'a'+k)
+		}
+		for p := range ring {
+			owner := strings.IndexByte(".xo", ring[p])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid cell %q", ring[p])
+			}
+			s.owner[cell(k, p)] = owner
+		}
+	}
+	switch next {
+	case "x":
+	case "o":
+		s.next = 1
+	default:
+		return nil, errors.New("invalid position: player to move must be x or o")
+	}
+	for i, owner := range s.owner {
+		if owner != 0 {
+			s.filled++
+			s.updateCorners(i)
+		}
+	}
+	if s.Over() {
+		return nil, errors.New("invalid position: game already over")
+	}
+	return s, nil
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	b := g.board()
+	if i, ok := b.index[strings.TrimSpace(s)]; ok {
+		return move{b, i}, true
+	}
+	return nil, false
+}
+
+func (s *State) Over() bool {
+	if s.filled == s.board.Size() {
+		return true
+	}
+	score := s.score()
+	return 2*score[0] > Sides || 2*score[1] > Sides
+}
+
+func (s *State) Next() int {
+	return s.next
+}
+
+func (s *State) ListMoves() []interface{} {
+	var moves []interface{}
+	for i, owner := range s.owner {
+		if owner == 0 {
+			moves = append(moves, move{s.board, i})
+		}
+	}
+	return moves
+}
+
+func (s *State) Execute(arg interface{}) bool {
+	m, ok := arg.(move)
+	if !ok || m.cell < 0 || m.cell >= len(s.owner) || s.owner[m.cell] != 0 || s.Over() {
+		return false
+	}
+	s.owner[m.cell] = s.next + 1
+	s.filled++
+	s.moves = append(s.moves, s.board.names[m.cell])
+	s.updateCorners(m.cell)
+	s.next = 1 - s.next
+	return true
+}
+
+// updateCorners assigns the corners owned by the group containing the given
+// cell to its owner. Corners, once owned, stay owned, since groups never
+// shrink.
+func (s *State) updateCorners(start int) {
+	player := s.owner[start]
+	sides := uint(0)
+	visited := map[int]bool{start: true}
+	queue := []int{start}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		sides |= s.board.sides[i]
+		for _, j := range s.board.neighbors[i] {
+			if !visited[j] && s.owner[j] == player {
+				visited[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	for c := range s.corners {
+		// Corner c lies between side c-1 and side c.
+		adjacent := uint(1)<<uint(c) | uint(1)<<uint((c+Sides-1)%Sides)
+		if s.corners[c] == 0 && sides&adjacent == adjacent && sides&^adjacent != 0 {
+			s.corners[c] = player
+		}
+	}
+}
+
+func (s *State) score() [2]int {
+	var score [2]int
+	for _, owner := range s.corners {
+		if owner != 0 {
+			score[owner-1]++
+		}
+	}
+	return score
+}
+
+func (s *State) Scores() (int, int) {
+	score := s.score()
+	return score[0], score[1]
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board ring by ring, from the center outward. Stones of the
+// first player are shown as 'X', those of the second player as 'O'.
+func (s *State) Render(w io.Writer) {
+	symbols := []byte{'.', 'X', 'O'}
+	for k := 0; k <= s.board.rings; k++ {
+		size := Sides * k
+		if k == 0 {
+			size = 1
+		}
+		var line []byte
+		for p := 0; p < size; p++ {
+			if k > 0 && p%k == 0 && p > 0 {
+				line = append(line, ' ', '|')
+			}
+			line = append(line, ' ', symbols[s.owner[cell(k, p)]])
+		}
+		fmt.Fprintf(w, "%c:%s\n", 'a'+k, line)
+	}
+	corners := make([]byte, 0, 2*Sides)
+	for _, owner := range s.corners {
+		corners = append(corners, ' ', symbols[owner])
+	}
+	fmt.Fprintf(w, "Corners:%s\n", corners)
+}
@@ -0,0 +1,107 @@
+package polyy
+
+import (
+	"testing"
+)
+
+// play plays moves from the start of g, and returns the state and whether the
+// last move was legal. The other moves must be legal.
+func play(t *testing.T, g Game, moves []string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, text := range moves {
+		m, ok := g.ParseMove(text)
+		ok = ok && s.Execute(m)
+		if i == len(moves)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: move %s is illegal", moves, text)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	// On a board with two rings, the cells of the outer ring (c) lie on the
+	// sides of the board: c1 on sides 5 and 1, c2 on side 1, c3 on sides 1
+	// and 2, and so on. The first player's chain c1-c5 touches sides 1, 2, 3
+	// and 5, which owns three corners; the second player plays inside.
+	g := Game{Rings: 2}
+	tests := []struct {
+		name   string
+		moves  []string
+		legal  bool // whether the last move is legal
+		over   bool
+		scores [2]int
+	}{
+		{"center", []string{"a1"}, true, false, [2]int{0, 0}},
+		{"outer ring", []string{"c10"}, true, false, [2]int{0, 0}},
+		{"outside the board", []string{"c11"}, false, false, [2]int{0, 0}},
+		{"no such ring", []string{"d1"}, false, false, [2]int{0, 0}},
+		{"occupied", []string{"b2", "b2"}, false, false, [2]int{0, 0}},
+		{"two corners", []string{"c1", "b1", "c2", "b2", "c3"}, true, false, [2]int{2, 0}},
+		{"three corners", []string{"c1", "b1", "c2", "b2", "c3", "b3", "c4", "b4", "c5"}, true, true, [2]int{3, 0}},
+		{"after the end", []string{"c1", "b1", "c2", "b2", "c3", "b3", "c4", "b4", "c5", "c6"}, false, true, [2]int{3, 0}},
+		{"second player", []string{"b1", "c1", "b2", "c2", "b3", "c3", "b4", "c4", "a1", "c5"}, true, true, [2]int{0, 3}},
+	}
+	for _, tt := range tests {
+		s, legal := play(t, g, tt.moves)
+		if legal != tt.legal {
+			t.Errorf("%s: last move legal is %v, want %v", tt.name, legal, tt.legal)
+		}
+		if s.Over() != tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := Game{Rings: 2}
+	tests := []struct {
+		moves []string
+		want  string
+		score [2]int // corners owned
+	}{
+		{nil, "./...../..........:x", [2]int{0, 0}},
+		{[]string{"a1"}, "x/...../..........:o", [2]int{0, 0}},
+		{[]string{"a1", "b3", "c10"}, "x/..o../.........x:o", [2]int{0, 0}},
+		{[]string{"c1", "b1", "c2", "b2", "c3"}, "./oo.../xxx.......:o", [2]int{2, 0}},
+	}
+	for _, tt := range tests {
+		s, _ := play(t, g, tt.moves)
+		if got := g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.moves, got, tt.want)
+		}
+		pg, err := g.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		ps := pg.CreateState().(*State)
+		if got := g.Position(ps); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+		if a, b := ps.Scores(); [2]int{a, b} != tt.score {
+			t.Errorf("%s: corners owned are %d, %d, want %v", tt.want, a, b, tt.score)
+		}
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Rings: 2}
+	for _, position := range []string{
+		"./.....:x",            // too few rings
+		"./..../..........:x",  // short ring
+		"./...../.........z:x", // invalid cell
+		"./...../..........:y", // invalid player
+		"./oooo./xxxxx.....:o", // the first player owns three corners
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Games that can be played by the arbiter, by name. Each entry creates the
+// game from the parameters given after the name, if any.
+var games = map[string]func(params string) (Game, error){}
+
+// Register makes a game available under the given name. It is meant to be
+// called from the init function of the package implementing the game. The
+// create function is passed the parameters of the game, which follow the name
+// after a colon (e.g. "13" for "hex:13"), or "" if there are none.
+func Register(name string, create func(params string) (Game, error)) {
+	if _, ok := games[name]; ok {
+		panic("game already registered: " + name)
+	}
+	games[name] = create
+}
+
+// Lookup returns the game described by spec, which consists of the name of a
+// registered game, optionally followed by a colon and game parameters.
+func Lookup(spec string) (Game, error) {
+	name, params, _ := strings.Cut(spec, ":")
+	create, ok := games[name]
+	if !ok {
+		return nil, errors.New("unknown game: " + name)
+	}
+	g, err := create(params)
+	if err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+	return g, nil
+}
+
+// Names returns the names of all registered games, separated by commas.
+func Names() string {
+	var names []string
+	for name := range games {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// NoParams returns a create function for Register, for games that don't take
+// any parameters.
+func NoParams(g Game) func(params string) (Game, error) {
+	return func(params string) (Game, error) {
+		if params != "" {
+			return nil, errors.New("game takes no parameters")
+		}
+		return g, nil
+	}
+}
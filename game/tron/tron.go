@@ -0,0 +1,391 @@
+// Package tron implements a light cycle game, in which both players move at
+// the same time. Each turn, both players move their cycle one cell north,
+// east, south or west, leaving a wall behind. A cycle that moves off the
+// board or into a wall crashes; if both cycles move into the same cell, both
+// crash. When a cycle crashes the game ends and the other player wins, with a
+// score of 1 against 0. If both crash in the same turn, the game is a draw.
+//
+// The first player starts on the left half of the board and the second player
+// on the right, in mirrored positions. Moves are written as "n", "e", "s" or
+// "w"; north is towards higher row numbers.
+//
+// Optionally, the board starts with walls placed at random, mirrored like the
+// starting positions so that neither player is favored. Players are told
+// where the walls are with the setting "walls", which lists their cells as
+// "<column>,<row>" (1-based), separated by spaces.
+//
+// Positions are written as the rows from the top row down, separated by '/',
+// with 'X' and 'O' for the cycles of the first and second player, 'x' and 'o'
+// for the walls they left behind, '#' for walls placed at the start and '.'
+// for empty cells. Random walls can't be combined with a position.
+package tron
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	game.Register("tron", parseParams)
+}
+
+// Game is a light cycle game on a board of a given size.
+type Game struct {
+	Cols, Rows int
+	Walls      int    // number of random walls on each half of the board
+	Seed       int64  // seed used to place the walls
+	Start      string // position the game starts from, or "" for the usual one
+}
+
+// parseParams creates a game from the parameters, separated by commas: the
+// board size, given as "n" for a square board or "<cols>x<rows>", and
+// "walls=<n>" to place n random walls on each half of the board. The default
+// board is 16x16 without walls.
+func parseParams(params string) (game.Game, error) {
+	g := Game{Cols: 16, Rows: 16}
+	if params == "" {
+		return g, nil
+	}
+	for _, param := range strings.Split(params, ",") {
+		if walls, ok := strings.CutPrefix(param, "walls="); ok {
+			n, err := strconv.Atoi(walls)
+			if err != nil || n < 0 {
+				return nil, errors.New("invalid number of walls: " + walls)
+			}
+			g.Walls = n
+			continue
+		}
+		cols, rows, found := strings.Cut(param, "x")
+		if !found {
+			rows = cols
+		}
+		var err1, err2 error
+		g.Cols, err1 = strconv.Atoi(cols)
+		g.Rows, err2 = strconv.Atoi(rows)
+		if err1 != nil || err2 != nil || g.Cols < 2 || g.Cols > 99 || g.Rows < 1 || g.Rows > 99 {
+			return nil, errors.New("invalid board size: " + param)
+		}
+	}
+	empty := Game{Cols: g.Cols, Rows: g.Rows}.CreateState().(*State)
+	if max := len(empty.wallCells()); g.Walls > max {
+		return nil, fmt.Errorf("too many walls: at most %d fit on a %dx%d board", max, g.Cols, g.Rows)
+	}
+	return g, nil
+}
+
+// move is a direction: north, east, south or west.
+type move int
+
+var directions = [4]struct {
+	name   string
+	dc, dr int
+}{{"n", 0, 1}, {"e", 1, 0}, {"s", 0, -1}, {"w", -1, 0}}
+
+func (m move) String() string {
+	return directions[m].name
+}
+
+func (g Game) ParseMove(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	for i, d := range directions {
+		if s == d.name {
+			return move(i), true
+		}
+	}
+	return nil, false
+}
+
+// Settings announces the board size to players if it isn't 16x16, and the
+// position the game starts from if it isn't the usual one.
+func (g Game) Settings() []game.Setting {
+	var settings []game.Setting
+	if g.Cols != 16 || g.Rows != 16 {
+		settings = append(settings, game.Setting{Name: "size", Value: fmt.Sprintf("%dx%d", g.Cols, g.Rows)})
+	}
+	if g.Start != "" {
+		settings = append(settings, game.Setting{Name: "position", Value: g.Start})
+	}
+	return settings
+}
+
+// WithDeal returns a copy of the game that places its walls using seed.
+func (g Game) WithDeal(seed int64) game.Game {
+	g.Seed = seed
+	return g
+}
+
+// Setup tells both players where the walls are, if there are any.
+func (g Game) Setup(state game.GameState, player int) []game.Setting {
+	s := state.(*State)
+	var cells []string
+	for i, owner := range s.owner {
+		if owner == wall {
+			cells = append(cells, fmt.Sprintf("%d,%d", i%s.cols+1, i/s.cols+1))
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	return []game.Setting{{Name: "walls", Value: strings.Join(cells, " ")}}
+}
+
+// wall is the owner of cells that are walls from the start of the game.
+const wall = 3
+
+func (g Game) CreateState() game.GameState {
+	if g.Start != "" {
+		s, _ := g.parsePosition(g.Start) // checked by WithPosition
+		return s
+	}
+	s := &State{cols: g.Cols, rows: g.Rows, owner: make([]int, g.Cols*g.Rows), winner: -1}
+	s.head[0] = [2]int{g.Cols / 4, g.Rows / 2}
+	s.head[1] = [2]int{g.Cols - 1 - g.Cols/4, g.Rows - 1 - g.Rows/2}
+	for i, h := range s.head {
+		s.owner[h[1]*g.Cols+h[0]] = i + 1
+	}
+	if g.Walls > 0 {
+		cells := s.wallCells()
+		rng := rand.New(rand.NewSource(g.Seed))
+		rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+		for _, i := range cells[:g.Walls] {
+			s.owner[i] = wall
+			s.owner[len(s.owner)-1-i] = wall
+		}
+	}
+	return s
+}
+
+// Position writes the board of state (see the package comment).
+func (g Game) Position(state game.GameState) string {
+	s := state.(*State)
+	rows := make([]string, s.rows)
+	for i := range rows {
+		r := s.rows - 1 - i
+		row := make([]byte, s.cols)
+		for c := range row {
+			row[c] = ".xo#"[s.owner[r*s.cols+c]]
+		}
+		for p, h := range s.head {
+			if h[1] == r {
+				row[h[0]] = "XO"[p]
+			}
+		}
+		rows[i] = string(row)
+	}
+	return strings.Join(rows, "/")
+}
+
+// WithPosition returns a copy of the game that starts from the given position.
+func (g Game) WithPosition(position string) (game.Game, error) {
+	if g.Walls != 0 {
+		return nil, errors.New("a position can't be combined with random walls")
+	}
+	if _, err := g.parsePosition(position); err != nil {
+		return nil, err
+	}
+	g.Start = position
+	return g, nil
+}
+
+// parsePosition returns the state given by position, which must have one
+// cycle of each player.
+func (g Game) parsePosition(position string) (*State, error) {
+	rows := strings.Split(position, "/")
+	if len(rows) != g.Rows {
+		return nil, fmt.Errorf("invalid position: expected %d rows", g.Rows)
+	}
+	s := &State{cols: g.Cols, rows: g.Rows, owner: make([]int, g.Cols*g.Rows), winner: -1}
+	var cycles [2]int
+	for i, row := range rows {
+		if len(row) != g.Cols {
+			return nil, fmt.Errorf("invalid position: expected %d cells per row", g.Cols)
+		}
+		r := g.Rows - 1 - i
+		for c := range row {
+			if p := strings.IndexByte("XO", row[c]); p >= 0 {
+				s.head[p] = [2]int{c, r}
+				s.owner[r*g.Cols+c] = p + 1
+				cycles[p]++
+				continue
+			}
+			owner := strings.IndexByte(".xo#", row[c])
+			if owner < 0 {
+				return nil, fmt.Errorf("invalid position: invalid cell %q", row[c])
+			}
+			s.owner[r*g.Cols+c] = owner
+		}
+	}
+	if cycles != [2]int{1, 1} {
+		return nil, errors.New("invalid position: expected one X and one O")
+	}
+	return s, nil
+}
+
+// wallCells returns the cells where a wall may be placed on each half of the
+// board: those whose mirrored cell is different, and which are not next to
+// either cycle, so that neither player crashes on its first move. Of each
+// pair of mirrored cells, only the first is included.
+func (s *State) wallCells() []int {
+	var cells []int
+	for i := 0; i < len(s.owner)-1-i; i++ {
+		c, r := i%s.cols, i/s.cols
+		if !s.nearHead(c, r) && !s.nearHead(s.cols-1-c, s.rows-1-r) {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
+
+// nearHead returns whether the given cell is next to or under either cycle.
+func (s *State) nearHead(c, r int) bool {
+	for _, h := range s.head {
+		if abs(c-h[0])+abs(r-h[1]) <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// State is the state of a light cycle game.
+type State struct {
+	cols, rows int
+	owner      []int     // 0 for empty cells, 1 + the player whose wall it is, or wall
+	head       [2][2]int // column and row of each player's cycle
+	moves      []string  // both players' moves, the first player's first
+	winner     int       // -1 while the game is in progress or drawn
+	over       bool
+}
+
+func (s *State) Over() bool {
+	return s.over
+}
+
+func (s *State) Simultaneous() bool {
+	return !s.over
+}
+
+// Next is not used, since players always move at the same time.
+func (s *State) Next() int {
+	return 0
+}
+
+func (s *State) PlayerMoves(player int) []interface{} {
+	var moves []interface{}
+	if s.over {
+		return moves
+	}
+	for i := range directions {
+		moves = append(moves, move(i))
+	}
+	return moves
+}
+
+// ListMoves is not used, since players always move at the same time.
+func (s *State) ListMoves() []interface{} {
+	return nil
+}
+
+// Execute is not used, since players always move at the same time.
+func (s *State) Execute(arg interface{}) bool {
+	return false
+}
+
+func (s *State) ExecuteBoth(moves [2]interface{}) [2]bool {
+	var valid [2]bool
+	var m [2]move
+	for i := range moves {
+		m[i], valid[i] = moves[i].(move)
+	}
+	if s.over || !valid[0] || !valid[1] {
+		return [2]bool{false, false}
+	}
+	var crashed [2]bool
+	var next [2][2]int
+	for i := range next {
+		d := directions[m[i]]
+		next[i] = [2]int{s.head[i][0] + d.dc, s.head[i][1] + d.dr}
+		c, r := next[i][0], next[i][1]
+		crashed[i] = c < 0 || c >= s.cols || r < 0 || r >= s.rows || s.owner[r*s.cols+c] != 0
+	}
+	if next[0] == next[1] {
+		crashed = [2]bool{true, true}
+	}
+	for i := range next {
+		if !crashed[i] {
+			s.head[i] = next[i]
+			s.owner[next[i][1]*s.cols+next[i][0]] = i + 1
+		}
+		s.moves = append(s.moves, m[i].String())
+	}
+	if crashed[0] || crashed[1] {
+		s.over = true
+		if !crashed[0] {
+			s.winner = 0
+		} else if !crashed[1] {
+			s.winner = 1
+		}
+	}
+	return valid
+}
+
+func (s *State) Scores() (int, int) {
+	switch s.winner {
+	case 0:
+		return 1, 0
+	case 1:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+func (s *State) WriteLog(w io.Writer) {
+	for _, m := range s.moves {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// Clone returns a copy of the state that can be changed independently.
+func (s *State) Clone() game.GameState {
+	c := *s
+	c.owner = append([]int(nil), s.owner...)
+	c.moves = append([]string(nil), s.moves...)
+	return &c
+}
+
+// History returns the moves played so far, both players' moves of each turn
+// with the first player's first.
+func (s *State) History() []string {
+	return append([]string(nil), s.moves...)
+}
+
+// Render draws the board with row 1 at the bottom. The first player's cycle
+// is shown as 'X' and its wall as 'x', the second player's as 'O' and 'o'.
+// Walls placed at the start of the game are shown as '#'.
+func (s *State) Render(w io.Writer) {
+	symbols := []string{".", "x", "o", "#"}
+	for r := s.rows - 1; r >= 0; r-- {
+		fmt.Fprintf(w, "%2d", r+1)
+		for c := 0; c < s.cols; c++ {
+			symbol := symbols[s.owner[r*s.cols+c]]
+			for i, h := range s.head {
+				if h == [2]int{c, r} {
+					symbol = strings.ToUpper(symbols[i+1])
+				}
+			}
+			fmt.Fprint(w, " "+symbol)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,132 @@
+package tron
+
+import (
+	"testing"
+)
+
+// play plays turns from the start of g, and returns the state and whether the
+// moves of the last turn were valid. The other moves must be valid.
+func play(t *testing.T, g Game, turns [][2]string) (*State, bool) {
+	t.Helper()
+	s := g.CreateState().(*State)
+	for i, turn := range turns {
+		var moves [2]interface{}
+		ok := true
+		for p, text := range turn {
+			var parsed bool
+			moves[p], parsed = g.ParseMove(text)
+			ok = ok && parsed
+		}
+		if ok {
+			valid := s.ExecuteBoth(moves)
+			ok = valid[0] && valid[1]
+		}
+		if i == len(turns)-1 {
+			return s, ok
+		}
+		if !ok {
+			t.Fatalf("%v: turn %v is invalid", turns, turn)
+		}
+	}
+	return s, true
+}
+
+func TestPlay(t *testing.T) {
+	// On a 4x4 board, the cycles start on b3 and c2 (column and row).
+	g := Game{Cols: 4, Rows: 4}
+	tests := []struct {
+		name   string
+		turns  [][2]string
+		valid  bool // whether the moves of the last turn are valid
+		over   bool
+		scores [2]int
+	}{
+		{"first turn", [][2]string{{"n", "s"}}, true, false, [2]int{0, 0}},
+		{"not a direction", [][2]string{{"up", "s"}}, false, false, [2]int{0, 0}},
+		{"both leave the board", [][2]string{{"n", "s"}, {"n", "s"}}, true, true, [2]int{0, 0}},
+		{"second player leaves the board", [][2]string{{"w", "s"}, {"n", "s"}}, true, true, [2]int{1, 0}},
+		{"into a wall", [][2]string{{"e", "w"}, {"s", "w"}}, true, true, [2]int{0, 1}},
+		{"into its own wall", [][2]string{{"n", "e"}, {"s", "n"}}, true, true, [2]int{0, 1}},
+		{"same cell", [][2]string{{"e", "n"}}, true, true, [2]int{0, 0}},
+		{"after the end", [][2]string{{"e", "n"}, {"n", "s"}}, false, true, [2]int{0, 0}},
+	}
+	for _, tt := range tests {
+		s, valid := play(t, g, tt.turns)
+		if valid != tt.valid {
+			t.Errorf("%s: last turn valid is %v, want %v", tt.name, valid, tt.valid)
+		}
+		if s.Over() != tt.over || s.Simultaneous() == tt.over {
+			t.Errorf("%s: over is %v, want %v", tt.name, s.Over(), tt.over)
+		}
+		if a, b := s.Scores(); [2]int{a, b} != tt.scores {
+			t.Errorf("%s: scores are %d, %d, want %v", tt.name, a, b, tt.scores)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	g := Game{Cols: 4, Rows: 4}
+	tests := []struct {
+		turns [][2]string
+		want  string
+	}{
+		{nil, "..../.X../..O./...."},
+		{[][2]string{{"n", "s"}}, ".X../.x../..o./..O."},
+		{[][2]string{{"n", "s"}, {"e", "e"}}, ".xX./.x../..o./..oO"},
+	}
+	for _, tt := range tests {
+		s := g.CreateState().(*State)
+		if len(tt.turns) > 0 {
+			s, _ = play(t, g, tt.turns)
+		}
+		if got := g.Position(s); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.turns, got, tt.want)
+		}
+		pg, err := g.WithPosition(tt.want)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want, err)
+			continue
+		}
+		if got := g.Position(pg.CreateState()); got != tt.want {
+			t.Errorf("%s: read as %s", tt.want, got)
+		}
+	}
+}
+
+// TestPositionWalls checks that walls placed at the start are kept, and that
+// cycles crash into them.
+func TestPositionWalls(t *testing.T) {
+	g, err := Game{Cols: 4, Rows: 4}.WithPosition("..../#X../..O#/....")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := g.CreateState().(*State)
+	valid := s.ExecuteBoth([2]interface{}{move(3), move(1)}) // west, east
+	if !valid[0] || !valid[1] || !s.Over() {
+		t.Fatalf("cycles didn't crash into the walls")
+	}
+	if got := g.(Game).Position(s); got != "..../#X../..O#/...." {
+		t.Errorf("got %s", got)
+	}
+	if a, b := s.Scores(); a != 0 || b != 0 {
+		t.Errorf("scores are %d, %d, want a draw", a, b)
+	}
+}
+
+func TestInvalidPosition(t *testing.T) {
+	g := Game{Cols: 4, Rows: 4}
+	for _, position := range []string{
+		"..../.X../..O.",      // too few rows
+		"..../.X../..O./...",  // short row
+		"..../.X../..O./..z.", // invalid cell
+		"..../.X../..../....", // no second cycle
+		"..../.XX./..O./....", // two cycles of the first player
+	} {
+		if _, err := g.WithPosition(position); err == nil {
+			t.Errorf("%s: no error", position)
+		}
+	}
+	if _, err := (Game{Cols: 4, Rows: 4, Walls: 1}).WithPosition("..../.X../..O./...."); err == nil {
+		t.Error("no error for a position with random walls")
+	}
+}
@@ -0,0 +1,202 @@
+// Package gamelog reads and writes the game logs written by the arbiter.
+//
+// A game log is a text file. Lines starting with '#' are comments written by
+// the arbiter, mostly of the form "# <key>: <value>"; all other lines are
+// written by the game (see game.GameState.WriteLog) and usually contain one
+// move per line. A log starts with a header that describes it:
+//
+//	# Arbiter game log, format 1
+//	# Arbiter version: v1.2.0
+//	# Date: 2024-05-01T12:34:56Z
+//	# Game type: connect4
+//	# Position: ......./......./......./......./......./...x...
+//	# Game: 85fb2faa622b-0001
+//	# Player 1: ./player1
+//	# Player 2: ./player2
+//	# Seed: 1234
+//	# Game seed: 5678
+//
+// followed by further comments about the game, the moves, and a summary of
+// the result, which ends with a line starting with "# Score: ".
+//
+// While a game is in progress, its log has the header and a "# Move" comment
+// for each move, but no lines written by the game and no summary. Readers
+// take the moves from these comments if there are no other lines.
+//
+// The format version is only increased when existing lines change meaning or
+// are removed. New kinds of comments may be added without changing the
+// version, so readers should ignore comments they don't understand. Logs
+// written before the format was versioned have no format line, and are read
+// as version 0.
+package gamelog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the version of the game log format written by WriteHeader.
+const FormatVersion = 1
+
+// Header describes a game log and the game it records.
+type Header struct {
+	Version  int       // format version (see FormatVersion), or 0 if not recorded
+	Arbiter  string    // version of the arbiter that wrote the log, if recorded
+	Date     time.Time // when the game started, if recorded
+	Game     string    // game played, as given to game.Lookup (e.g. "hex:13"), if recorded
+	Position string    // position the game started from (see game.Positioner), if recorded
+	GameId   string    // unique game identifier
+	Players  [2]string // commands of the first and second player
+	Seed     int64     // seed of the run
+	GameSeed int64     // seed of the game (see match.Options.GameSeed)
+}
+
+// WriteHeader writes the header of a game log. Empty fields are left out.
+func WriteHeader(w io.Writer, h *Header) {
+	fmt.Fprintf(w, "# Arbiter game log, format %d\n", h.Version)
+	if h.Arbiter != "" {
+		fmt.Fprintf(w, "# Arbiter version: %s\n", h.Arbiter)
+	}
+	if !h.Date.IsZero() {
+		fmt.Fprintf(w, "# Date: %s\n", h.Date.UTC().Format(time.RFC3339))
+	}
+	if h.Game != "" {
+		fmt.Fprintf(w, "# Game type: %s\n", h.Game)
+	}
+	if h.Position != "" {
+		fmt.Fprintf(w, "# Position: %s\n", h.Position)
+	}
+	if h.GameId != "" {
+		fmt.Fprintf(w, "# Game: %s\n", h.GameId)
+	}
+	for i, player := range h.Players {
+		fmt.Fprintf(w, "# Player %d: %s\n", i+1, player)
+	}
+	fmt.Fprintf(w, "# Seed: %d\n", h.Seed)
+	fmt.Fprintf(w, "# Game seed: %d\n", h.GameSeed)
+}
+
+// ArbiterVersion returns the version of the running arbiter, as recorded in
+// its build information: the module version, followed by the revision it was
+// built from if known.
+func ArbiterVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+			version += " " + s.Value[:12]
+		}
+	}
+	return version
+}
+
+// CreateLog creates a game or message log file. If path ends in ".gz", the
+// file is compressed with gzip.
+func CreateLog(path string) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return f, err
+	}
+	return &gzipFile{gzip.NewWriter(f), f}, nil
+}
+
+// gzipFile is a file written through a gzip compressor.
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (gf *gzipFile) Close() error {
+	err := gf.Writer.Close()
+	if err2 := gf.f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// AppendLog is a log file that is written in pieces, such as a game log that
+// is extended after every move. Each piece is written to the file by Flush,
+// and stays complete if the program stops before the next one. If the file is
+// compressed, each piece is a separate gzip member, which OpenLog reads as
+// one stream.
+type AppendLog struct {
+	f    *os.File
+	gzip bool
+	buf  bytes.Buffer // the piece being written
+}
+
+// CreateAppendLog creates a log file to be written in pieces. If path ends in
+// ".gz", the file is compressed with gzip.
+func CreateAppendLog(path string) (*AppendLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &AppendLog{f: f, gzip: strings.HasSuffix(path, ".gz")}, nil
+}
+
+// Write adds to the piece that is written by the next Flush.
+func (al *AppendLog) Write(p []byte) (int, error) {
+	return al.buf.Write(p)
+}
+
+// Flush appends what was written since the last call to the file.
+func (al *AppendLog) Flush() error {
+	if al.buf.Len() == 0 {
+		return nil
+	}
+	defer al.buf.Reset()
+	if !al.gzip {
+		_, err := al.f.Write(al.buf.Bytes())
+		return err
+	}
+	zw := gzip.NewWriter(al.f)
+	if _, err := zw.Write(al.buf.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Close flushes the log and closes the file.
+func (al *AppendLog) Close() error {
+	err := al.Flush()
+	if err2 := al.f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// OpenLog opens a log file for reading. Files compressed with gzip are
+// decompressed, regardless of their name.
+func OpenLog(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	if magic, _ := r.Peek(2); len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return struct {
+			io.Reader
+			io.Closer
+		}{r, f}, nil
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
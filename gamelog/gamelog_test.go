@@ -0,0 +1,103 @@
+package gamelog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWriteHeader checks that the header read from a log is written the same
+// way.
+func TestWriteHeader(t *testing.T) {
+	data, err := os.ReadFile("testdata/connect4-v1.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl, err := ReadLog(nil, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	WriteHeader(&buf, &gl.Header)
+	lines := strings.SplitAfter(string(data), "\n")
+	if want := strings.Join(lines[:10], ""); buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteHeaderEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	WriteHeader(&buf, &Header{Version: FormatVersion, Players: [2]string{"a", "b"}})
+	want := "# Arbiter game log, format 1\n# Player 1: a\n# Player 2: b\n# Seed: 0\n# Game seed: 0\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// TestAppendLog checks that every flushed piece of a log can be read, also
+// when it's compressed.
+func TestAppendLog(t *testing.T) {
+	pieces := []string{"# Arbiter game log, format 1\n", "# Move 1 by player 1: 4 (0.100s)\n", "", "4\n"}
+	for _, name := range []string{"game.log", "game.log.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		al, err := CreateAppendLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := ""
+		for _, piece := range pieces {
+			al.Write([]byte(piece))
+			if err := al.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			want += piece
+			if got := readFile(t, path); got != want {
+				t.Errorf("%s: got %q, want %q", name, got, want)
+			}
+		}
+		al.Write([]byte("# Score: 1 - 0.\n"))
+		if err := al.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, path); got != want+"# Score: 1 - 0.\n" {
+			t.Errorf("%s: got %q after closing", name, got)
+		}
+	}
+}
+
+func TestCreateLog(t *testing.T) {
+	for _, name := range []string{"game.log", "game.log.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		w, err := CreateLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		WriteHeader(w, &Header{Version: FormatVersion, Date: time.Date(2024, 5, 1, 12, 34, 56, 0, time.UTC)})
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got := readFile(t, path)
+		if !strings.HasPrefix(got, "# Arbiter game log, format 1\n# Date: 2024-05-01T12:34:56Z\n") {
+			t.Errorf("%s: got %q", name, got)
+		}
+	}
+}
+
+// readFile reads a log through OpenLog.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	r, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
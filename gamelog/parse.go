@@ -0,0 +1,195 @@
+package gamelog
+
+import (
+	"arbiter/game"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LogParser may be implemented by games whose GameState.WriteLog records
+// moves in some other way than one move per line.
+type LogParser interface {
+	// ParseLog returns the moves recorded in the body of a game log, which
+	// excludes lines starting with '#'.
+	ParseLog(body string) ([]string, error)
+}
+
+// ErrNoGame is returned by ReadLog if no game is given and the log doesn't
+// record the game played.
+var ErrNoGame = errors.New("log doesn't record the game played")
+
+// GameLog is the information recovered from a game log file written by
+// match.Run.
+type GameLog struct {
+	Header
+	Deal        int64 // seed of the initial state (see game.Dealer)
+	Handicap    game.Handicap
+	TimeLimit   [2]time.Duration // time each player could use, or 0 if unlimited
+	Increment   time.Duration    // time added to the clocks after each move
+	Periods     [2]int           // number of byo-yomi periods players used up
+	Moves       []string
+	Movers      []int      // 0-based player that made each move, if recorded
+	Times       []float64  // time taken for each move in seconds, if recorded
+	CPUTime     [2]float64 // CPU time used by each player in seconds, if recorded
+	Score       [2]int
+	HasScore    bool // whether the score line was present
+	Failed      [2]bool
+	Code        [2]string // why players failed (see match.Result.Code), if recorded
+	Resigned    [2]bool
+	Restarted   [2]bool
+	Exit        [2]string // how player processes exited, if recorded
+	DrawAgreed  bool
+	Forfeited   bool   // whether the game ended when a player failed
+	Rejected    [2]int // number of illegal moves players were allowed to retry
+	NotPlayed   bool   // whether the game wasn't played because a player failed to start
+	Swapped     bool   // whether the players swapped sides after the first move
+	Interrupted bool
+	Adjudicated bool
+	Solved      bool // whether the game ended early because its outcome was determined
+}
+
+// ReadLog parses a game log file for the given game, or if g is nil, for the
+// game recorded in the log. Lines in the log that start with '#' are written
+// by the arbiter, and the remaining lines are written by the game's
+// GameState.WriteLog. Unless the game implements LogParser, these must
+// contain one move per line. If there are no such lines, as in the log of a
+// game in progress, the moves are taken from the arbiter's comments. Logs of
+// a newer format version than FormatVersion are rejected.
+func ReadLog(g game.Game, r io.Reader) (*GameLog, error) {
+	gl := &GameLog{}
+	var body strings.Builder
+	var commented []string // moves recorded in comments
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#") {
+			body.WriteString(line)
+			body.WriteString("\n")
+			continue
+		}
+		comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		var i int
+		if n, _ := fmt.Sscanf(comment, "Player %d: ", &i); n == 1 && i >= 1 && i <= 2 {
+			// Sscanf also matches other comments about the player.
+			if rest, ok := strings.CutPrefix(comment, fmt.Sprintf("Player %d: ", i)); ok && rest != "" {
+				gl.Players[i-1] = rest
+			}
+		}
+		if n, _ := fmt.Sscanf(comment, "Player %d", &i); n == 1 && i >= 1 && i <= 2 {
+			if _, reason, ok := strings.Cut(comment, " failed!"); ok {
+				gl.Failed[i-1] = true
+				if code, ok := strings.CutPrefix(reason, " Reason: "); ok {
+					gl.Code[i-1], _, _ = strings.Cut(code, " ")
+				}
+			} else if strings.HasSuffix(comment, " resigned.") {
+				gl.Resigned[i-1] = true
+			} else if strings.Contains(comment, " was restarted ") {
+				gl.Restarted[i-1] = true
+			} else if _, status, ok := strings.Cut(comment, " exited ("); ok {
+				gl.Exit[i-1] = strings.TrimSuffix(status, ").")
+			}
+		}
+		var mover int
+		var move string
+		var elapsed float64
+		if n, _ := fmt.Sscanf(comment, "Move %d by player %d: %s (%fs)", &i, &mover, &move, &elapsed); n == 4 && mover >= 1 && mover <= 2 {
+			gl.Movers = append(gl.Movers, mover-1)
+			gl.Times = append(gl.Times, elapsed)
+			// Sscanf stops at spaces, which moves may contain.
+			_, move, _ = strings.Cut(comment, ": ")
+			commented = append(commented, move[:strings.LastIndex(move, " (")])
+		}
+		if n, _ := fmt.Sscanf(comment, "Rejected move %d by player %d:", &i, &mover); n == 2 && mover >= 1 && mover <= 2 {
+			gl.Rejected[mover-1]++
+		}
+		var limit string
+		if n, _ := fmt.Sscanf(comment, "Time limit of player %d: %s", &i, &limit); n == 2 && i >= 1 && i <= 2 {
+			gl.TimeLimit[i-1], _ = time.ParseDuration(limit)
+		}
+		fmt.Sscanf(comment, "CPU time: %fs - %fs.", &gl.CPUTime[0], &gl.CPUTime[1])
+		var periods int
+		if n, _ := fmt.Sscanf(comment, "Byo-yomi periods used by player %d: %d", &i, &periods); n == 2 && i >= 1 && i <= 2 {
+			gl.Periods[i-1] = periods
+		}
+		if n, _ := fmt.Sscanf(comment, "Increment: %s", &limit); n == 1 {
+			gl.Increment, _ = time.ParseDuration(limit)
+		}
+		fmt.Sscanf(comment, "Arbiter game log, format %d", &gl.Version)
+		if version, ok := strings.CutPrefix(comment, "Arbiter version: "); ok {
+			gl.Arbiter = version
+		}
+		if date, ok := strings.CutPrefix(comment, "Date: "); ok {
+			gl.Date, _ = time.Parse(time.RFC3339, date)
+		}
+		if name, ok := strings.CutPrefix(comment, "Game type: "); ok {
+			gl.Game = name
+		}
+		if position, ok := strings.CutPrefix(comment, "Position: "); ok {
+			gl.Position = position
+		}
+		fmt.Sscanf(comment, "Game: %s", &gl.GameId)
+		fmt.Sscanf(comment, "Seed: %d", &gl.Seed)
+		// Older logs only record the seed of games that were dealt.
+		if n, _ := fmt.Sscanf(comment, "Game seed: %d", &gl.GameSeed); n == 1 {
+			gl.Deal = gl.GameSeed
+		} else if n, _ := fmt.Sscanf(comment, "Deal: %d", &gl.Deal); n == 1 {
+			gl.GameSeed = gl.Deal
+		}
+		fmt.Sscanf(comment, "Komi: %d", &gl.Handicap.Komi)
+		fmt.Sscanf(comment, "Handicap: %d", &gl.Handicap.Stones)
+		if _, err := fmt.Sscanf(comment, "Score: %d - %d.", &gl.Score[0], &gl.Score[1]); err == nil {
+			gl.HasScore = true
+		}
+		switch {
+		case comment == "Draw agreed.":
+			gl.DrawAgreed = true
+		case comment == "Game forfeited.":
+			gl.Forfeited = true
+		case comment == "Game not played.":
+			gl.NotPlayed = true
+		case comment == "Players swapped sides after the first move.":
+			gl.Swapped = true
+		case comment == "Game interrupted!":
+			gl.Interrupted = true
+		case strings.HasPrefix(comment, "Game adjudicated after "):
+			gl.Adjudicated = true
+		case strings.HasPrefix(comment, "Game solved after "):
+			gl.Solved = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if gl.Version > FormatVersion {
+		return nil, fmt.Errorf("log format version %d is not supported (up to %d)", gl.Version, FormatVersion)
+	}
+	if g == nil {
+		if gl.Game == "" {
+			return nil, ErrNoGame
+		}
+		var err error
+		if g, err = game.Lookup(gl.Game); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(body.String()) == "" {
+		gl.Moves = commented
+	} else if lp, ok := g.(LogParser); ok {
+		moves, err := lp.ParseLog(body.String())
+		if err != nil {
+			return nil, err
+		}
+		gl.Moves = moves
+	} else {
+		for _, line := range strings.Split(body.String(), "\n") {
+			if line != "" {
+				gl.Moves = append(gl.Moves, line)
+			}
+		}
+	}
+	return gl, nil
+}
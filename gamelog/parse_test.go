@@ -0,0 +1,114 @@
+package gamelog
+
+import (
+	"arbiter/game"
+	_ "arbiter/game/connect4"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestLog(t *testing.T, g game.Game, name string) *GameLog {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gl, err := ReadLog(g, f)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return gl
+}
+
+func TestReadLog(t *testing.T) {
+	connect4, err := game.Lookup("connect4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := Header{Version: 1, Arbiter: "v1.2.0 53ebc97c0426",
+		Date: time.Date(2026, 10, 15, 3, 51, 4, 0, time.UTC), Game: "connect4",
+		Position: "......./......./......./......./......./.......",
+		GameId:   "53ebc97c0426-0001", Players: [2]string{"./player1", "./player2 --fast"},
+		Seed: 42, GameSeed: 4242}
+	tests := []struct {
+		name string
+		g    game.Game // game given to ReadLog
+		want GameLog
+	}{
+		{"connect4-v1.log", nil, GameLog{
+			Header: header, Deal: 4242,
+			TimeLimit: [2]time.Duration{10 * time.Second, 10 * time.Second},
+			Moves:     []string{"6", "3", "5", "2", "6", "1", "7", "5", "5", "4"},
+			Movers:    []int{0, 1, 0, 1, 0, 1, 0, 1, 0, 1},
+			Times:     []float64{0.120, 0.031, 0.250, 0.044, 0.118, 0.029, 0.301, 0.037, 0.090, 0.026},
+			CPUTime:   [2]float64{0.850, 0.160},
+			Score:     [2]int{0, 1}, HasScore: true,
+			Exit: [2]string{"exit status 0", ""}}},
+		// A game in progress, whose moves are only in the comments.
+		{"connect4-progress.log", nil, GameLog{
+			Header: header, Deal: 4242,
+			TimeLimit: [2]time.Duration{10 * time.Second, 10 * time.Second},
+			Moves:     []string{"6", "3", "5", "2"},
+			Movers:    []int{0, 1, 0, 1},
+			Times:     []float64{0.120, 0.031, 0.250, 0.044}}},
+		// Written before the format was versioned, when logs didn't record
+		// the game, and the game seed was called the deal.
+		{"connect4-v0.log", connect4, GameLog{
+			Header: Header{GameId: "85fb2faa622b-0003", Players: [2]string{"./player1", "./player2"},
+				Seed: 1234, GameSeed: 77},
+			Deal:      77,
+			TimeLimit: [2]time.Duration{5 * time.Second, 5 * time.Second},
+			Moves:     []string{"4", "4", "3"},
+			Movers:    []int{0, 1, 0},
+			Times:     []float64{0.5, 0.25, 0.75},
+			CPUTime:   [2]float64{1.2, 0.3},
+			Score:     [2]int{1, 0}, HasScore: true,
+			Failed:    [2]bool{false, true},
+			Code:      [2]string{"", "crash"},
+			Restarted: [2]bool{false, true},
+			Forfeited: true}},
+	}
+	for _, tt := range tests {
+		got := readTestLog(t, tt.g, tt.name)
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got\n%+v\nwant\n%+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestReadLogHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		err  string // expected error, or "" if the log is read
+	}{
+		{"newer version", "# Arbiter game log, format 2\n# Game type: connect4\n4\n", "log format version 2 is not supported (up to 1)"},
+		{"no game", "# Arbiter game log, format 1\n# Game: x-0001\n4\n", ErrNoGame.Error()},
+		{"unknown game", "# Arbiter game log, format 1\n# Game type: nosuchgame\n4\n", "nosuchgame"},
+		{"invalid parameters", "# Arbiter game log, format 1\n# Game type: connect4:x\n4\n", "connect4"},
+		// Lines that can't be parsed are ignored, as readers must ignore
+		// comments they don't understand.
+		{"invalid version", "# Arbiter game log, format x\n# Game type: connect4\n4\n", ""},
+		{"invalid date", "# Date: yesterday\n# Game type: connect4\n4\n", ""},
+		{"invalid player", "# Player 3: ./player3\n# Game type: connect4\n4\n", ""},
+	}
+	for _, tt := range tests {
+		gl, err := ReadLog(nil, strings.NewReader(tt.log))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err == "" && (gl.Version != 0 || !gl.Date.IsZero() || gl.Players != [2]string{} || len(gl.Moves) != 1):
+			t.Errorf("%s: got %+v", tt.name, gl)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := ReadLog(nil, strings.NewReader("4\n")); !errors.Is(err, ErrNoGame) {
+		t.Errorf("log without header: got error %v, want ErrNoGame", err)
+	}
+}
@@ -0,0 +1,76 @@
+package match
+
+import (
+	"arbiter/game"
+	"strings"
+)
+
+// AdjudicationMethods lists the valid values of Options.Adjudication.
+var AdjudicationMethods = []string{"scores", "game", "draw"}
+
+// ValidAdjudication returns whether method is a valid adjudication method.
+func ValidAdjudication(method string) bool {
+	for _, m := range AdjudicationMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// AdjudicationNames returns a comma-separated list of adjudication methods.
+func AdjudicationNames() string {
+	return strings.Join(AdjudicationMethods, ", ")
+}
+
+// FailurePolicies lists the valid values of Options.FailurePolicy.
+var FailurePolicies = []string{"random-moves", "immediate-forfeit", "adjudicate-current-position"}
+
+// ValidFailurePolicy returns whether policy is a valid failure policy.
+func ValidFailurePolicy(policy string) bool {
+	for _, p := range FailurePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// FailurePolicyNames returns a comma-separated list of failure policies.
+func FailurePolicyNames() string {
+	return strings.Join(FailurePolicies, ", ")
+}
+
+// IllegalMovePolicies lists the valid values of Options.IllegalMoves.
+var IllegalMovePolicies = []string{"fail", "retry", "warn"}
+
+// ValidIllegalMovePolicy returns whether policy is a valid illegal move policy.
+func ValidIllegalMovePolicy(policy string) bool {
+	for _, p := range IllegalMovePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// IllegalMovePolicyNames returns a comma-separated list of illegal move
+// policies.
+func IllegalMovePolicyNames() string {
+	return strings.Join(IllegalMovePolicies, ", ")
+}
+
+// adjudicate returns the scores for a game that was stopped before it was
+// over. With the "game" method, games that do not implement game.Adjudicator
+// are adjudicated by their current scores instead.
+func adjudicate(method string, gamestate game.GameState) (int, int) {
+	switch method {
+	case "draw":
+		return 0, 0
+	case "game":
+		if a, ok := gamestate.(game.Adjudicator); ok {
+			return a.Adjudicate()
+		}
+	}
+	return gamestate.Scores()
+}
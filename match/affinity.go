@@ -0,0 +1,28 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUList parses a list of CPU cores like "0-3,6", as used by taskset and
+// cgroups, into the numbers of the cores.
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		lo, err := strconv.Atoi(first)
+		hi := lo
+		if err == nil && isRange {
+			hi, err = strconv.Atoi(last)
+		}
+		if err != nil || lo < 0 || hi < lo {
+			return nil, fmt.Errorf("invalid CPU list: %s", list)
+		}
+		for cpu := lo; cpu <= hi; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,106 @@
+package match
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// cpuMask is a set of CPU cores, as passed to sched_setaffinity.
+type cpuMask [1024 / 64]uint64
+
+func newCPUMask(cpus []int) (*cpuMask, error) {
+	var mask cpuMask
+	for _, cpu := range cpus {
+		if cpu >= len(mask)*64 {
+			return nil, syscall.EINVAL
+		}
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+	return &mask, nil
+}
+
+func getAffinity(tid int) (*cpuMask, error) {
+	var mask cpuMask
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, uintptr(tid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return &mask, nil
+}
+
+func setAffinity(tid int, mask *cpuMask) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Pinning of the arbiter before PinArbiter was called, or nil if it wasn't.
+var unpinnedMask *cpuMask
+
+// startPinned calls start with the current thread pinned to the given CPU
+// cores, so that the process it starts inherits the pinning from the start,
+// and then restores the pinning of the thread.
+func startPinned(cpus []int, start func() error) error {
+	mask, err := newCPUMask(cpus)
+	if err != nil {
+		return err
+	}
+	return startWithMask(mask, start)
+}
+
+// startUnpinned calls start so that the process it starts isn't pinned to the
+// arbiter's CPU cores if PinArbiter was called, but can run on the cores the
+// arbiter could use before.
+func startUnpinned(start func() error) error {
+	if unpinnedMask == nil {
+		return start()
+	}
+	return startWithMask(unpinnedMask, start)
+}
+
+func startWithMask(mask *cpuMask, start func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	old, err := getAffinity(0)
+	if err != nil {
+		return err
+	}
+	if err := setAffinity(0, mask); err != nil {
+		return fmt.Errorf("couldn't set CPU affinity: %w", err)
+	}
+	defer setAffinity(0, old)
+	return start()
+}
+
+// PinArbiter pins all threads of the arbiter to the given CPU cores. Threads
+// started later inherit the pinning, but player processes don't (see
+// startUnpinned).
+func PinArbiter(cpus []int) error {
+	mask, err := newCPUMask(cpus)
+	if err != nil {
+		return err
+	}
+	if unpinnedMask == nil {
+		if unpinnedMask, err = getAffinity(os.Getpid()); err != nil {
+			return err
+		}
+	}
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if tid, err := strconv.Atoi(task.Name()); err == nil {
+			if err := setAffinity(tid, mask); err != nil {
+				return fmt.Errorf("couldn't set CPU affinity: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package match
+
+import "errors"
+
+var errAffinity = errors.New("CPU affinity is only supported on Linux")
+
+func startPinned(cpus []int, start func() error) error {
+	return errAffinity
+}
+
+func startUnpinned(start func() error) error {
+	return start()
+}
+
+func PinArbiter(cpus []int) error {
+	return errAffinity
+}
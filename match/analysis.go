@@ -0,0 +1,162 @@
+package match
+
+import (
+	"arbiter/game"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// AnalysisOptions configures the analysis of finished games by a reference
+// engine, which looks for moves that made the player's position much worse.
+type AnalysisOptions struct {
+	Engine    string // command or engine name of the reference engine, or "" for no analysis
+	Go        string // arguments of the "go" command, e.g. "movetime 1000"
+	Threshold int    // minimum drop in evaluation for a move to be a blunder
+}
+
+// Blunder is a move after which the reference engine's evaluation of the
+// position, from the point of view of the player that made it, dropped by at
+// least AnalysisOptions.Threshold.
+type Blunder struct {
+	Move   int    `json:"move"`   // 1-based move number
+	Player int    `json:"player"` // 0-based index of the player that made the move
+	Text   string `json:"text"`   // the move
+	Before int    `json:"before"` // evaluation before the move
+	After  int    `json:"after"`  // evaluation after the move
+}
+
+// Evaluation given to positions in which the side to move can force a win.
+// Each move until the win reduces the evaluation by 1.
+const mateScore = 100000
+
+var errNotAnalyzable = errors.New("games with hidden information, dealt initial states or simultaneous moves can't be analyzed")
+
+// Analyze asks the reference engine configured in opts.Analysis to evaluate
+// each position of a game, and returns the blunders it finds. The engine must
+// implement the Universal Game Interface, and report its evaluation as
+// "info score cp <n>" or "info score mate <n>" before its best move.
+func Analyze(ctx context.Context, opts *Options, history []string) ([]Blunder, error) {
+	if _, ok := opts.Game.(game.Viewer); ok {
+		return nil, errNotAnalyzable
+	}
+	if _, ok := opts.Game.(game.Dealer); ok {
+		return nil, errNotAnalyzable
+	}
+
+	// Determine which player was to move in each position.
+	state := opts.Game.CreateState()
+	next := make([]int, len(history)+1)
+	for i, move := range history {
+		if game.IsSimultaneous(state) {
+			return nil, errNotAnalyzable
+		}
+		next[i] = state.Next()
+		arg, ok := opts.Game.ParseMove(move)
+		if !ok || !state.Execute(arg) {
+			return nil, fmt.Errorf("invalid move: %s", move)
+		}
+	}
+	over := state.Over()
+	if !over {
+		next[len(history)] = state.Next()
+	}
+
+	engine := opts.engine(opts.Analysis.Engine)
+	proc, stdin, stdout, err := runPlayer(ctx, opts, engine, nil, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	c := newConnection(opts, stdout, stdin, nil)
+	defer func() {
+		c.close()
+		if proc != nil {
+			proc.Kill()
+			proc.Wait()
+		}
+	}()
+	if err := (UGIProtocol{}).Start(c, true, game.Settings(opts.Game)); err != nil {
+		return nil, err
+	}
+
+	// Evaluate each position from the point of view of the player to move.
+	// The final position is not evaluated if the game is over.
+	evals := make([]int, 0, len(history)+1)
+	for i := 0; i <= len(history) && !(i == len(history) && over); i++ {
+		position := "position startpos"
+		if i > 0 {
+			position += " moves " + strings.Join(history[:i], " ")
+		}
+		eval, err := evaluate(c, position, opts.Analysis.Go)
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, eval)
+	}
+	c.writeLine("quit")
+
+	var blunders []Blunder
+	for i := 0; i+1 < len(evals); i++ {
+		before, after := evals[i], evals[i+1]
+		if next[i+1] != next[i] {
+			after = -after
+		}
+		if before-after >= opts.Analysis.Threshold {
+			blunders = append(blunders, Blunder{i + 1, next[i], history[i], before, after})
+		}
+	}
+	return blunders, nil
+}
+
+// evaluate asks a UGI engine to search the given position, and returns the
+// last evaluation it reported.
+func evaluate(c *Connection, position, goArgs string) (int, error) {
+	if err := c.writeLine(position); err != nil {
+		return 0, err
+	}
+	if err := c.writeLine(strings.TrimSpace("go " + goArgs)); err != nil {
+		return 0, err
+	}
+	eval, found := 0, false
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if err == io.EOF {
+				err = errUnexpectedEOF
+			}
+			return 0, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "bestmove" {
+			if !found {
+				return 0, errors.New("no evaluation reported for " + position)
+			}
+			return eval, nil
+		}
+		if len(fields) == 0 || fields[0] != "info" {
+			continue
+		}
+		for i := 1; i+2 < len(fields); i++ {
+			if fields[i] != "score" {
+				continue
+			}
+			n, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				break
+			}
+			switch fields[i+1] {
+			case "cp":
+				eval, found = n, true
+			case "mate":
+				if n >= 0 {
+					eval, found = mateScore-n, true
+				} else {
+					eval, found = -mateScore-n, true
+				}
+			}
+		}
+	}
+}
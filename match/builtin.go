@@ -0,0 +1,166 @@
+package match
+
+import (
+	"arbiter/game"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix of player commands that denote players built into the arbiter.
+const builtinPrefix = "builtin:"
+
+// Players built into the arbiter, by name.
+var builtins = map[string]func(g game.Game) Player{}
+
+func init() {
+	RegisterPlayer("random", strategyPlayer(randomMove))
+	RegisterPlayer("greedy", strategyPlayer(greedyMove))
+}
+
+// RegisterPlayer makes a player implemented in Go available as
+// "builtin:<name>". The create function is called to create a new instance of
+// the player for each game, which is passed the game being played. This is
+// typically called from an init function.
+func RegisterPlayer(name string, create func(g game.Game) Player) {
+	if builtins[name] != nil {
+		panic("Player registered twice: " + name)
+	}
+	builtins[name] = create
+}
+
+func isBuiltin(command string) bool {
+	return strings.HasPrefix(command, builtinPrefix)
+}
+
+func newBuiltinPlayer(g game.Game, command string) (Player, error) {
+	create := builtins[command[len(builtinPrefix):]]
+	if create == nil {
+		return nil, errors.New("unknown built-in player")
+	}
+	return create(g), nil
+}
+
+// Strategy selects a move for the player to move in the given state of game g,
+// which was reached by playing the given moves. It must not modify the state.
+type Strategy func(g game.Game, gamestate game.GameState, history []string) interface{}
+
+// StrategyPlayer is a built-in player that keeps track of the game state and
+// selects moves using a Strategy. In turns where both players move at once
+// (see game.SimultaneousState), it moves randomly instead.
+type StrategyPlayer struct {
+	strategy Strategy
+	game     game.Game
+	state    game.GameState
+	player   int         // 0-based index of the player
+	pending  interface{} // own move in a simultaneous turn, until the opponent's is known
+}
+
+func strategyPlayer(strategy Strategy) func(g game.Game) Player {
+	return func(g game.Game) Player {
+		return &StrategyPlayer{strategy: strategy, game: g}
+	}
+}
+
+func (sp *StrategyPlayer) NotifyStart(first bool) error {
+	sp.state = sp.game.CreateState()
+	if !first {
+		sp.player = 1
+	}
+	return nil
+}
+
+func (sp *StrategyPlayer) Resume(first bool, history []string, own []bool) error {
+	sp.NotifyStart(first)
+	var moves []string
+	for _, move := range history {
+		if move != swapToken {
+			moves = append(moves, move)
+		}
+	}
+	state, err := Replay(sp.game, moves)
+	sp.state = state
+	return err
+}
+
+func (sp *StrategyPlayer) GetMove(history []string) (string, error) {
+	if ss, ok := sp.state.(game.SimultaneousState); ok && ss.Simultaneous() {
+		sp.pending = randomPlayerMove(ss, sp.player)
+		return sp.pending.(fmt.Stringer).String(), nil
+	}
+	move := sp.strategy(sp.game, sp.state, history)
+	if !sp.state.Execute(move) {
+		return "", errors.New("invalid move generated")
+	}
+	return move.(fmt.Stringer).String(), nil
+}
+
+func (sp *StrategyPlayer) NotifyMove(move string) error {
+	if move == swapToken {
+		// Only the sides change, not the game state.
+		return nil
+	}
+	if ss, ok := sp.state.(game.SimultaneousState); ok && sp.pending != nil {
+		var moves [2]interface{}
+		moves[sp.player], sp.pending = sp.pending, nil
+		var ok bool
+		if moves[1-sp.player], ok = sp.game.ParseMove(move); !ok || ss.ExecuteBoth(moves) != [2]bool{true, true} {
+			return fmt.Errorf("invalid move received: %s", move)
+		}
+		return nil
+	}
+	if m, ok := sp.game.ParseMove(move); !ok || !sp.state.Execute(m) {
+		return fmt.Errorf("invalid move received: %s", move)
+	}
+	return nil
+}
+
+func (sp *StrategyPlayer) Quit() {
+}
+
+// randomMove selects a move uniformly at random.
+func randomMove(g game.Game, gamestate game.GameState, history []string) interface{} {
+	moves := gamestate.ListMoves()
+	return moves[rng.Intn(len(moves))]
+}
+
+// randomPlayerMove selects a move for the given player in a simultaneous turn
+// uniformly at random.
+func randomPlayerMove(ss game.SimultaneousState, player int) interface{} {
+	moves := ss.PlayerMoves(player)
+	return moves[rng.Intn(len(moves))]
+}
+
+// greedyMove selects a move that maximizes the difference between the player's
+// score and the opponent's score after the move. Ties are broken randomly.
+func greedyMove(g game.Game, gamestate game.GameState, history []string) interface{} {
+	player := gamestate.Next()
+	var best []interface{}
+	bestValue := 0
+	for _, move := range gamestate.ListMoves() {
+		state := branch(g, gamestate, history)
+		if !state.Execute(move) {
+			panic("Invalid move generated!")
+		}
+		var score [2]int
+		score[0], score[1] = state.Scores()
+		value := score[player] - score[1-player]
+		if len(best) == 0 || value > bestValue {
+			best, bestValue = nil, value
+		}
+		if value == bestValue {
+			best = append(best, move)
+		}
+	}
+	return best[rng.Intn(len(best))]
+}
+
+// branch returns a copy of gamestate, which was reached by playing the given
+// moves (see Branch).
+func branch(g game.Game, gamestate game.GameState, history []string) game.GameState {
+	state, err := Branch(g, gamestate, history)
+	if err != nil {
+		panic("Invalid move in history!")
+	}
+	return state
+}
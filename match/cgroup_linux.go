@@ -0,0 +1,120 @@
+//go:build linux
+
+package match
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Cgroup is a transient cgroup (v2) created to run a single player process.
+type Cgroup struct {
+	path string
+	dir  *os.File
+}
+
+var cgroupCount = 0
+
+// createCgroup creates a new cgroup under the configured parent, with the
+// configured CPU and memory limits.
+func createCgroup(co *CgroupOptions) (*Cgroup, error) {
+	cgroupCount++
+	path := filepath.Join(co.Parent, fmt.Sprintf("arbiter-%d-%d", os.Getpid(), cgroupCount))
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, err
+	}
+	cg := &Cgroup{path: path}
+	if co.CPUs > 0 {
+		if err := cg.write("cpu.max", fmt.Sprintf("%d 100000", int(co.CPUs*100000))); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+	if co.Memory != "" {
+		if err := cg.write("memory.max", co.Memory); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.dir = dir
+	return cg, nil
+}
+
+func (cg *Cgroup) write(name, value string) error {
+	return os.WriteFile(filepath.Join(cg.path, name), []byte(value), 0644)
+}
+
+// configure sets process attributes that cause a new process to be started
+// inside the cgroup.
+func (cg *Cgroup) configure(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(cg.dir.Fd())
+}
+
+// peakMemory returns the maximum memory usage of the cgroup in bytes, or 0 if
+// unknown.
+func (cg *Cgroup) peakMemory() int64 {
+	data, err := os.ReadFile(filepath.Join(cg.path, "memory.peak"))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n
+}
+
+// cpuTime returns the CPU time used by the processes in the cgroup, or 0 if
+// unknown.
+func (cg *Cgroup) cpuTime() time.Duration {
+	data, err := os.ReadFile(filepath.Join(cg.path, "cpu.stat"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if usec, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			n, _ := strconv.ParseInt(usec, 10, 64)
+			return time.Duration(n) * time.Microsecond
+		}
+	}
+	return 0
+}
+
+// oomKilled returns whether any process in the cgroup was killed because the
+// cgroup ran out of memory.
+func (cg *Cgroup) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(cg.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			return count != "0"
+		}
+	}
+	return false
+}
+
+// remove kills any processes left in the cgroup and removes it.
+func (cg *Cgroup) remove() {
+	if cg.dir != nil {
+		cg.dir.Close()
+	}
+	cg.write("cgroup.kill", "1")
+	for i := 0; i < 100; i++ {
+		if err := os.Remove(cg.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	slog.Warn("couldn't remove cgroup", "path", cg.path)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package match
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// Cgroup is not supported on this platform.
+type Cgroup struct{}
+
+func createCgroup(co *CgroupOptions) (*Cgroup, error) {
+	return nil, errors.New("cgroups are only supported on Linux")
+}
+
+func (cg *Cgroup) configure(attr *syscall.SysProcAttr) {}
+func (cg *Cgroup) peakMemory() int64                   { return 0 }
+func (cg *Cgroup) cpuTime() time.Duration              { return 0 }
+func (cg *Cgroup) oomKilled() bool                     { return false }
+func (cg *Cgroup) remove()                             {}
@@ -0,0 +1,62 @@
+package match
+
+import "time"
+
+// gameClock keeps track of the time a player with a time limit has left in a
+// game: its main time, which grows by the increment after each move (see
+// Options.Increment), followed by its byo-yomi periods (see
+// Options.ByoYomiPeriods). A move that takes longer than the main time left
+// uses up one period for each full period it takes beyond it. The player runs
+// out of time when it uses up its last period, or its main time if it has no
+// periods.
+type gameClock struct {
+	limited   bool
+	main      time.Duration // main time left
+	increment time.Duration // added to the main time after each move
+	periods   int           // byo-yomi periods left
+	period    time.Duration // length of each byo-yomi period
+	used      int           // number of byo-yomi periods used up
+	flagged   bool          // whether the player ran out of time
+}
+
+// newGameClock returns the clock of a player with the given time limit, or
+// an unlimited clock if the limit is 0 and there is no byo-yomi.
+func newGameClock(limit time.Duration, opts *Options) gameClock {
+	byoYomi := opts.ByoYomiPeriods > 0 && opts.ByoYomiTime > 0
+	if limit <= 0 && !byoYomi {
+		return gameClock{}
+	}
+	c := gameClock{limited: true, main: max(limit, 0), increment: opts.Increment}
+	if byoYomi {
+		c.periods, c.period = opts.ByoYomiPeriods, opts.ByoYomiTime
+	}
+	return c
+}
+
+// left returns the time the player may use for its next move.
+func (c *gameClock) left() time.Duration {
+	return c.main + time.Duration(c.periods)*c.period
+}
+
+// use charges the time the player took for a move to the clock.
+func (c *gameClock) use(d time.Duration) {
+	if !c.limited || c.flagged {
+		return
+	}
+	if d < c.main {
+		c.main -= d
+	} else {
+		d -= c.main
+		c.main = 0
+		if d >= time.Duration(c.periods)*c.period {
+			c.used += c.periods
+			c.periods = 0
+			c.flagged = true
+			return
+		}
+		n := int(d / c.period)
+		c.periods -= n
+		c.used += n
+	}
+	c.main += c.increment
+}
@@ -0,0 +1,78 @@
+package match
+
+import (
+	"errors"
+	"strings"
+)
+
+// splitCommand splits a player command into arguments like a POSIX shell
+// would, without expanding variables or globs. Arguments are separated by
+// whitespace, which can be included in an argument by quoting it with single
+// or double quotes, or by escaping it with a backslash. Inside double quotes,
+// a backslash escapes only '"', '\', '$' and '`'.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false // whether an argument has been started (possibly empty)
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 == len(command) {
+				return nil, errors.New("trailing backslash in command")
+			}
+			i++
+			arg.WriteByte(command[i])
+			inArg = true
+		case c == '\'':
+			j := strings.IndexByte(command[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.New("unterminated single quote in command")
+			}
+			arg.WriteString(command[i+1 : i+1+j])
+			i += j + 1
+			inArg = true
+		case c == '"':
+			for i++; ; i++ {
+				if i == len(command) {
+					return nil, errors.New("unterminated double quote in command")
+				}
+				if command[i] == '"' {
+					break
+				}
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`", command[i+1]) >= 0 {
+					i++
+				}
+				arg.WriteByte(command[i])
+			}
+			inArg = true
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// ExpandVars replaces placeholders of the form {name} in s by the value of
+// the variable with that name. Placeholders of unknown variables are left
+// unchanged.
+func ExpandVars(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+	var pairs []string
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
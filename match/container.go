@@ -0,0 +1,110 @@
+package match
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ContainerOptions configures running players inside containers. If Image is
+// empty, players are run directly on the host. Engines may override the
+// image and limits (see Options.container).
+type ContainerOptions struct {
+	Image   string // container image
+	Runtime string // container runtime command, e.g. "docker" or "podman"
+	CPUs    string // CPU limit, if not empty
+	Memory  string // memory limit, if not empty
+}
+
+// container returns the options for running the given engine in a container,
+// with an empty Image if it runs on the host.
+func (opts *Options) container(e *Engine) ContainerOptions {
+	co := opts.Container
+	if e.Container != "" {
+		co.Image = e.Container
+	}
+	if e.ContainerCPUs != "" {
+		co.CPUs = e.ContainerCPUs
+	}
+	if e.ContainerMemory != "" {
+		co.Memory = e.ContainerMemory
+	}
+	return co
+}
+
+// containerize returns the argument list that runs argv inside a new
+// container with the given name, with the environment variables in env set,
+// pinned to the CPU cores in cpus if not empty, as the given user ("uid:gid")
+// if not empty. The working directory dir is mounted read-only at the same
+// path inside the container, and so is the directory containing the
+// executable if it's given by a path outside of dir. Networking is disabled.
+func (co *ContainerOptions) containerize(argv []string, dir string, env []string, cpus string, user string, name string) []string {
+	args := []string{co.Runtime, "run", "--rm", "--interactive",
+		"--name=" + name,
+		"--network=none",
+		fmt.Sprintf("--volume=%s:%s:ro", dir, dir),
+		"--workdir=" + dir}
+	if user != "" {
+		args = append(args, "--user="+user)
+	}
+	if exe := argv[0]; filepath.IsAbs(exe) {
+		if rel, err := filepath.Rel(dir, filepath.Dir(exe)); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			args = append(args, fmt.Sprintf("--volume=%s:%s:ro", filepath.Dir(exe), filepath.Dir(exe)))
+		}
+	}
+	if co.CPUs != "" {
+		args = append(args, "--cpus="+co.CPUs)
+	}
+	if cpus != "" {
+		args = append(args, "--cpuset-cpus="+cpus)
+	}
+	if co.Memory != "" {
+		args = append(args, "--memory="+co.Memory)
+	}
+	for _, kv := range env {
+		args = append(args, "--env="+kv)
+	}
+	args = append(args, co.Image)
+	return append(args, argv...)
+}
+
+// containerUser returns the ids of a user given by name or id on the host, as
+// "uid:gid", to run a container as. The runtime would look up names inside
+// the container otherwise. The container's processes get no supplementary
+// groups, like players run on the host (see Options.User).
+func containerUser(name string) (string, error) {
+	u, err := lookupUser(name)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid + ":" + u.Gid, nil
+}
+
+// newContainerName returns a unique name for a player's container, by which
+// it can be killed.
+func newContainerName() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "arbiter-" + hex.EncodeToString(b[:])
+}
+
+// killContainer kills the container the process runs in, if any. Killing the
+// runtime's client process doesn't stop the container.
+func (p *Process) killContainer() {
+	if p.container == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, p.runtime, "kill", p.container).CombinedOutput(); err != nil {
+		// Fails if the container exited already.
+		slog.Debug("couldn't kill container", "container", p.container, "error", err,
+			"output", strings.TrimSpace(string(out)))
+	}
+}
@@ -0,0 +1,43 @@
+package match
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestContainerize(t *testing.T) {
+	co := ContainerOptions{Image: "img", Runtime: "docker", CPUs: "1", Memory: "512m"}
+	tests := []struct {
+		name  string
+		argv  []string
+		cpus  string
+		user  string
+		extra []string // arguments between the common ones and the image
+	}{
+		{"plain", []string{"./bot"}, "", "", []string{"--cpus=1", "--memory=512m"}},
+		{"pinned", []string{"./bot"}, "0-1", "", []string{"--cpus=1", "--cpuset-cpus=0-1", "--memory=512m"}},
+		{"user", []string{"./bot"}, "", "1001:1001", []string{"--user=1001:1001", "--cpus=1", "--memory=512m"}},
+		{"outside", []string{"/opt/bots/bot"}, "", "", []string{"--volume=/opt/bots:/opt/bots:ro", "--cpus=1", "--memory=512m"}},
+	}
+	for _, tt := range tests {
+		got := co.containerize(tt.argv, "/work", []string{"A=1"}, tt.cpus, tt.user, "arbiter-x")
+		want := append([]string{"docker", "run", "--rm", "--interactive", "--name=arbiter-x",
+			"--network=none", "--volume=/work:/work:ro", "--workdir=/work"}, tt.extra...)
+		want = append(want, "--env=A=1", "img")
+		want = append(want, tt.argv...)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestContainerUser(t *testing.T) {
+	for _, name := range []string{"root", "0"} {
+		if got, err := containerUser(name); err != nil || got != "0:0" {
+			t.Errorf("%s: got %q, %v, want 0:0", name, got, err)
+		}
+	}
+	if _, err := containerUser("no-such-user-arbiter"); err == nil {
+		t.Error("no error for unknown user")
+	}
+}
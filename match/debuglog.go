@@ -0,0 +1,117 @@
+package match
+
+import (
+	"arbiter/gamelog"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Format of the timestamps in message logs and debug logs.
+const timestampFormat = "15:04:05.000000"
+
+// lineWriter splits the data written to it into lines, and passes each line
+// (without the line ending) to emit. Closing it passes any incomplete last
+// line, and closes next, if not nil.
+type lineWriter struct {
+	emit func(line string)
+	next io.Closer
+	buf  []byte
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lw.emit(string(bytes.TrimSuffix(lw.buf[:i], []byte("\r"))))
+		lw.buf = lw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (lw *lineWriter) Close() error {
+	if len(lw.buf) > 0 {
+		lw.emit(string(lw.buf))
+		lw.buf = nil
+	}
+	if lw.next != nil {
+		return lw.next.Close()
+	}
+	return nil
+}
+
+// timestamped returns a writer that writes each line written to it to w,
+// prefixed with the current time. Closing it closes w.
+func timestamped(w io.WriteCloser) io.WriteCloser {
+	return &lineWriter{next: w, emit: func(line string) {
+		fmt.Fprintf(w, "%s %s\n", time.Now().Format(timestampFormat), line)
+	}}
+}
+
+// debugLog is a log of everything that happened during a game, in
+// chronological order: the messages exchanged with both players, and the
+// messages they wrote to stderr, each with a timestamp and the player
+// involved. It may be written to by multiple goroutines.
+type debugLog struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// createDebugLog creates a debug log at the given path.
+func createDebugLog(path, gameId string) (*debugLog, error) {
+	w, err := gamelog.CreateLog(path)
+	if err != nil {
+		return nil, err
+	}
+	if gameId != "" {
+		fmt.Fprintf(w, "# Game: %s\n", gameId)
+	}
+	return &debugLog{w: w}, nil
+}
+
+// write adds a line to the log, about the given 0-based player. The kind of
+// line is "<-" for a message sent to the player, "->" for a message received
+// from it, or "stderr:" for a message it wrote to stderr.
+func (d *debugLog) write(player int, kind, line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Fprintf(d.w, "%s P%d %s %s\n", time.Now().Format(timestampFormat), player+1, kind, line)
+}
+
+func (d *debugLog) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.w.Close()
+}
+
+// debugTrace records the messages of one player in a debug log.
+type debugTrace struct {
+	log    *debugLog
+	player int
+}
+
+func (t *debugTrace) sent(line string)     { t.log.write(t.player, "<-", line) }
+func (t *debugTrace) received(line string) { t.log.write(t.player, "->", line) }
+
+// stderr returns a writer for the messages that the player writes to stderr.
+func (t *debugTrace) stderr() io.WriteCloser {
+	return &lineWriter{emit: func(line string) { t.log.write(t.player, "stderr:", line) }}
+}
+
+// multiCloser closes several files.
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var first error
+	for _, c := range mc {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,276 @@
+package match
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Engine describes how to run a player program. Engines are defined in engine
+// files (see ReadEngines) and added to Options.Engines, after which their
+// names can be used in place of player commands.
+type Engine struct {
+	Name     string
+	Command  string   // command line, split into arguments like a shell would
+	Args     []string // additional arguments, used as is
+	Env      []string // additional environment variables, as "KEY=value"
+	ClearEnv bool     // don't inherit the arbiter's environment
+	Dir      string   // working directory, if not the default (see workDir)
+	Protocol string   // name of the protocol to use, or "" for Options.Protocol
+
+	// Total time the engine may use per game, or 0 for Options.TimeLimit.
+	TimeLimit time.Duration
+
+	Nice   int    // scheduling priority (nice level), if not 0
+	IONice string // I/O scheduling class and level (see ParseIONice), if not empty
+	User   string // user name or id to run the engine as, if not Options.User
+
+	// Container image to run the engine in, and its CPU and memory limits,
+	// if not those of Options.Container.
+	Container       string
+	ContainerCPUs   string
+	ContainerMemory string
+}
+
+// engine returns the engine registered under the given name, or an engine
+// that runs name as a command if there is none.
+func (opts *Options) engine(name string) *Engine {
+	if e, ok := opts.Engines[name]; ok {
+		return e
+	}
+	return &Engine{Name: name, Command: name}
+}
+
+// workDir returns the working directory for the engine, given the path to its
+// executable. Unless Dir is set, engines whose executable is given by path run
+// in the directory containing it, and others (found through $PATH) run in the
+// arbiter's working directory.
+func (e *Engine) workDir(exe string, vars map[string]string) (string, error) {
+	if e.Dir != "" {
+		return filepath.Abs(ExpandVars(e.Dir, vars))
+	}
+	if strings.Contains(exe, "/") {
+		return filepath.Dir(exe), nil
+	}
+	return os.Getwd()
+}
+
+// SetDir sets the working directory of the engine with the given name. If no
+// such engine is defined, one is defined that runs name as a command.
+func (opts *Options) SetDir(name string, dir string) {
+	opts.redefine(name).Dir = dir
+}
+
+// AddEnv adds environment variables, given as "KEY=value", to the engine with
+// the given name. If no such engine is defined, one is defined that runs name
+// as a command.
+func (opts *Options) AddEnv(name string, env ...string) {
+	e := opts.redefine(name)
+	e.Env = append(append([]string(nil), e.Env...), env...)
+}
+
+// SetTimeLimit sets the time limit per game of the engine with the given name,
+// e.g. to give it less time than its opponents. If no such engine is defined,
+// one is defined that runs name as a command.
+func (opts *Options) SetTimeLimit(name string, limit time.Duration) {
+	opts.redefine(name).TimeLimit = limit
+}
+
+// timeLimit returns the time the given engine may use per game, or 0 if it is
+// unlimited.
+func (opts *Options) timeLimit(name string) time.Duration {
+	if limit := opts.engine(name).TimeLimit; limit > 0 {
+		return limit
+	}
+	return opts.TimeLimit
+}
+
+// SetNice sets the nice level of the engine with the given name. If no such
+// engine is defined, one is defined that runs name as a command.
+func (opts *Options) SetNice(name string, nice int) {
+	opts.redefine(name).Nice = nice
+}
+
+// SetIONice sets the I/O scheduling class of the engine with the given name.
+// If no such engine is defined, one is defined that runs name as a command.
+func (opts *Options) SetIONice(name string, ionice string) {
+	opts.redefine(name).IONice = ionice
+}
+
+// user returns the user to run the given engine as, or "" to run it as the
+// arbiter's user.
+func (opts *Options) user(e *Engine) string {
+	if e.User != "" {
+		return e.User
+	}
+	return opts.User
+}
+
+// redefine replaces the engine with the given name by a copy that can be
+// modified without affecting other copies of opts.
+func (opts *Options) redefine(name string) *Engine {
+	e := *opts.engine(name)
+	engines := map[string]*Engine{name: &e}
+	for n, other := range opts.Engines {
+		if n != name {
+			engines[n] = other
+		}
+	}
+	opts.Engines = engines
+	return &e
+}
+
+// protocol returns the protocol to use for the given engine.
+func (opts *Options) protocol(e *Engine) Protocol {
+	if p, ok := Protocols[e.Protocol]; ok {
+		return p
+	}
+	return opts.Protocol
+}
+
+// ReadEngines parses engine definitions. Each definition starts with the name
+// of the engine in square brackets, followed by "key = value" lines:
+//
+//	[bot-book]
+//	command = ./bot --threads 1
+//	arg = --book
+//	arg = my book.bin
+//	env = OMP_NUM_THREADS=1
+//	clearenv = yes
+//	dir = /opt/bot
+//	protocol = ugi
+//	time = 10s
+//	nice = 10
+//	ionice = best-effort:7
+//	user = player1
+//	container = python:3.12-slim
+//	container-cpus = 1
+//	container-memory = 512m
+//
+// The "time" key gives the engine's time limit per game (see
+// Engine.TimeLimit), and "nice" and "ionice" its priority (see Engine.Nice
+// and ParseIONice). The "user" key gives the user to run the engine as (see
+// Options.User), and "container", "container-cpus" and "container-memory"
+// the container to run it in and its limits (see ContainerOptions). The "arg"
+// and "env" keys may be repeated. With "clearenv = yes", the engine
+// gets only the environment variables given with "env". Empty lines and lines
+// starting with '#' or ';' are ignored.
+func ReadEngines(r io.Reader) ([]*Engine, error) {
+	var engines []*Engine
+	var e *Engine
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			e = &Engine{Name: strings.TrimSpace(line[1 : len(line)-1])}
+			if e.Name == "" {
+				return nil, fmt.Errorf("line %d: empty engine name", lineNo)
+			}
+			engines = append(engines, e)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", lineNo)
+		}
+		if e == nil {
+			return nil, fmt.Errorf("line %d: %s outside of engine definition", lineNo, strings.TrimSpace(key))
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "command":
+			e.Command = value
+		case "arg":
+			e.Args = append(e.Args, value)
+		case "env":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("line %d: expected env = KEY=value", lineNo)
+			}
+			e.Env = append(e.Env, value)
+		case "clearenv":
+			switch value {
+			case "yes":
+				e.ClearEnv = true
+			case "no":
+				e.ClearEnv = false
+			default:
+				return nil, fmt.Errorf("line %d: expected clearenv = yes or no", lineNo)
+			}
+		case "dir":
+			e.Dir = value
+		case "protocol":
+			if _, ok := Protocols[value]; !ok {
+				return nil, fmt.Errorf("line %d: unknown protocol: %s", lineNo, value)
+			}
+			e.Protocol = value
+		case "time":
+			limit, err := time.ParseDuration(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("line %d: expected time = <duration>, e.g. 10s", lineNo)
+			}
+			e.TimeLimit = limit
+		case "nice":
+			nice, err := strconv.Atoi(value)
+			if err != nil || nice < -20 || nice > 19 {
+				return nil, fmt.Errorf("line %d: expected nice = <level> from -20 to 19", lineNo)
+			}
+			e.Nice = nice
+		case "ionice":
+			if _, _, err := ParseIONice(value); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err)
+			}
+			e.IONice = value
+		case "user":
+			e.User = value
+		case "container":
+			e.Container = value
+		case "container-cpus":
+			e.ContainerCPUs = value
+		case "container-memory":
+			e.ContainerMemory = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown key: %s", lineNo, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, e := range engines {
+		if e.Command == "" {
+			return nil, fmt.Errorf("engine %s has no command", e.Name)
+		}
+	}
+	return engines, nil
+}
+
+// LoadEngines reads the engine definitions in the file at path, and adds them
+// to opts.Engines.
+func (opts *Options) LoadEngines(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	engines, err := ReadEngines(f)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if opts.Engines == nil {
+		opts.Engines = map[string]*Engine{}
+	}
+	for _, e := range engines {
+		if _, ok := opts.Engines[e.Name]; ok {
+			return fmt.Errorf("%s: engine %s defined twice", path, e.Name)
+		}
+		opts.Engines[e.Name] = e
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package match
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event describes something that happened during a game or tournament.
+type Event struct {
+	Type      string      `json:"type"`
+	Time      time.Time   `json:"time"`
+	Game      int         `json:"game,omitempty"`      // 1-based game number
+	GameId    string      `json:"game_id,omitempty"`   // unique game identifier
+	Players   []string    `json:"players,omitempty"`   // player commands
+	Player    int         `json:"player,omitempty"`    // 1-based player in game
+	Seed      int64       `json:"seed,omitempty"`      // seed of a dealt initial state
+	Position  string      `json:"position,omitempty"`  // position the game started from (see game.Positioner)
+	Move      string      `json:"move,omitempty"`      // move played
+	Elapsed   float64     `json:"elapsed,omitempty"`   // time taken for move
+	Code      string      `json:"code,omitempty"`      // reason player failed, as a code (e.g. CodeCrash)
+	Reason    string      `json:"reason,omitempty"`    // reason player failed
+	Result    *Result     `json:"result,omitempty"`    // result of finished game
+	Standings interface{} `json:"standings,omitempty"` // current standings
+	Pairings  interface{} `json:"pairings,omitempty"`  // current results of each pair of players
+	Progress  interface{} `json:"progress,omitempty"`  // progress of the tournament
+}
+
+// Event types:
+const (
+	GameStarted      = "game_started"
+	MovePlayed       = "move_played"
+	PlayerFailed     = "player_failed"
+	PlayersSwapped   = "players_swapped"
+	GameFinished     = "game_finished"
+	StandingsUpdated = "standings_updated"
+)
+
+// EventLog writes events to a stream as JSON objects, one per line, and passes
+// them to any handlers added with AddHandler. All methods may be called on a
+// nil *EventLog, which discards events.
+type EventLog struct {
+	out    *eventWriter
+	game   int
+	gameId string
+}
+
+type eventWriter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder // nil if events are only passed to handlers
+	handlers []func(Event)
+}
+
+// NewEventLog returns an event log that writes to w, or only passes events to
+// its handlers if w is nil.
+func NewEventLog(w io.Writer) *EventLog {
+	out := &eventWriter{}
+	if w != nil {
+		out.enc = json.NewEncoder(w)
+	}
+	return &EventLog{out: out}
+}
+
+// AddHandler returns an event log that passes all events to handle, in
+// addition to what el already does with them. Handlers are called one at a
+// time, and must not emit events themselves.
+func (el *EventLog) AddHandler(handle func(Event)) *EventLog {
+	if el == nil {
+		el = NewEventLog(nil)
+	}
+	el.out.mu.Lock()
+	el.out.handlers = append(el.out.handlers, handle)
+	el.out.mu.Unlock()
+	return el
+}
+
+// WithGame returns an event log writing to the same stream, which sets the
+// game number and identifier of all events to game and id.
+func (el *EventLog) WithGame(game int, id string) *EventLog {
+	if el == nil {
+		return nil
+	}
+	return &EventLog{out: el.out, game: game, gameId: id}
+}
+
+// Emit writes an event to the stream, setting its time and game number and
+// identifier.
+func (el *EventLog) Emit(e Event) {
+	if el == nil {
+		return
+	}
+	e.Time = time.Now()
+	if el.game != 0 {
+		e.Game = el.game
+	}
+	if el.gameId != "" {
+		e.GameId = el.gameId
+	}
+	el.out.mu.Lock()
+	if el.out.enc != nil {
+		el.out.enc.Encode(e)
+	}
+	for _, handle := range el.out.handlers {
+		handle(e)
+	}
+	el.out.mu.Unlock()
+}
@@ -0,0 +1,270 @@
+package match
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix of player commands that denote players that serve the Player gRPC
+// service defined in proto/player.proto.
+const grpcPrefix = "grpc://"
+
+// Time allowed for calls that don't have a deadline otherwise.
+const grpcTimeout = 10 * time.Second
+
+// Client for all gRPC players, or the reason why there is none.
+var grpcClient, grpcClientErr = newGRPCClient()
+
+func isGRPC(command string) bool {
+	return strings.HasPrefix(command, grpcPrefix)
+}
+
+// GRPCPlayer is a player that serves the Player gRPC service at the address
+// given by a command like "grpc://host:port", over HTTP/2 without TLS. Calls
+// carry the game id (see Options.GameId), so that the same server can play
+// several games at once.
+//
+// Calls to GetMove are cancelled when the player runs out of time, and have
+// the time left as their deadline if the clock is sent (see Options.SendClock).
+// Other calls must finish within grpcTimeout. All calls must finish within
+// Options.ReadTimeout, if it is set.
+type GRPCPlayer struct {
+	ctx    context.Context
+	cancel context.CancelFunc // called when the player is killed
+	opts   *Options
+	url    string
+	clock  *Clock // time left when the next move is requested, if limited
+	result []byte // EndGame fields describing the result, if known
+}
+
+func newGRPCPlayer(ctx context.Context, opts *Options, command string) *GRPCPlayer {
+	ctx, cancel := context.WithCancel(ctx)
+	return &GRPCPlayer{ctx: ctx, cancel: cancel, opts: opts,
+		url: "http://" + command[len(grpcPrefix):] + "/arbiter.Player/"}
+}
+
+func (gp *GRPCPlayer) NotifyStart(first bool) error {
+	var req []byte
+	req = appendString(req, 1, gp.opts.GameId)
+	req = appendString(req, 2, gp.opts.GameName)
+	req = appendBool(req, 3, first)
+	_, err := gp.call("NewGame", req, grpcTimeout)
+	return err
+}
+
+func (gp *GRPCPlayer) GetMove(history []string) (string, error) {
+	var req []byte
+	req = appendString(req, 1, gp.opts.GameId)
+	for _, move := range history {
+		req = appendBytes(req, 2, move)
+	}
+	var timeout time.Duration // none
+	if gp.clock != nil {
+		timeout = gp.clock.Left[gp.clock.Player]
+	}
+	resp, err := gp.call("GetMove", req, timeout)
+	if err != nil {
+		return "", err
+	}
+	return protoString(resp, 1)
+}
+
+func (gp *GRPCPlayer) NotifyMove(move string) error {
+	var req []byte
+	req = appendString(req, 1, gp.opts.GameId)
+	req = appendString(req, 2, move)
+	_, err := gp.call("NotifyMove", req, grpcTimeout)
+	return err
+}
+
+// SetResult sets the result sent with EndGame.
+func (gp *GRPCPlayer) SetResult(score, opponentScore int, failed bool) {
+	var b []byte
+	b = appendBool(b, 2, true)
+	b = appendInt(b, 3, score)
+	b = appendInt(b, 4, opponentScore)
+	b = appendBool(b, 5, failed)
+	gp.result = b
+}
+
+// Quit ends the game, with its result if it's known (see SetResult). Errors
+// are ignored, since the player may have failed.
+func (gp *GRPCPlayer) Quit() {
+	if gp.ctx.Err() == nil {
+		req := appendString(nil, 1, gp.opts.GameId)
+		gp.call("EndGame", append(req, gp.result...), grpcTimeout)
+	}
+	gp.cancel()
+}
+
+// SetClock sets the deadline of the next call to GetMove.
+func (gp *GRPCPlayer) SetClock(clock Clock) {
+	gp.clock = &clock
+}
+
+// Kill cancels the call in progress, if any, and all further calls.
+func (gp *GRPCPlayer) Kill() {
+	gp.cancel()
+}
+
+// call calls a method of the Player service with a protobuf-encoded request,
+// and returns the protobuf-encoded response. If timeout is positive, the
+// call fails if it doesn't finish within that time.
+func (gp *GRPCPlayer) call(method string, req []byte, timeout time.Duration) ([]byte, error) {
+	if grpcClientErr != nil {
+		return nil, grpcClientErr
+	}
+	readTimeout := gp.opts.ReadTimeout > 0 && (timeout <= 0 || gp.opts.ReadTimeout < timeout)
+	if readTimeout {
+		timeout = gp.opts.ReadTimeout
+	}
+	ctx, cancel := gp.ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	body := make([]byte, 5, 5+len(req))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(req)))
+	body = append(body, req...)
+	hreq, err := http.NewRequestWithContext(ctx, "POST", gp.url+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/grpc")
+	hreq.Header.Set("TE", "trailers")
+	if timeout > 0 {
+		hreq.Header.Set("Grpc-Timeout", grpcTimeoutValue(timeout))
+	}
+	resp, err := grpcClient.Do(hreq)
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+	}
+	if err != nil {
+		if readTimeout && errors.Is(err, context.DeadlineExceeded) {
+			return nil, ErrReadTimeout
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	// Errors are reported in the trailers, or in the headers if there is no
+	// response.
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return nil, fmt.Errorf("%s failed with status %s: %s", method, status, message)
+	}
+	if len(body) < 5 || body[0] != 0 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		return nil, fmt.Errorf("%s: invalid response", method)
+	}
+	return body[5:], nil
+}
+
+// grpcTimeoutValue formats a duration as the value of a grpc-timeout header,
+// which allows at most 8 digits.
+func grpcTimeoutValue(d time.Duration) string {
+	if ms := d.Milliseconds(); ms < 1e8 {
+		return strconv.FormatInt(max(ms, 1), 10) + "m"
+	}
+	return strconv.FormatInt(int64(d.Seconds()), 10) + "S"
+}
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+	wire32Bit  = 5
+)
+
+// appendString appends a string field to a protobuf message, unless it's
+// empty, which is the default.
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, field, s)
+}
+
+// appendBytes appends a string field to a protobuf message, even if it's
+// empty, as elements of repeated fields must be.
+func appendBytes(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireBytes))
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendBool appends a bool field to a protobuf message, unless it's false,
+// which is the default.
+func appendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return append(binary.AppendUvarint(b, uint64(field<<3|wireVarint)), 1)
+}
+
+// appendInt appends an int32 field to a protobuf message, unless it's 0, which
+// is the default. Negative numbers take ten bytes, as in other encoders.
+func appendInt(b []byte, field int, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(b, uint64(int64(int32(v))))
+}
+
+// protoString returns the value of a string field of a protobuf message, or ""
+// if the field is missing.
+func protoString(msg []byte, field int) (string, error) {
+	value := ""
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", errors.New("invalid protobuf message")
+		}
+		msg = msg[n:]
+		switch tag & 7 {
+		case wireVarint:
+			if _, n = binary.Uvarint(msg); n <= 0 {
+				return "", errors.New("invalid protobuf message")
+			}
+		case wire64Bit:
+			n = 8
+		case wire32Bit:
+			n = 4
+		case wireBytes:
+			size, m := binary.Uvarint(msg)
+			if m <= 0 || size > uint64(len(msg)-m) {
+				return "", errors.New("invalid protobuf message")
+			}
+			if int(tag>>3) == field {
+				value = string(msg[m : m+int(size)])
+			}
+			n = m + int(size)
+		default:
+			return "", errors.New("invalid protobuf message")
+		}
+		if n > len(msg) {
+			return "", errors.New("invalid protobuf message")
+		}
+		msg = msg[n:]
+	}
+	return value, nil
+}
@@ -0,0 +1,13 @@
+//go:build go1.24
+
+package match
+
+import "net/http"
+
+// newGRPCClient returns a client that speaks HTTP/2 without TLS, as gRPC
+// servers accept with insecure credentials.
+func newGRPCClient() (*http.Client, error) {
+	t := &http.Transport{Protocols: new(http.Protocols)}
+	t.Protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: t}, nil
+}
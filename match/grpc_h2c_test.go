@@ -0,0 +1,133 @@
+//go:build go1.24
+
+package match
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// grpcCall is a call received by a test server.
+type grpcCall struct {
+	method  string
+	timeout string
+	req     string
+}
+
+// newGRPCServer starts a server for the Player service over HTTP/2 without
+// TLS, which records the calls it receives, and answers each GetMove with
+// move, or fails it with status 13 if move is empty.
+func newGRPCServer(t *testing.T, move string) (*httptest.Server, func() []grpcCall) {
+	var mu sync.Mutex
+	var calls []grpcCall
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("got %s request with content type %q", r.Proto, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Errorf("invalid request frame: % x", body)
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, "/arbiter.Player/")
+		mu.Lock()
+		calls = append(calls, grpcCall{method, r.Header.Get("Grpc-Timeout"), string(body[5:])})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		var resp []byte
+		if method == "GetMove" {
+			if move == "" {
+				w.Header().Set("Grpc-Status", "13")
+				w.Header().Set("Grpc-Message", "no%20moves")
+				return
+			}
+			resp = appendString(nil, 1, move)
+		}
+		frame := make([]byte, 5, 5+len(resp))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		w.Write(append(frame, resp...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts, func() []grpcCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestGRPCPlayer(t *testing.T) {
+	ts, calls := newGRPCServer(t, "e4")
+	opts := &Options{GameId: "g1", GameName: "hex"}
+	gp := newGRPCPlayer(context.Background(), opts, "grpc://"+ts.Listener.Addr().String())
+
+	if err := gp.NotifyStart(true); err != nil {
+		t.Fatal("NotifyStart:", err)
+	}
+	gp.SetClock(Clock{Player: 0, Left: [2]time.Duration{2 * time.Second, time.Second}})
+	move, err := gp.GetMove([]string{"a1", ""})
+	if err != nil || move != "e4" {
+		t.Fatalf("GetMove returned %q, %v; want %q", move, err, "e4")
+	}
+	if err := gp.NotifyMove("b2"); err != nil {
+		t.Fatal("NotifyMove:", err)
+	}
+	gp.SetResult(1, -1, false)
+	gp.Quit()
+
+	var id []byte
+	id = appendString(id, 1, "g1")
+	cat := func(parts ...[]byte) string {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return string(b)
+	}
+	want := []grpcCall{
+		{"NewGame", "10000m", cat(id, appendString(nil, 2, "hex"), appendBool(nil, 3, true))},
+		{"GetMove", "2000m", cat(id, appendBytes(nil, 2, "a1"), appendBytes(nil, 2, ""))},
+		{"NotifyMove", "10000m", cat(id, appendString(nil, 2, "b2"))},
+		{"EndGame", "10000m", cat(id, appendBool(nil, 2, true), appendInt(nil, 3, 1), appendInt(nil, 4, -1))},
+	}
+	if got := calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestGRPCPlayerErrors(t *testing.T) {
+	ts, calls := newGRPCServer(t, "")
+	opts := &Options{GameId: "g2", ReadTimeout: time.Second}
+	gp := newGRPCPlayer(context.Background(), opts, "grpc://"+ts.Listener.Addr().String())
+
+	_, err := gp.GetMove(nil)
+	if err == nil || !strings.Contains(err.Error(), "status 13: no moves") {
+		t.Errorf("GetMove returned error %v, want status 13", err)
+	}
+	if got := calls(); len(got) != 1 || got[0].timeout != "1000m" {
+		t.Errorf("got calls %q, want one GetMove with the read timeout", got)
+	}
+
+	// Killed players aren't called anymore, not even to end the game.
+	gp.Kill()
+	if _, err := gp.GetMove(nil); err == nil {
+		t.Error("GetMove succeeded after Kill")
+	}
+	gp.Quit()
+	if got := calls(); len(got) != 1 {
+		t.Errorf("got %d calls after Kill, want none", len(got)-1)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !go1.24
+
+package match
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Before Go 1.24, the standard library's HTTP client only speaks HTTP/2 over
+// TLS.
+func newGRPCClient() (*http.Client, error) {
+	return nil, errors.New("gRPC players require the arbiter to be built with Go 1.24 or later")
+}
@@ -0,0 +1,82 @@
+package match
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestProtoAppend(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"string", appendString(nil, 1, "ab"), []byte{0x0a, 0x02, 'a', 'b'}},
+		{"empty string", appendString(nil, 1, ""), nil},
+		{"empty bytes", appendBytes(nil, 2, ""), []byte{0x12, 0x00}},
+		{"long bytes", appendBytes(nil, 2, string(make([]byte, 200))), append([]byte{0x12, 0xc8, 0x01}, make([]byte, 200)...)},
+		{"high field", appendString(nil, 16, "x"), []byte{0x82, 0x01, 0x01, 'x'}},
+		{"true", appendBool(nil, 3, true), []byte{0x18, 0x01}},
+		{"false", appendBool(nil, 3, false), nil},
+		{"int", appendInt(nil, 4, 300), []byte{0x20, 0xac, 0x02}},
+		{"zero", appendInt(nil, 4, 0), nil},
+		{"negative int", appendInt(nil, 4, -1), []byte{0x20, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"appended", appendBool(appendString(nil, 1, "g"), 3, true), []byte{0x0a, 0x01, 'g', 0x18, 0x01}},
+	}
+	for _, tt := range tests {
+		if !bytes.Equal(tt.got, tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestProtoString(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+		want string
+		err  bool
+	}{
+		{"empty message", nil, "", false},
+		{"field", []byte{0x0a, 0x02, 'e', '4'}, "e4", false},
+		{"other fields first", []byte{
+			0x10, 0xac, 0x02, // varint
+			0x19, 1, 2, 3, 4, 5, 6, 7, 8, // 64-bit
+			0x25, 1, 2, 3, 4, // 32-bit
+			0x12, 0x01, 'x', // other string
+			0x0a, 0x01, 'y'}, "y", false},
+		{"last one wins", []byte{0x0a, 0x01, 'a', 0x0a, 0x01, 'b'}, "b", false},
+		{"missing field", []byte{0x12, 0x01, 'x'}, "", false},
+		{"round trip", appendBytes(nil, 1, "pass"), "pass", false},
+		{"truncated string", []byte{0x0a, 0x05, 'a'}, "", true},
+		{"truncated 64-bit", []byte{0x19, 1, 2}, "", true},
+		{"truncated varint", []byte{0x10, 0x80}, "", true},
+		{"truncated tag", []byte{0x80}, "", true},
+		{"group", []byte{0x0b}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := protoString(tt.msg, 1)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("%s: got %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestGRPCTimeoutValue(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{1500 * time.Millisecond, "1500m"},
+		{time.Microsecond, "1m"},
+		{time.Minute, "60000m"},
+		{99999999 * time.Millisecond, "99999999m"},
+		{30 * time.Hour, "108000S"},
+	}
+	for _, tt := range tests {
+		if got := grpcTimeoutValue(tt.d); got != tt.want {
+			t.Errorf("grpcTimeoutValue(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
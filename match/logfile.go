@@ -0,0 +1,119 @@
+package match
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Replay plays the moves of a game log from the initial state of the game,
+// and returns the resulting state. If a move cannot be parsed or is invalid,
+// or the game ends before all moves are played, an error is returned instead
+// together with the state before the offending move.
+func Replay(g game.Game, moves []string) (game.GameState, error) {
+	state := g.CreateState()
+	var pending []interface{} // first player's move in a simultaneous turn
+	for i, s := range moves {
+		if state.Over() {
+			return state, fmt.Errorf("move %d (%s): game already over", i+1, s)
+		}
+		move, ok := g.ParseMove(s)
+		if !ok {
+			return state, fmt.Errorf("move %d (%s): unparseable move", i+1, s)
+		}
+		if ss, ok := state.(game.SimultaneousState); ok && ss.Simultaneous() {
+			// Both players' moves are recorded, the first player's first.
+			if pending = append(pending, move); len(pending) < 2 {
+				continue
+			}
+			valid := ss.ExecuteBoth([2]interface{}{pending[0], pending[1]})
+			pending = nil
+			if !valid[0] {
+				return state, fmt.Errorf("move %d (%s): invalid move", i, moves[i-1])
+			}
+			if !valid[1] {
+				return state, fmt.Errorf("move %d (%s): invalid move", i+1, s)
+			}
+		} else if !state.Execute(move) {
+			return state, fmt.Errorf("move %d (%s): invalid move", i+1, s)
+		}
+	}
+	if pending != nil {
+		return state, fmt.Errorf("move %d (%s): second player's move missing", len(moves), moves[len(moves)-1])
+	}
+	return state, nil
+}
+
+// Branch returns a copy of state, which was reached in a game of g by playing
+// the given moves, that can be changed without affecting state. States that
+// implement game.Cloner are copied directly; other states are created again
+// by replaying the moves.
+func Branch(g game.Game, state game.GameState, history []string) (game.GameState, error) {
+	if c, ok := state.(game.Cloner); ok {
+		return c.Clone(), nil
+	}
+	return Replay(g, history)
+}
+
+// Verify replays a game log and checks that all moves are valid and, where
+// the score is determined by the game rules, that the recorded score matches
+// the final position.
+func Verify(g game.Game, gl *gamelog.GameLog) error {
+	state, err := Replay(g, gl.Moves)
+	if err != nil {
+		return err
+	}
+	if !gl.HasScore {
+		return fmt.Errorf("score missing from log")
+	}
+	var score [2]int
+	switch {
+	case gl.Resigned[0] || gl.Resigned[1]:
+		for i := range score {
+			if !gl.Resigned[i] {
+				score[i] = 1
+			}
+		}
+	case gl.DrawAgreed:
+	case gl.Forfeited:
+		for i := range score {
+			if !gl.Failed[i] {
+				score[i] = 1
+			}
+		}
+	case gl.Solved:
+		s, ok := state.(game.Solver)
+		if !ok {
+			return fmt.Errorf("game can't be solved")
+		}
+		if score[0], score[1], ok = s.Solve(); !ok {
+			return fmt.Errorf("game not solvable after %d moves", len(gl.Moves))
+		}
+	case gl.Adjudicated:
+		// The adjudication method is not recorded, so the score can't be
+		// checked.
+		return nil
+	default:
+		if !state.Over() && !gl.Interrupted && !gl.NotPlayed {
+			return fmt.Errorf("game not over after %d moves", len(gl.Moves))
+		}
+		score[0], score[1] = state.Scores()
+	}
+	if score != gl.Score {
+		return fmt.Errorf("score mismatch: log says %d - %d, replay gives %d - %d",
+			gl.Score[0], gl.Score[1], score[0], score[1])
+	}
+	return nil
+}
+
+// writeBoard writes a drawing of the board (see game.RenderBoard) to a game
+// log under the given title, as comment lines that are ignored when reading
+// the log.
+func writeBoard(w io.Writer, title, board string) {
+	fmt.Fprintf(w, "# %s\n", title)
+	for _, line := range strings.Split(strings.TrimRight(board, "\n"), "\n") {
+		fmt.Fprintf(w, "#   %s\n", line)
+	}
+}
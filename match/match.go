@@ -0,0 +1,1282 @@
+// Package match runs single games between two players, which are either
+// external programs or players built into the arbiter.
+package match
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options controls how matches are played.
+type Options struct {
+	Game     game.Game
+	GameName string // game as given to game.Lookup, recorded in game logs
+	Protocol Protocol
+
+	MaxRestarts  int    // number of times a crashed player may be restarted per game
+	MaxMoves     int    // maximum number of moves per game, or 0 for no limit
+	Adjudication string // adjudication method for games reaching MaxMoves
+	Swap         bool   // whether the second player may swap sides after the first move
+	Solve        bool   // whether to end games once their outcome is determined (see game.Solver)
+
+	// What happens when a player fails during a game: with "random-moves"
+	// (or ""), the arbiter plays random moves for it until the game is over.
+	// With "immediate-forfeit", the game ends, and the players that failed
+	// lose. With "adjudicate-current-position", the game ends, and is
+	// adjudicated like games reaching MaxMoves.
+	FailurePolicy string
+
+	// What happens when a player makes an illegal or unparseable move: with
+	// "fail" (or ""), the player fails. With "retry", the player is asked
+	// for another move once, and fails if that move is illegal too. With
+	// "warn", the first illegal move of each player in a game is only a
+	// warning, after which the player is asked for another move. Rejected
+	// moves are recorded in the game log. This only applies to players that
+	// implement MoveRetrier, in turns where only one player moves.
+	IllegalMoves string
+
+	// Whether to not play games in which a player fails to start, instead of
+	// playing random moves for it. The result then has NotPlayed set.
+	SkipUnstarted bool
+
+	// Whether to prefix each line in the player message logs with the time it
+	// was written.
+	MsgTimestamps bool
+
+	// Path of a log of everything that happens during the game (see
+	// debugLog), if not empty.
+	DebugPath string
+
+	// Whether to ignore empty lines written by players, instead of treating
+	// them as invalid moves.
+	SkipBlankLines bool
+
+	// Time limit for reading a line from a player, or 0 for no limit. Players
+	// that exceed it are considered hung, and fail.
+	ReadTimeout time.Duration
+
+	// Number of moves at the start of each game that are recorded as the
+	// opening in the result.
+	OpeningLength int
+
+	// Number of moves after which the board is drawn in the game log (see
+	// game.RenderBoard), so that humans can follow the game without a replay
+	// tool, or 0 to not draw it. The final board is drawn too.
+	LogBoards int
+
+	// Time limit for turns in which both players move at once (see
+	// game.SimultaneousState), or 0 for no limit.
+	TurnTime time.Duration
+
+	// Total time each player may use per game, or 0 for no limit. Engines
+	// may have their own limit (see Engine.TimeLimit). Players that exceed
+	// it fail.
+	TimeLimit time.Duration
+
+	// Time added to the clock of a player with a time limit after each of
+	// its moves, for Fischer time controls.
+	Increment time.Duration
+
+	// Number and length of the byo-yomi periods that players get after
+	// their main time (see TimeLimit) runs out. A move that takes longer than
+	// the main time left uses up a period for every full period it takes
+	// beyond it. Players that use up their last period fail.
+	ByoYomiPeriods int
+	ByoYomiTime    time.Duration
+
+	// Whether to tell players with a time limit how much time both players
+	// have left before each move (see ClockReceiver).
+	SendClock bool
+
+	// User name or id to run player processes as, or "" to run them as the
+	// arbiter's user, for engines that don't give their own (see
+	// Engine.User). Running players as an unprivileged user keeps them
+	// from reading the arbiter's files, and running each engine as its own
+	// user keeps them from reading each other's. This requires the arbiter
+	// to run as root, and is only supported on Unix. Players run in
+	// containers are run as the user inside the container, while the
+	// runtime's client runs as the arbiter's user.
+	User string
+
+	// CPU cores to pin the processes of the first and second player to, as
+	// lists like "0-3,6", or "" to not pin them. Pinning is only supported on
+	// Linux, and in containers.
+	Affinity [2]string
+
+	Container ContainerOptions
+	Cgroup    CgroupOptions
+	Analysis  AnalysisOptions
+
+	Engines map[string]*Engine // engine definitions, by name
+
+	// Variables to expand in each player's command and arguments (see
+	// ExpandVars), e.g. {"game": "1", "color": "first"}.
+	Vars [2]map[string]string
+
+	GameId string    // unique identifier of the game, recorded in logs and results
+	Events *EventLog // receives events for each game, if not nil
+
+	// 1-based number of the game in a series of games, such as a tournament,
+	// and the number of games in the series, or 0 if unknown.
+	GameNumber int
+	Games      int
+
+	// Whether to tell players the game number, the number of games (if
+	// known), and which player they are at the start of each game, as the
+	// settings "game", "games" and "player".
+	AnnounceGames bool
+
+	// Keeps player processes alive between games, if not nil.
+	Sessions *SessionPool
+	Seed     int64 // random seed of the run, recorded in game logs
+
+	// Seed of the game, recorded in game logs. It deals the initial state of
+	// games implementing game.Dealer, and seeds the random choices of the
+	// arbiter and built-in players during the game, so that a game can be
+	// reproduced with the same seed.
+	GameSeed int64
+
+	// State of an interrupted game to continue, or nil to start a new game.
+	// Players are told the moves so far instead of being started normally
+	// (see Resumer), and the time taken for each move is charged to their
+	// clocks again.
+	Resume *Resumption
+}
+
+// Resumption is the state of an interrupted game, as recorded in its game log.
+type Resumption struct {
+	Moves   []string  // moves played so far
+	Movers  []int     // 0-based player that made each move
+	Times   []float64 // time taken for each move in seconds
+	Swapped bool      // whether the players swapped sides after the first move
+}
+
+// Result is the outcome of a single game.
+type Result struct {
+	GameId   string     `json:"game_id,omitempty"`   // unique game identifier
+	Player   [2]int     `json:"player"`              // 0-based player indices
+	Score    [2]int     `json:"score"`               // final score
+	Failed   [2]bool    `json:"failed"`              // whether player failed
+	TimedOut [2]bool    `json:"timed_out,omitempty"` // whether player failed by exceeding a time limit
+	Code     [2]string  `json:"code,omitempty"`      // why player failed or resigned (e.g. CodeCrash)
+	Reason   [2]string  `json:"reason,omitempty"`    // why player failed, e.g. "invalid move: a1"
+	Points   [2]int     `json:"points"`              // CodeCup-style points
+	Time     [2]float64 `json:"time"`                // total time taken
+	CPUTime  [2]float64 `json:"cpu_time"`            // CPU time used by player processes (0 for builtin and remote players)
+	Memory   [2]int64   `json:"memory,omitempty"`    // peak memory usage in bytes (if known)
+	Restarts [2]int     `json:"restarts,omitempty"`  // number of times player was restarted
+	Moves    int        `json:"moves"`               // number of moves played
+	Duration float64    `json:"duration"`            // wall-clock time of the game in seconds
+
+	Interrupted bool    `json:"interrupted,omitempty"` // game was cancelled before it finished
+	Adjudicated bool    `json:"adjudicated,omitempty"` // game was adjudicated after reaching the move limit
+	Solved      bool    `json:"solved,omitempty"`      // game ended early because its outcome was determined (see Options.Solve)
+	Resigned    [2]bool `json:"resigned,omitempty"`    // whether player resigned
+	DrawAgreed  bool    `json:"draw_agreed,omitempty"` // game ended in a draw by agreement
+	Forfeited   bool    `json:"forfeited,omitempty"`   // game ended when a player failed (see Options.FailurePolicy)
+	Rejected    [2]int  `json:"rejected,omitempty"`    // number of illegal moves player was allowed to retry (see Options.IllegalMoves)
+	NotPlayed   bool    `json:"not_played,omitempty"`  // game wasn't played because a player failed to start (see Options.SkipUnstarted)
+	Swapped     bool    `json:"swapped,omitempty"`     // players swapped sides after the first move
+
+	MoveTime        [2][]float64 `json:"move_time,omitempty"`        // time taken for each move by player
+	MoveHash        string       `json:"move_hash,omitempty"`        // hash of the moves played (see HashMoves)
+	Seed            int64        `json:"seed"`                       // seed of the game (see Options.GameSeed)
+	Opening         []string     `json:"opening,omitempty"`          // first moves played (see Options.OpeningLength)
+	Position        string       `json:"position,omitempty"`         // position the game started from, if not the usual one (see game.Positioner)
+	OpeningPosition string       `json:"opening_position,omitempty"` // position after the opening, up to symmetry (see game.CanonicalPosition)
+	Blunders        []Blunder    `json:"blunders,omitempty"`         // blunders found by analysis (see Options.Analysis)
+	Exit            [2]string    `json:"exit,omitempty"`             // how player processes exited, e.g. "signal: killed"
+
+	TimeLimit [2]float64 `json:"time_limit,omitempty"` // total time player could use in seconds, or 0 if unlimited
+	Periods   [2]int     `json:"periods,omitempty"`    // number of byo-yomi periods player used up
+}
+
+// Codes that classify why a player failed or resigned (see Result.Code).
+const (
+	CodeStartFailure      = "start_failure"      // the player couldn't be started
+	CodeCrash             = "crash"              // communication failed, e.g. because the player exited
+	CodeTimeout           = "timeout"            // the player exceeded a time limit
+	CodeIllegalMove       = "illegal_move"       // the player made a move that isn't allowed
+	CodeUnparseableMove   = "unparseable_move"   // the player wrote something that isn't a move
+	CodeProtocolViolation = "protocol_violation" // the player wrote output it wasn't asked for
+	CodeResigned          = "resigned"           // the player resigned (which is not a failure)
+)
+
+// HashMoves returns a hash of a sequence of moves, which identifies games
+// that were played the same way. If the game was dealt, the seed is included,
+// since the same moves from a different initial state make a different game.
+func HashMoves(moves []string, dealt bool, seed int64) string {
+	h := sha256.New()
+	if dealt {
+		fmt.Fprintf(h, "deal %d\n", seed)
+	}
+	for _, move := range moves {
+		fmt.Fprintln(h, move)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// runPlayer starts an engine, and returns its process (nil for remote
+// players) and the pipes connected to it. The messages that it writes to
+// stderr are written to the file named by msgPath, or to stderr if the path is
+// "-", and to the debug log, if trace is not nil. If cpus is not empty, the
+// process is pinned to those CPU cores (see Options.Affinity).
+func runPlayer(ctx context.Context, opts *Options, engine *Engine, vars map[string]string, cpus string, msgPath string, trace *debugTrace) (*Process, io.WriteCloser, io.ReadCloser, error) {
+	if isRemote(engine.Command) {
+		conn, err := connectRemote(ctx, engine.Command)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return nil, conn, conn, nil
+	}
+	argv, err := splitCommand(engine.Command)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	argv = append(argv, engine.Args...)
+	for i := range argv {
+		argv[i] = ExpandVars(argv[i], vars)
+	}
+	if len(argv) == 0 {
+		return nil, nil, nil, os.ErrInvalid
+	}
+	if strings.Contains(argv[0], "/") {
+		// Make the path independent of the working directory.
+		if argv[0], err = filepath.Abs(argv[0]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	dir, err := engine.workDir(argv[0], vars)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	co := opts.container(engine)
+	containerName := ""
+	user := opts.user(engine)
+	if co.Image != "" {
+		// The runtime's client runs as the arbiter's user, and the player
+		// as the given user inside the container.
+		ids := ""
+		if user != "" {
+			if ids, err = containerUser(user); err != nil {
+				return nil, nil, nil, err
+			}
+			user = ""
+		}
+		containerName = newContainerName()
+		argv = co.containerize(argv, dir, engine.Env, cpus, ids, containerName)
+	}
+	if name, err := exec.LookPath(argv[0]); err != nil {
+		return nil, nil, nil, err
+	} else {
+		cmd := exec.Cmd{Path: name, Args: argv, Dir: dir}
+		if engine.ClearEnv && co.Image == "" {
+			cmd.Env = append([]string{}, engine.Env...)
+		} else if len(engine.Env) > 0 && co.Image == "" {
+			cmd.Env = append(os.Environ(), engine.Env...)
+		}
+		if stdin, err := cmd.StdinPipe(); err != nil {
+			return nil, nil, nil, err
+		} else if stdout, err := cmd.StdoutPipe(); err != nil {
+			return nil, nil, nil, err
+		} else {
+			proc := &Process{cmd: &cmd, stdout: stdout, user: user,
+				runtime: co.Runtime, container: containerName}
+			if cpus != "" && co.Image == "" {
+				if proc.cpus, err = ParseCPUList(cpus); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			stderr, closers := openStderr(opts, msgPath, trace)
+			if opts.Sessions != nil {
+				// The process may play several games, whose messages are
+				// written to different logs.
+				proc.stderr = &switchWriter{w: stderr}
+				stderr = proc.stderr
+			}
+			if stderr != nil {
+				cmd.Stderr = stderr
+				if _, ok := stderr.(*os.File); !ok {
+					// Output is copied by a goroutine, which must not
+					// wait for processes the player left behind.
+					cmd.WaitDelay = time.Second
+				}
+			}
+			if len(closers) > 0 {
+				proc.msgLog = closers
+			}
+			if opts.Cgroup.Parent != "" {
+				if proc.cgroup, err = createCgroup(&opts.Cgroup); err != nil {
+					proc.closeMsgLog()
+					return nil, nil, nil, err
+				}
+			}
+			if err := proc.Start(ctx); err != nil {
+				if proc.cgroup != nil {
+					proc.cgroup.remove()
+				}
+				proc.closeMsgLog()
+				return nil, nil, nil, err
+			}
+			if co.Image == "" {
+				if err := setPriority(cmd.Process.Pid, engine.Nice, engine.IONice); err != nil {
+					proc.Kill()
+					proc.Wait()
+					return nil, nil, nil, err
+				}
+			}
+			return proc, stdin, stdout, nil
+		}
+	}
+}
+
+// openStderr returns the writer that a player's messages to stderr are
+// written to (see runPlayer), or nil if they are discarded, and the files to
+// close when the player exits.
+func openStderr(opts *Options, msgPath string, trace *debugTrace) (io.Writer, multiCloser) {
+	var stderr io.Writer
+	var closers multiCloser
+	if msgPath == "-" {
+		stderr = os.Stderr
+	} else if msgPath != "" {
+		if w, err := gamelog.CreateLog(msgPath); err != nil {
+			// Connect to stderr instead
+			slog.Error("couldn't create message log", "error", err)
+			stderr = os.Stderr
+		} else {
+			if opts.GameId != "" {
+				fmt.Fprintf(w, "# Game: %s\n", opts.GameId)
+			}
+			if opts.MsgTimestamps {
+				w = timestamped(w)
+			}
+			stderr = w
+			closers = append(closers, w)
+		}
+	}
+	if trace != nil {
+		w := trace.stderr()
+		closers = append(closers, w)
+		if stderr != nil {
+			stderr = io.MultiWriter(stderr, w)
+		} else {
+			stderr = w
+		}
+	}
+	return stderr, closers
+}
+
+// Run plays a game between two players, given by their indices in the
+// tournament and their commands. If logPath is not empty, a log of the game is
+// written to it. Messages written by the players to stderr are written to the
+// files named by msgPath, or to stderr if the path is "-".
+//
+// If ctx is done before the game is over, the players are killed and the
+// partial result is returned with Interrupted set.
+//
+// If opts.Swap is set and the second player answers the first move with
+// "swap", the players change sides: the first move is then considered to have
+// been played by the second player, and the first player continues as the
+// second player. All per-player fields of the result, and the log, refer to
+// the sides as they were after the swap, with Swapped set.
+//
+// If the game implements game.Dealer, its initial state is dealt using
+// opts.GameSeed, and each player is told its setup along with the settings of
+// the game. The random number generator of the arbiter is reset with
+// opts.GameSeed too (see Seed).
+func Run(ctx context.Context, opts *Options, players [2]int, commands [2]string, logPath string, msgPath [2]string) Result {
+	result := Result{GameId: opts.GameId, Player: players, Seed: opts.GameSeed}
+	started := time.Now()
+
+	var clients [2]Player
+
+	log := slog.Default()
+	if opts.GameId != "" {
+		log = log.With("game", opts.GameId)
+	}
+
+	Seed(opts.GameSeed)
+	_, dealt := opts.Game.(game.Dealer)
+	if dealt {
+		dealtOpts := *opts
+		dealtOpts.Game = game.WithDeal(opts.Game, opts.GameSeed)
+		opts = &dealtOpts
+		opts.Events.Emit(Event{Type: GameStarted, Players: commands[:], Seed: opts.GameSeed})
+	} else {
+		opts.Events.Emit(Event{Type: GameStarted, Players: commands[:],
+			Position: game.Position(opts.Game, opts.Game.CreateState())})
+	}
+	log.Info("game started", "player1", commands[0], "player2", commands[1])
+
+	// Marks a player as failed, and kills it (if possible):
+	fail := func(i int, code, reason string) {
+		opts.Events.Emit(Event{Type: PlayerFailed, Player: i + 1, Code: code, Reason: reason})
+		result.Failed[i] = true
+		if result.Reason[i] == "" {
+			result.Code[i] = code
+			result.Reason[i] = reason
+		}
+		if k, ok := clients[i].(Killer); ok {
+			k.Kill()
+		}
+	}
+
+	var debug *debugLog
+	if opts.DebugPath != "" {
+		var err error
+		if debug, err = createDebugLog(opts.DebugPath, opts.GameId); err != nil {
+			log.Error("couldn't create debug log", "error", err)
+		} else {
+			defer debug.Close()
+		}
+	}
+
+	for i := range players {
+		var trace *debugTrace
+		if debug != nil {
+			trace = &debugTrace{debug, i}
+		}
+		if client, err := newPlayer(ctx, opts, commands[i], opts.Vars[i], opts.Affinity[i], msgPath[i], trace); err != nil {
+			log.Warn("couldn't run player", "player", commands[i], "error", err)
+			fail(i, CodeStartFailure, "couldn't run: "+err.Error())
+		} else {
+			clients[i] = client
+			if opts.Resume != nil {
+				continue // the player is told the moves so far below
+			}
+			err := client.NotifyStart(i == 0)
+			if pp, ok := client.(*ProcessPlayer); ok && err != nil && pp.reused {
+				// The process kept from an earlier game may have exited.
+				log.Info("starting new player process", "player", commands[i], "error", err)
+				err = pp.Restart(nil, nil)
+			}
+			if err != nil {
+				log.Warn("couldn't start player", "player", commands[i], "error", err)
+				fail(i, CodeStartFailure, "couldn't start: "+err.Error())
+			}
+		}
+	}
+
+	if opts.SkipUnstarted && (result.Code[0] == CodeStartFailure || result.Code[1] == CodeStartFailure) {
+		log.Warn("game not played")
+		result.NotPlayed = true
+	}
+
+	var gamestate game.GameState = opts.Game.CreateState()
+	startPosition := game.Position(opts.Game, gamestate)
+	var history []string
+	var movers []int       // player that made each move
+	var times []float64    // time taken for each move
+	var positions []string // position after each move (see game.Positioner)
+	var boards []string    // drawing of the board after each move, if due (see Options.LogBoards)
+	drawOffered := false   // whether the player to move offered a draw already
+	retried := false       // whether the player to move made an illegal move already
+
+	// Returns the drawing of the board of state after n moves for the game
+	// log, or "" if it's not due (see Options.LogBoards).
+	boardAfter := func(n int, state game.GameState) string {
+		if opts.LogBoards <= 0 || n%opts.LogBoards != 0 {
+			return ""
+		}
+		var board strings.Builder
+		if !game.RenderBoard(&board, state) {
+			return ""
+		}
+		return board.String()
+	}
+
+	// Illegal moves that players were allowed to retry:
+	type rejection struct {
+		move, player int // 1-based number of the move, and 0-based player
+		text, code   string
+	}
+	var rejections []rejection
+
+	// Total time each player may use, or 0 if unlimited, and their clocks:
+	var limits [2]time.Duration
+	var clocks [2]gameClock
+	for i := range limits {
+		limits[i] = opts.timeLimit(commands[i])
+		clocks[i] = newGameClock(limits[i], opts)
+		result.TimeLimit[i] = limits[i].Seconds()
+	}
+
+	// Adds the time player i took for a move to its total, and charges it to
+	// its clock.
+	useTime := func(i int, elapsed float64) {
+		result.Time[i] += elapsed
+		clocks[i].use(time.Duration(elapsed * float64(time.Second)))
+	}
+
+	// Returns how much time player i may use for its next move, and whether
+	// it has a limit.
+	timeLeft := func(i int) (time.Duration, bool) {
+		return clocks[i].left(), clocks[i].limited
+	}
+
+	// Tells player i the time left on both clocks before it's asked for a
+	// move, if opts.SendClock is set and it supports it.
+	sendClock := func(i int) {
+		cr, ok := clients[i].(ClockReceiver)
+		if _, limited := timeLeft(i); !opts.SendClock || !limited || !ok {
+			return
+		}
+		clock := Clock{Player: i}
+		for j := range clock.Left {
+			clock.Left[j] = clocks[j].left()
+			clock.Increment[j] = clocks[j].increment
+		}
+		cr.SetClock(clock)
+	}
+
+	// Returns whether player i has used up its time.
+	outOfTime := func(i int) bool {
+		return clocks[i].flagged
+	}
+
+	// Kills player i when it runs out of time, unless the returned function
+	// is called first. The function waits for the player to be killed if
+	// that has started already.
+	watchTime := func(i int) (stop func()) {
+		left, limited := timeLeft(i)
+		k, ok := clients[i].(Killer)
+		if !limited || !ok {
+			return func() {}
+		}
+		killed := make(chan struct{})
+		timer := time.AfterFunc(left, func() {
+			k.Kill()
+			close(killed)
+		})
+		return func() {
+			if !timer.Stop() {
+				<-killed
+			}
+		}
+	}
+
+	// In games with hidden information, players that support it are sent
+	// their own view of the game instead of the moves so far, and are not told
+	// their opponent's moves.
+	viewer, _ := opts.Game.(game.Viewer)
+	seesView := func(i int) bool {
+		_, ok := clients[i].(ViewPlayer)
+		return viewer != nil && ok
+	}
+
+	// Asks player i for its next move. view is the player's view of the game,
+	// which is only used if seesView(i).
+	getMove := func(i int, view string) (string, error) {
+		if seesView(i) {
+			return clients[i].(ViewPlayer).GetMoveView(view)
+		}
+		return clients[i].GetMove(history)
+	}
+
+	// Returns player i's view of the current game state, if it sees one.
+	playerView := func(i int) string {
+		if !seesView(i) {
+			return ""
+		}
+		return viewer.PlayerView(gamestate, i)
+	}
+
+	// Offers a draw to the given player. Returns whether it was accepted.
+	offerDraw := func(i int) bool {
+		dn, ok := clients[i].(DrawNegotiator)
+		if result.Failed[i] || !ok {
+			return false
+		}
+		accepted, err := dn.OfferDraw()
+		if err != nil {
+			log.Warn("draw offer failed", "player", commands[i], "error", err)
+			fail(i, CodeCrash, "draw offer failed: "+err.Error())
+			return false
+		}
+		return accepted
+	}
+
+	// Returns the moves so far, as player i must be told them to bring it up
+	// to date with the game, and for each move, whether it was the player's.
+	replayFor := func(i int) ([]string, []bool) {
+		replay, own := history, make([]bool, len(movers))
+		for j, mover := range movers {
+			own[j] = mover == i
+		}
+		if result.Swapped {
+			// The player now moving first sent the swap after the first move.
+			replay = append([]string{history[0], swapToken}, history[1:]...)
+			own = append([]bool{own[0], i == 0}, own[1:]...)
+		}
+		return replay, own
+	}
+
+	// Restarts a crashed player and replays the game so far, if the restart
+	// budget allows it. Returns whether the player was restarted successfully.
+	// A process kept from an earlier game may always be restarted once,
+	// since a new process may work where the old one didn't.
+	restart := func(i int) bool {
+		r, ok := clients[i].(Restarter)
+		budget := opts.MaxRestarts
+		if pp, isProcess := clients[i].(*ProcessPlayer); isProcess && pp.reused {
+			budget++
+		}
+		if !ok || result.Restarts[i] >= budget || ctx.Err() != nil {
+			return false
+		}
+		result.Restarts[i]++
+		replay, own := replayFor(i)
+		if err := r.Restart(replay, own); err != nil {
+			log.Warn("couldn't restart player", "player", commands[i], "error", err)
+			return false
+		}
+		log.Info("player restarted", "player", commands[i], "moves", len(history))
+		return true
+	}
+
+	// Handles an error telling a player its opponent's move: the player fails
+	// if it broke the protocol, or if it crashed and can't be restarted.
+	notifyFailed := func(i int, err error) {
+		var pv *ProtocolViolation
+		if errors.As(err, &pv) {
+			log.Warn("protocol violation", "player", commands[i], "output", pv.Output)
+			fail(i, CodeProtocolViolation, "protocol violation: "+pv.Error())
+		} else {
+			log.Warn("couldn't write to player", "player", commands[i], "error", err)
+			if !restart(i) {
+				fail(i, CodeCrash, "write failed: "+err.Error())
+			}
+		}
+	}
+
+	// Exchanges the sides of the players after the second player swapped.
+	swapSides := func() {
+		result.Swapped = true
+		clients[0], clients[1] = clients[1], clients[0]
+		commands[0], commands[1] = commands[1], commands[0]
+		result.Player[0], result.Player[1] = result.Player[1], result.Player[0]
+		result.Failed[0], result.Failed[1] = result.Failed[1], result.Failed[0]
+		result.TimedOut[0], result.TimedOut[1] = result.TimedOut[1], result.TimedOut[0]
+		result.Code[0], result.Code[1] = result.Code[1], result.Code[0]
+		result.Reason[0], result.Reason[1] = result.Reason[1], result.Reason[0]
+		result.Time[0], result.Time[1] = result.Time[1], result.Time[0]
+		result.Restarts[0], result.Restarts[1] = result.Restarts[1], result.Restarts[0]
+		result.TimeLimit[0], result.TimeLimit[1] = result.TimeLimit[1], result.TimeLimit[0]
+		result.Rejected[0], result.Rejected[1] = result.Rejected[1], result.Rejected[0]
+		limits[0], limits[1] = limits[1], limits[0]
+		clocks[0], clocks[1] = clocks[1], clocks[0]
+		for j := range movers {
+			movers[j] = 1 - movers[j]
+		}
+		for j := range rejections {
+			rejections[j].player = 1 - rejections[j].player
+		}
+	}
+
+	// Exchanges the sides of the players after the second player swapped, and
+	// tells the first player, who now moves second.
+	swap := func() {
+		swapSides()
+		opts.Events.Emit(Event{Type: PlayersSwapped, Players: commands[:]})
+		if !result.Failed[1] {
+			if err := clients[1].NotifyMove(swapToken); err != nil {
+				notifyFailed(1, err)
+			}
+		}
+	}
+
+	// Returns whether player p may make another move after making an illegal
+	// move (see Options.IllegalMoves), and if so, tells it so.
+	retry := func(p int, line, code string) bool {
+		r, ok := clients[p].(MoveRetrier)
+		switch {
+		case !ok:
+			return false
+		case opts.IllegalMoves == "retry" && !retried:
+		case opts.IllegalMoves == "warn" && result.Rejected[p] == 0:
+		default:
+			return false
+		}
+		if err := r.MoveRejected(line); err != nil {
+			log.Warn("couldn't write to player", "player", commands[p], "error", err)
+			return false
+		}
+		retried = true
+		result.Rejected[p]++
+		rejections = append(rejections, rejection{len(history) + 1, p, line, code})
+		return true
+	}
+
+	// Whether the game ends when a player fails (see Options.FailurePolicy):
+	stopOnFailure := opts.FailurePolicy != "" && opts.FailurePolicy != "random-moves"
+
+	// Plays a turn in which both players move at once. Both players are asked
+	// for their move before either is told the other's move, and players that
+	// don't answer within opts.TurnTime, or before they run out of time, fail.
+	// Failed players are killed, and the turn doesn't wait for their reply,
+	// in case killing them doesn't make it return. Returns whether the game
+	// is over.
+	simultaneousTurn := func(ss game.SimultaneousState) bool {
+		type reply struct {
+			player  int
+			line    string
+			err     error
+			elapsed float64
+		}
+		replies := make(chan reply, 2)
+		var waiting [2]bool
+		for i := range clients {
+			if !result.Failed[i] {
+				waiting[i] = true
+				sendClock(i)
+				go func(i int, view string) {
+					timeStart := time.Now()
+					line, err := getMove(i, view)
+					replies <- reply{i, line, err, float64(time.Now().Sub(timeStart).Nanoseconds()) / 1e9}
+				}(i, playerView(i))
+			}
+		}
+		var deadline [2]<-chan time.Time
+		var turnTime [2]time.Duration // time each player has for this turn
+		for i := range clients {
+			turnTime[i] = opts.TurnTime
+			if left, limited := timeLeft(i); limited && (turnTime[i] <= 0 || left < turnTime[i]) {
+				turnTime[i] = left
+			}
+			if waiting[i] && turnTime[i] > 0 {
+				timer := time.NewTimer(turnTime[i])
+				defer timer.Stop()
+				deadline[i] = timer.C
+			}
+		}
+		var lines [2]string
+		var elapsed [2]float64
+		expired := func(i int) {
+			deadline[i] = nil
+			if !waiting[i] {
+				return
+			}
+			waiting[i] = false // replies has room for the late reply
+			elapsed[i] = turnTime[i].Seconds()
+			useTime(i, elapsed[i])
+			result.TimedOut[i] = true
+			if outOfTime(i) {
+				log.Warn("time limit exceeded", "player", commands[i], "limit", limits[i])
+				fail(i, CodeTimeout, "time limit exceeded")
+			} else {
+				log.Warn("turn time exceeded", "player", commands[i], "limit", opts.TurnTime)
+				fail(i, CodeTimeout, "turn time exceeded")
+			}
+		}
+		for waiting[0] || waiting[1] {
+			select {
+			case r := <-replies:
+				waiting[r.player] = false
+				if result.Failed[r.player] {
+					continue // the player ran out of time and was killed
+				}
+				elapsed[r.player] = r.elapsed
+				useTime(r.player, r.elapsed)
+				if outOfTime(r.player) {
+					log.Warn("time limit exceeded", "player", commands[r.player], "limit", limits[r.player])
+					result.TimedOut[r.player] = true
+					fail(r.player, CodeTimeout, "time limit exceeded")
+				} else if errors.Is(r.err, ErrReadTimeout) {
+					log.Warn("read timed out", "player", commands[r.player], "limit", opts.ReadTimeout)
+					result.TimedOut[r.player] = true
+					fail(r.player, CodeTimeout, "read timed out")
+				} else if r.err != nil {
+					log.Warn("couldn't read from player", "player", commands[r.player], "error", r.err)
+					fail(r.player, CodeCrash, "read failed: "+r.err.Error())
+				} else {
+					lines[r.player] = r.line
+				}
+			case <-deadline[0]:
+				expired(0)
+			case <-deadline[1]:
+				expired(1)
+			}
+		}
+
+		var moves [2]interface{}
+		for i, line := range lines {
+			if result.Failed[i] {
+				moves[i] = randomPlayerMove(ss, i)
+			} else if line == resignToken {
+				result.Resigned[i] = true
+				result.Code[i] = CodeResigned
+			} else if move, ok := opts.Game.ParseMove(line); !ok {
+				log.Warn("unparseable move", "player", commands[i], "move", line)
+				fail(i, CodeUnparseableMove, "unparseable move: "+line)
+				moves[i] = randomPlayerMove(ss, i)
+			} else {
+				moves[i] = move
+			}
+		}
+		if result.Resigned[0] || result.Resigned[1] {
+			return true
+		}
+		if stopOnFailure && (result.Failed[0] || result.Failed[1]) {
+			return false
+		}
+		if valid := ss.ExecuteBoth(moves); !valid[0] || !valid[1] {
+			for i := range valid {
+				if !valid[i] {
+					log.Warn("invalid move", "player", commands[i], "move", lines[i])
+					fail(i, CodeIllegalMove, "invalid move: "+lines[i])
+					moves[i] = randomPlayerMove(ss, i)
+				}
+			}
+			if stopOnFailure {
+				return false
+			}
+			if valid = ss.ExecuteBoth(moves); !valid[0] || !valid[1] {
+				panic("Invalid move generated!")
+			}
+		}
+
+		var moveStr [2]string
+		for i, move := range moves {
+			moveStr[i] = move.(fmt.Stringer).String()
+			log.Debug("move played", "player", commands[i], "move", moveStr[i], "elapsed", elapsed[i])
+			opts.Events.Emit(Event{Type: MovePlayed, Player: i + 1, Move: moveStr[i], Elapsed: elapsed[i]})
+		}
+		history = append(history, moveStr[0], moveStr[1])
+		movers = append(movers, 0, 1)
+		times = append(times, elapsed[0], elapsed[1])
+		positions = append(positions, "", game.Position(opts.Game, gamestate))
+		boards = append(boards, "", boardAfter(len(history), gamestate))
+		over := ss.Over()
+		for i, client := range clients {
+			if result.Failed[i] || over || seesView(i) {
+				continue
+			}
+			if err := client.NotifyMove(moveStr[1-i]); err != nil {
+				notifyFailed(i, err)
+			}
+		}
+		return over
+	}
+
+	resumed := 0 // number of moves played before the game was resumed (see Options.Resume)
+
+	// Writes the header of the game log, and the settings of the game.
+	writeLogHeader := func(w io.Writer) {
+		gamelog.WriteHeader(w, &gamelog.Header{
+			Version: gamelog.FormatVersion, Arbiter: gamelog.ArbiterVersion(), Date: started,
+			Game: opts.GameName, Position: startPosition, GameId: opts.GameId, Players: commands,
+			Seed: opts.Seed, GameSeed: opts.GameSeed})
+		for i := range players {
+			if limits[i] > 0 {
+				fmt.Fprintf(w, "# Time limit of player %d: %s\n", i+1, limits[i])
+			}
+		}
+		if opts.Increment > 0 && (clocks[0].limited || clocks[1].limited) {
+			fmt.Fprintf(w, "# Increment: %s\n", opts.Increment)
+		}
+		if clocks[0].period > 0 {
+			fmt.Fprintf(w, "# Byo-yomi: %d periods of %s\n", opts.ByoYomiPeriods, opts.ByoYomiTime)
+		}
+		h := game.HandicapOf(opts.Game)
+		if h.Komi != 0 {
+			fmt.Fprintf(w, "# Komi: %d\n", h.Komi)
+		}
+		if h.Stones != 0 {
+			fmt.Fprintf(w, "# Handicap: %d\n", h.Stones)
+		}
+	}
+
+	// Writes the comments about move j of the game log, including the board
+	// after the move if withBoard is set.
+	writeLogMove := func(w io.Writer, j int, withBoard bool) {
+		fmt.Fprintf(w, "# Move %d by player %d: %s (%.3fs)\n", j+1, movers[j]+1, history[j], times[j])
+		if positions[j] != "" {
+			fmt.Fprintf(w, "# Position after move %d: %s\n", j+1, positions[j])
+		}
+		if boards[j] != "" && withBoard {
+			writeBoard(w, fmt.Sprintf("Board after move %d:", j+1), boards[j])
+		}
+	}
+
+	// Until the game is over, the moves are appended to the game log as they
+	// are played, so that the game can be resumed from it if the arbiter stops
+	// unexpectedly (see Options.Resume).
+	var progressLog *gamelog.AppendLog
+	loggedMoves := 0    // number of moves in progressLog
+	loggedSwap := false // whether the swap is recorded in progressLog
+	appendLog := func() error {
+		if progressLog == nil {
+			var err error
+			if progressLog, err = gamelog.CreateAppendLog(logPath); err != nil {
+				return err
+			}
+			writeLogHeader(progressLog)
+		}
+		for ; loggedMoves < len(history); loggedMoves++ {
+			writeLogMove(progressLog, loggedMoves, true)
+		}
+		if result.Swapped && !loggedSwap {
+			fmt.Fprintln(progressLog, "# Players swapped sides after the first move.")
+			loggedSwap = true
+		}
+		return progressLog.Flush()
+	}
+
+	// Writes the complete game log when the game is over. The log is written
+	// to a temporary file first, which replaces the log of the game in
+	// progress, so that the log is never left incomplete.
+	writeLog := func() error {
+		if progressLog != nil {
+			progressLog.Close()
+		}
+		tmpPath := filepath.Join(filepath.Dir(logPath), "."+filepath.Base(logPath))
+		w, err := gamelog.CreateLog(tmpPath)
+		if err != nil {
+			return err
+		}
+		writeLogHeader(w)
+		gamestate.WriteLog(w)
+		for j := range history {
+			// The final board is written below instead.
+			writeLogMove(w, j, j < len(history)-1)
+		}
+		for _, r := range rejections {
+			fmt.Fprintf(w, "# Rejected move %d by player %d: %s (%s)\n", r.move, r.player+1, r.text, r.code)
+		}
+		for _, b := range result.Blunders {
+			fmt.Fprintf(w, "# Blunder at move %d by player %d: %s (%+d to %+d)\n", b.Move, b.Player+1, b.Text, b.Before, b.After)
+		}
+		if result.Swapped {
+			fmt.Fprintln(w, "# Players swapped sides after the first move.")
+		}
+		if resumed > 0 {
+			fmt.Fprintf(w, "# Game resumed after %d moves.\n", resumed)
+		}
+		for i := range players {
+			if result.Restarts[i] > 0 {
+				fmt.Fprintf(w, "# Player %d was restarted %d time(s).\n", i+1, result.Restarts[i])
+			}
+			if clocks[i].period > 0 {
+				fmt.Fprintf(w, "# Byo-yomi periods used by player %d: %d\n", i+1, clocks[i].used)
+			}
+			if result.Exit[i] != "" {
+				fmt.Fprintf(w, "# Player %d exited (%s).\n", i+1, result.Exit[i])
+			}
+			if result.Failed[i] {
+				fmt.Fprintf(w, "# Player %d failed! Reason: %s (%s)\n", i+1, result.Code[i], result.Reason[i])
+			}
+			if result.Resigned[i] {
+				fmt.Fprintf(w, "# Player %d resigned.\n", i+1)
+			}
+		}
+		if opts.LogBoards > 0 {
+			var board strings.Builder
+			if game.RenderBoard(&board, gamestate) {
+				writeBoard(w, "Final board:", board.String())
+			}
+		}
+		if result.DrawAgreed {
+			fmt.Fprintln(w, "# Draw agreed.")
+		}
+		if result.Forfeited {
+			fmt.Fprintln(w, "# Game forfeited.")
+		}
+		if result.NotPlayed {
+			fmt.Fprintln(w, "# Game not played.")
+		}
+		if result.Interrupted {
+			fmt.Fprintln(w, "# Game interrupted!")
+		}
+		if result.Adjudicated {
+			fmt.Fprintf(w, "# Game adjudicated after %d moves.\n", len(history))
+		}
+		if result.Solved {
+			fmt.Fprintf(w, "# Game solved after %d moves.\n", len(history))
+		}
+		fmt.Fprintf(w, "# CPU time: %.3fs - %.3fs.\n", result.CPUTime[0], result.CPUTime[1])
+		summary := fmt.Sprintf("# Score: %d - %d. Time: %.3fs - %.3fs. ",
+			result.Score[0], result.Score[1],
+			result.Time[0], result.Time[1])
+		if result.Score[0] > result.Score[1] {
+			summary += "Player 1 won!"
+		} else if result.Score[1] > result.Score[0] {
+			summary += "Player 2 won!"
+		} else {
+			summary += "It's a tie!"
+		}
+		fmt.Fprintln(w, summary)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmpPath, logPath)
+	}
+
+	// Continues an interrupted game: replays its moves, charges their time to
+	// the clocks again, and tells the players the moves so far.
+	if res := opts.Resume; res != nil && !result.NotPlayed {
+		if state, err := Replay(opts.Game, res.Moves); err != nil {
+			log.Error("couldn't resume game", "error", err)
+			result.Interrupted = true
+		} else {
+			if res.Swapped {
+				swapSides()
+			}
+			gamestate = state
+			history = append(history, res.Moves...)
+			movers = append(movers, res.Movers...)
+			times = append(times, res.Times...)
+			for j := range res.Moves {
+				// In turns where both players move at once, there is only
+				// a position after the second move.
+				position, board := "", ""
+				if state, err := Replay(opts.Game, res.Moves[:j+1]); err == nil {
+					position = game.Position(opts.Game, state)
+					board = boardAfter(j+1, state)
+				}
+				positions = append(positions, position)
+				boards = append(boards, board)
+			}
+			for j, mover := range movers {
+				useTime(mover, times[j])
+			}
+			for i, client := range clients {
+				if result.Failed[i] {
+					continue
+				}
+				r, ok := client.(Resumer)
+				if !ok {
+					fail(i, CodeStartFailure, "can't resume game")
+					continue
+				}
+				replay, own := replayFor(i)
+				if err := r.Resume((i == 0) != result.Swapped, replay, own); err != nil {
+					log.Warn("couldn't resume player", "player", commands[i], "error", err)
+					fail(i, CodeStartFailure, "couldn't resume: "+err.Error())
+				}
+			}
+			resumed = len(history)
+			log.Info("game resumed", "moves", resumed)
+		}
+	}
+
+	over := gamestate.Over() || result.NotPlayed || result.Interrupted
+	logFailed := false // whether writing the game log in progress failed
+	for !over {
+		if logPath != "" && !logFailed {
+			if err := appendLog(); err != nil {
+				log.Error("couldn't write game log", "error", err)
+				logFailed = true
+			}
+		}
+		if ctx.Err() != nil {
+			result.Interrupted = true
+			break
+		}
+		if opts.MaxMoves > 0 && len(history) >= opts.MaxMoves {
+			result.Adjudicated = true
+			break
+		}
+		if s, ok := gamestate.(game.Solver); ok && opts.Solve {
+			if score1, score2, ok := s.Solve(); ok {
+				log.Info("game solved", "score1", score1, "score2", score2)
+				result.Score = [2]int{score1, score2}
+				result.Solved = true
+				break
+			}
+		}
+		if stopOnFailure && (result.Failed[0] || result.Failed[1]) {
+			if opts.FailurePolicy == "adjudicate-current-position" {
+				result.Adjudicated = true
+			} else {
+				result.Forfeited = true
+			}
+			break
+		}
+		if ss, ok := gamestate.(game.SimultaneousState); ok && ss.Simultaneous() {
+			over = simultaneousTurn(ss)
+			continue
+		}
+		moveStr := ""
+		elapsed := 0.0
+		p := gamestate.Next()
+		if result.Failed[p] {
+			// Player failed before; move randomly instead:
+			move := randomMove(opts.Game, gamestate, history)
+			if !gamestate.Execute(move) {
+				panic("Invalid move generated!")
+			}
+			moveStr = move.(fmt.Stringer).String()
+			over = gamestate.Over()
+		} else {
+			// Read move from client
+			sendClock(p)
+			timeStart := time.Now()
+			stop := watchTime(p)
+			line, err := getMove(p, playerView(p))
+			stop()
+			elapsed = float64(time.Now().Sub(timeStart).Nanoseconds()) / 1e9
+			useTime(p, elapsed)
+			if outOfTime(p) {
+				log.Warn("time limit exceeded", "player", commands[p], "limit", limits[p])
+				result.TimedOut[p] = true
+				fail(p, CodeTimeout, "time limit exceeded")
+			} else if errors.Is(err, ErrReadTimeout) {
+				log.Warn("read timed out", "player", commands[p], "limit", opts.ReadTimeout)
+				result.TimedOut[p] = true
+				fail(p, CodeTimeout, "read timed out")
+			} else if err != nil {
+				log.Warn("couldn't read from player", "player", commands[p], "error", err)
+				if !restart(p) {
+					fail(p, CodeCrash, "read failed: "+err.Error())
+				}
+			} else if line == resignToken {
+				result.Resigned[p] = true
+				result.Code[p] = CodeResigned
+				over = true
+			} else if dn, ok := clients[p].(DrawNegotiator); ok && line == drawOfferToken && !drawOffered {
+				drawOffered = true
+				if offerDraw(1 - p) {
+					result.DrawAgreed = true
+					over = true
+				} else if err := dn.DrawDeclined(); err != nil {
+					log.Warn("couldn't write to player", "player", commands[p], "error", err)
+					fail(p, CodeCrash, "write failed: "+err.Error())
+				}
+			} else if line == swapToken && opts.Swap && p == 1 && len(history) == 1 && !result.Swapped {
+				swap()
+			} else {
+				if move, ok := opts.Game.ParseMove(line); !ok {
+					log.Warn("unparseable move", "player", commands[p], "move", line)
+					if !retry(p, line, CodeUnparseableMove) {
+						fail(p, CodeUnparseableMove, "unparseable move: "+line)
+					}
+				} else if !gamestate.Execute(move) {
+					log.Warn("invalid move", "player", commands[p], "move", line)
+					if !retry(p, line, CodeIllegalMove) {
+						fail(p, CodeIllegalMove, "invalid move: "+line)
+					}
+				} else {
+					moveStr = move.(fmt.Stringer).String()
+					over = gamestate.Over()
+				}
+			}
+		}
+		if moveStr != "" {
+			log.Debug("move played", "player", commands[p], "move", moveStr, "elapsed", elapsed)
+			opts.Events.Emit(Event{Type: MovePlayed, Player: p + 1, Move: moveStr, Elapsed: elapsed})
+			history = append(history, moveStr)
+			movers = append(movers, p)
+			times = append(times, elapsed)
+			positions = append(positions, game.Position(opts.Game, gamestate))
+			boards = append(boards, boardAfter(len(history), gamestate))
+			drawOffered = false
+			retried = false
+		}
+		if moveStr != "" && !result.Failed[1-p] && !over && !seesView(1-p) {
+			if err := clients[1-p].NotifyMove(moveStr); err != nil {
+				notifyFailed(1-p, err)
+			}
+		}
+	}
+
+	// Determine scores:
+	if result.Resigned[0] || result.Resigned[1] {
+		// The player that resigned loses.
+		for i := range result.Score {
+			if !result.Resigned[i] {
+				result.Score[i] = 1
+			}
+		}
+	} else if result.DrawAgreed {
+		result.Score[0], result.Score[1] = 0, 0
+	} else if result.Forfeited {
+		// The players that failed lose.
+		for i := range result.Score {
+			if !result.Failed[i] {
+				result.Score[i] = 1
+			}
+		}
+	} else if result.Adjudicated {
+		result.Score[0], result.Score[1] = adjudicate(opts.Adjudication, gamestate)
+	} else if result.Solved {
+		// The score was determined when the game was solved.
+	} else {
+		result.Score[0], result.Score[1] = gamestate.Scores()
+	}
+
+	// Determine competition points:
+	if !result.NotPlayed {
+		result.Points = game.Points(opts.Game, result.Score, result.Failed)
+	}
+
+	// Tell players to quit, and wait for processes to exit:
+	// Processes that may play the next game are kept instead.
+	for i, client := range clients {
+		if client == nil {
+			continue
+		}
+		pp, ok := client.(*ProcessPlayer)
+		if ok && !result.Failed[i] && !result.Interrupted && opts.Sessions.keep(pp) {
+			continue
+		}
+		if rr, ok := client.(ResultReceiver); ok && !result.Interrupted {
+			rr.SetResult(result.Score[i], result.Score[1-i], result.Failed[i])
+		}
+		client.Quit()
+		if ok {
+			result.Memory[i] = pp.peakMemory
+			result.CPUTime[i] = pp.cpuTime.Seconds()
+			result.Exit[i] = pp.exitStatus
+		}
+	}
+
+	result.Moves = len(history)
+	for i := range clocks {
+		result.Periods[i] = clocks[i].used
+	}
+	for j, mover := range movers {
+		result.MoveTime[mover] = append(result.MoveTime[mover], times[j])
+	}
+	result.Duration = time.Since(started).Seconds()
+	result.MoveHash = HashMoves(history, dealt, opts.GameSeed)
+	result.Opening = history[:min(opts.OpeningLength, len(history))]
+	if len(result.Opening) > 0 {
+		if state, err := Replay(opts.Game, result.Opening); err == nil {
+			result.OpeningPosition = game.CanonicalPosition(opts.Game, state)
+		}
+	}
+	if opts.Analysis.Engine != "" && !result.Interrupted {
+		if blunders, err := Analyze(ctx, opts, history); err != nil {
+			log.Warn("couldn't analyze game", "error", err)
+		} else {
+			for i := range blunders {
+				blunders[i].Player = movers[blunders[i].Move-1]
+			}
+			result.Blunders = blunders
+		}
+	}
+
+	// Write to log file, if desired:
+	if logPath != "" {
+		if err := writeLog(); err != nil {
+			log.Error("couldn't write game log", "error", err)
+		}
+	}
+
+	log.Info("game finished", "score1", result.Score[0], "score2", result.Score[1])
+	opts.Events.Emit(Event{Type: GameFinished, Result: &result})
+
+	return result
+}
@@ -0,0 +1,293 @@
+package match
+
+import (
+	"arbiter/game"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Player is a participant in a game. Players are either external programs
+// (see ProcessPlayer) or Go code compiled into the arbiter (see
+// RegisterPlayer).
+type Player interface {
+	// NotifyStart is called once before the game begins.
+	NotifyStart(first bool) error
+	// GetMove returns the player's next move, given all moves played so far.
+	GetMove(history []string) (string, error)
+	// NotifyMove informs the player of the move made by its opponent.
+	NotifyMove(move string) error
+	// Quit is called when the game is over. It is also called for players that
+	// failed during the game.
+	Quit()
+}
+
+// DrawNegotiator is implemented by players that can respond to draw offers.
+type DrawNegotiator interface {
+	// OfferDraw forwards a draw offer by the opponent to the player, and
+	// returns whether the player accepted it.
+	OfferDraw() (bool, error)
+	// DrawDeclined tells the player that its draw offer was declined.
+	DrawDeclined() error
+}
+
+// MoveRetrier is implemented by players that can make another move after an
+// illegal one (see Options.IllegalMoves).
+type MoveRetrier interface {
+	// MoveRejected tells the player that its last move was rejected, after
+	// which it is asked for another move.
+	MoveRejected(move string) error
+}
+
+// Restarter is implemented by players that can be restarted after crashing.
+type Restarter interface {
+	// Restart restarts the player and brings it up to date with the game in
+	// progress, given all moves so far and, for each move, whether it was the
+	// player's.
+	Restart(history []string, own []bool) error
+}
+
+// Resumer is implemented by players that can join a game in progress (see
+// Options.Resume).
+type Resumer interface {
+	// Resume is called instead of NotifyStart when a game is resumed, with
+	// all moves so far and, for each move, whether it was the player's.
+	Resume(first bool, history []string, own []bool) error
+}
+
+// ViewPlayer is implemented by players that can play games with hidden
+// information (see game.Viewer) knowing only their own view of the game.
+// These players are asked for moves with GetMoveView, and are not told their
+// opponent's moves. Other players are told all moves as usual.
+type ViewPlayer interface {
+	// GetMoveView returns the player's next move, given its view of the game.
+	GetMoveView(view string) (string, error)
+}
+
+// Clock is the time both players have left in a game with a time limit (see
+// Options.TimeLimit).
+type Clock struct {
+	Player    int              // 0-based index of the player the clock is sent to
+	Left      [2]time.Duration // time each player may use for its next move, including byo-yomi periods, or 0 if it has no limit
+	Increment [2]time.Duration // time added after each move of each player
+}
+
+// ClockReceiver is implemented by players that can be told the time left on
+// the clocks (see Options.SendClock).
+type ClockReceiver interface {
+	// SetClock is called before the player is asked for its next move.
+	SetClock(clock Clock)
+}
+
+// ResultReceiver is implemented by players that can be told the result of the
+// game when it ends.
+type ResultReceiver interface {
+	// SetResult is called before Quit, unless the game was interrupted, with
+	// the player's score, its opponent's score, and whether the player failed.
+	SetResult(score, opponentScore int, failed bool)
+}
+
+// Killer is implemented by players that can be stopped forcibly.
+type Killer interface {
+	Kill()
+}
+
+// newPlayer creates the player described by a command or engine name for a
+// new game. Player processes are killed when ctx is done.
+func newPlayer(ctx context.Context, opts *Options, command string, vars map[string]string, cpus string, msgPath string, trace *debugTrace) (Player, error) {
+	engine := opts.engine(command)
+	if isBuiltin(engine.Command) {
+		return newBuiltinPlayer(opts.Game, engine.Command)
+	}
+	if isGRPC(engine.Command) {
+		return newGRPCPlayer(ctx, opts, engine.Command), nil
+	}
+	if pp := opts.Sessions.take(engine, vars, cpus); pp != nil {
+		err := pp.reuse(ctx, opts, engine, vars, msgPath, trace)
+		if err == nil {
+			return pp, nil
+		}
+		slog.Warn("couldn't reuse player process", "player", command, "error", err)
+		pp.Kill()
+		pp.Quit()
+	}
+	proc, stdin, stdout, err := runPlayer(ctx, opts, engine, vars, cpus, msgPath, trace)
+	if err != nil {
+		return nil, err
+	}
+	return &ProcessPlayer{ctx: ctx, opts: opts, engine: engine,
+		protocol: opts.protocol(engine), vars: vars, cpus: cpus, msgPath: msgPath, trace: trace,
+		proc: proc, conn: newConnection(opts, stdout, stdin, trace)}, nil
+}
+
+// ProcessPlayer is a player implemented by an external program (or a remote
+// program connected over TCP) that communicates using the selected Protocol.
+type ProcessPlayer struct {
+	ctx      context.Context
+	opts     *Options
+	engine   *Engine
+	protocol Protocol
+	vars     map[string]string
+	cpus     string // CPU cores to pin the program to, if not empty
+	msgPath  string
+	trace    *debugTrace // nil if no debug log is written
+	proc     *Process    // nil for remote players
+	conn     *Connection
+	first    bool
+	restarts int
+	reused   bool // whether the process played an earlier game (see SessionPool)
+
+	peakMemory int64         // peak memory usage in bytes, if known
+	cpuTime    time.Duration // CPU time used by all instances of the program
+	exitStatus string        // how the program exited, if known
+}
+
+func (pp *ProcessPlayer) NotifyStart(first bool) error {
+	pp.first = first
+	moves, settings := pp.startArgs()
+	return pp.protocol.Start(pp.conn, moves, settings)
+}
+
+// startArgs returns whether the player moves at the start of the game, and
+// the settings to announce to it, followed by its setup if the initial state
+// is dealt. If both players move at once at the start, or if games are
+// announced (see Options.AnnounceGames), they are also told which player they
+// are, with the setting "player".
+func (pp *ProcessPlayer) startArgs() (bool, []game.Setting) {
+	settings := game.Settings(pp.opts.Game)
+	state := pp.opts.Game.CreateState()
+	player := 0
+	if !pp.first {
+		player = 1
+	}
+	if d, ok := pp.opts.Game.(game.Dealer); ok {
+		settings = append(settings, d.Setup(state, player)...)
+	}
+	if pp.opts.AnnounceGames && pp.opts.GameNumber > 0 {
+		settings = append(settings, game.Setting{Name: "game", Value: strconv.Itoa(pp.opts.GameNumber)})
+		if pp.opts.Games > 0 {
+			settings = append(settings, game.Setting{Name: "games", Value: strconv.Itoa(pp.opts.Games)})
+		}
+	}
+	simultaneous := game.IsSimultaneous(state)
+	if simultaneous || pp.opts.AnnounceGames {
+		settings = append(settings, game.Setting{Name: "player", Value: strconv.Itoa(player + 1)})
+	}
+	return pp.first || simultaneous, settings
+}
+
+func (pp *ProcessPlayer) GetMove(history []string) (string, error) {
+	return pp.protocol.GetMove(pp.conn, history)
+}
+
+func (pp *ProcessPlayer) GetMoveView(view string) (string, error) {
+	return pp.protocol.GetMoveView(pp.conn, view)
+}
+
+func (pp *ProcessPlayer) NotifyMove(move string) error {
+	return pp.protocol.NotifyMove(pp.conn, move)
+}
+
+// SetClock makes the protocol send the clock with the next move request.
+func (pp *ProcessPlayer) SetClock(clock Clock) {
+	pp.conn.clock = &clock
+}
+
+func (pp *ProcessPlayer) OfferDraw() (bool, error) {
+	return pp.protocol.OfferDraw(pp.conn)
+}
+
+func (pp *ProcessPlayer) DrawDeclined() error {
+	return pp.protocol.DrawDeclined(pp.conn)
+}
+
+func (pp *ProcessPlayer) MoveRejected(move string) error {
+	rp, ok := pp.protocol.(RetryProtocol)
+	if !ok {
+		return errors.New("protocol can't reject moves")
+	}
+	return rp.MoveRejected(pp.conn, move)
+}
+
+// Quit tells the program to quit and waits for it to exit.
+func (pp *ProcessPlayer) Quit() {
+	if pp.conn != nil {
+		pp.protocol.Quit(pp.conn)
+		pp.conn.close()
+		pp.conn = nil
+	}
+	if pp.proc != nil {
+		pp.proc.Wait()
+		pp.peakMemory = pp.proc.peakMemory
+		pp.cpuTime += pp.proc.cpuTime
+		pp.exitStatus = pp.proc.exitStatus
+		pp.proc = nil
+	}
+}
+
+// reuse prepares a program that finished a game for the next game (see
+// SessionPool): its messages are written to the logs of the new game, and it's
+// told that a new game starts.
+func (pp *ProcessPlayer) reuse(ctx context.Context, opts *Options, engine *Engine, vars map[string]string, msgPath string, trace *debugTrace) error {
+	pp.ctx, pp.opts, pp.engine, pp.vars, pp.msgPath, pp.trace = ctx, opts, engine, vars, msgPath, trace
+	pp.restarts = 0
+	pp.reused = true
+	pp.conn.trace = trace
+	pp.proc.redirectStderr(openStderr(opts, msgPath, trace))
+	return pp.protocol.(SessionProtocol).NewGame(pp.conn)
+}
+
+// Kill kills the program, or closes the connection to a remote player.
+func (pp *ProcessPlayer) Kill() {
+	if pp.proc != nil {
+		pp.proc.Kill()
+	} else if pp.conn != nil {
+		pp.conn.writer.Close()
+	}
+}
+
+// Restart starts a new instance of the program. Its messages are written to a
+// separate file, so the messages of the crashed instance are preserved.
+func (pp *ProcessPlayer) Restart(history []string, own []bool) error {
+	pp.Kill()
+	pp.Quit()
+	slog.Info("crashed player exited", "player", pp.engine.Name, "status", pp.exitStatus)
+	pp.restarts++
+	pp.reused = false
+	msgFilePath := pp.msgPath
+	if msgFilePath != "" && msgFilePath != "-" {
+		base, compressed := strings.CutSuffix(msgFilePath, ".gz")
+		msgFilePath = fmt.Sprintf("%s.%d", base, pp.restarts)
+		if compressed {
+			msgFilePath += ".gz"
+		}
+	}
+	proc, stdin, stdout, err := runPlayer(pp.ctx, pp.opts, pp.engine, pp.vars, pp.cpus, msgFilePath, pp.trace)
+	if err != nil {
+		return err
+	}
+	pp.proc = proc
+	pp.conn = newConnection(pp.opts, stdout, stdin, pp.trace)
+	return pp.resume(history, own)
+}
+
+func (pp *ProcessPlayer) Resume(first bool, history []string, own []bool) error {
+	pp.first = first
+	return pp.resume(history, own)
+}
+
+// resume starts the game and brings the program up to date with the moves
+// played so far.
+func (pp *ProcessPlayer) resume(history []string, own []bool) error {
+	moves, settings := pp.startArgs()
+	if _, ok := pp.opts.Game.(game.Viewer); ok {
+		// The player must not learn the moves it didn't see, and its next
+		// view tells it all it may know anyway.
+		history, own = nil, nil
+	}
+	return pp.protocol.Resume(pp.conn, moves, settings, history, own)
+}
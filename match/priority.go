@@ -0,0 +1,38 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes, as used by ionice.
+const (
+	ioClassRealtime   = 1
+	ioClassBestEffort = 2
+	ioClassIdle       = 3
+)
+
+// ParseIONice parses an I/O scheduling class and level like ionice's: "idle",
+// "best-effort" or "realtime", optionally followed by a colon and a level from
+// 0 (highest priority) to 7 (lowest), e.g. "best-effort:7".
+func ParseIONice(s string) (class, level int, err error) {
+	name, levelStr, hasLevel := strings.Cut(s, ":")
+	switch name {
+	case "realtime":
+		class = ioClassRealtime
+	case "best-effort":
+		class = ioClassBestEffort
+	case "idle":
+		class = ioClassIdle
+	default:
+		return 0, 0, fmt.Errorf("unknown I/O scheduling class: %s", name)
+	}
+	level = 4
+	if hasLevel {
+		if level, err = strconv.Atoi(levelStr); err != nil || level < 0 || level > 7 {
+			return 0, 0, fmt.Errorf("invalid I/O priority level: %s", levelStr)
+		}
+	}
+	return class, level, nil
+}
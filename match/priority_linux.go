@@ -0,0 +1,33 @@
+package match
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Targets of ioprio_set.
+const ioprioWhoPgrp = 2
+
+// setPriority sets the nice level and I/O scheduling class (see ParseIONice)
+// of the processes in the given process group.
+func setPriority(pgid int, nice int, ionice string) error {
+	if nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pgid, nice); err != nil {
+			return fmt.Errorf("couldn't set nice level: %w", err)
+		}
+	}
+	if ionice != "" {
+		class, level, err := ParseIONice(ionice)
+		if err != nil {
+			return err
+		}
+		prio := class<<13 | level
+		if class == ioClassIdle {
+			prio = class << 13
+		}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pgid), uintptr(prio)); errno != 0 {
+			return fmt.Errorf("couldn't set I/O priority: %w", errno)
+		}
+	}
+	return nil
+}
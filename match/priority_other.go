@@ -0,0 +1,12 @@
+//go:build !linux
+
+package match
+
+import "errors"
+
+func setPriority(pgid int, nice int, ionice string) error {
+	if nice != 0 || ionice != "" {
+		return errors.New("process priorities are only supported on Linux")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package match
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"os/user"
+	"time"
+)
+
+// CgroupOptions configures running players in cgroups. If Parent is empty, no
+// cgroups are created.
+type CgroupOptions struct {
+	Parent string  // cgroup directory to create player cgroups in
+	CPUs   float64 // CPU limit, if positive
+	Memory string  // memory limit, if not empty
+}
+
+// Process is a running player process.
+type Process struct {
+	cmd    *exec.Cmd
+	stdout io.Closer     // read end of the process's stdout
+	cgroup *Cgroup       // nil if not running in a cgroup
+	msgLog io.Closer     // file that stderr is written to, if any
+	stderr *switchWriter // passes on stderr, if the process may play several games
+	cpus   []int         // CPU cores to pin the process to, if not empty
+	user   string        // user name or id to run the process as, if not empty and not in a container
+
+	// Container runtime and name of the container the process runs, if any
+	// (see ContainerOptions).
+	runtime   string
+	container string
+
+	peakMemory int64         // peak memory usage in bytes, if known
+	cpuTime    time.Duration // CPU time used by the process and its children
+	exitStatus string        // how the process exited, e.g. "exit status 1"
+	exited     chan struct{} // closed when the process has been waited for
+}
+
+// Start starts the process. The process is killed when ctx is done.
+func (p *Process) Start(ctx context.Context) error {
+	p.cmd.SysProcAttr = newSysProcAttr()
+	if p.user != "" {
+		if err := setUser(p.cmd.SysProcAttr, p.user); err != nil {
+			return err
+		}
+	}
+	if p.cgroup != nil {
+		p.cgroup.configure(p.cmd.SysProcAttr)
+	}
+	start := func() error { return startUnpinned(p.cmd.Start) }
+	if len(p.cpus) > 0 {
+		start = func() error { return startPinned(p.cpus, p.cmd.Start) }
+	}
+	if err := start(); err != nil {
+		return err
+	}
+	p.exited = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.Kill()
+		case <-p.exited:
+		}
+	}()
+	return nil
+}
+
+// Kill kills the process and the processes it started, and the container it
+// runs in, if any. Its stdout is closed, so that reading from it doesn't wait
+// for processes that escaped its process group and still hold it open.
+func (p *Process) Kill() {
+	p.killGroup()
+	p.killContainer()
+	if p.stdout != nil {
+		p.stdout.Close()
+	}
+}
+
+// Wait waits for the process to exit and releases its resources. Any
+// processes it left behind are killed.
+func (p *Process) Wait() error {
+	err := p.cmd.Wait()
+	p.killGroup()
+	close(p.exited)
+	p.closeMsgLog()
+	if p.cmd.ProcessState != nil {
+		p.exitStatus = p.cmd.ProcessState.String()
+		p.cpuTime = p.cmd.ProcessState.UserTime() + p.cmd.ProcessState.SystemTime()
+	}
+	if p.cgroup != nil {
+		p.peakMemory = p.cgroup.peakMemory()
+		if t := p.cgroup.cpuTime(); t > 0 {
+			// Includes child processes that weren't waited for.
+			p.cpuTime = t
+		}
+		if p.cgroup.oomKilled() {
+			p.exitStatus += " (out of memory)"
+		}
+		p.cgroup.remove()
+		p.cgroup = nil
+	}
+	return err
+}
+
+// redirectStderr writes the process's further messages to stderr to w
+// instead, and closes the files they were written to. The process must have
+// been started with a switchWriter as stderr.
+func (p *Process) redirectStderr(w io.Writer, closers multiCloser) {
+	p.stderr.set(w)
+	p.closeMsgLog()
+	if len(closers) > 0 {
+		p.msgLog = closers
+	}
+}
+
+// closeMsgLog closes the file that stderr was written to, if any.
+func (p *Process) closeMsgLog() {
+	if p.msgLog != nil {
+		p.msgLog.Close()
+		p.msgLog = nil
+	}
+}
+
+// lookupUser looks up a user by name, or by id if there is no user with that
+// name.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if _, isId := err.(user.UnknownUserError); isId {
+		u, err = user.LookupId(name)
+	}
+	return u, err
+}
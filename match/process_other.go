@@ -0,0 +1,24 @@
+//go:build !unix
+
+package match
+
+import (
+	"errors"
+	"syscall"
+)
+
+func newSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{}
+}
+
+// killGroup kills the process. Child processes are not killed on this
+// platform.
+func (p *Process) killGroup() {
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+func setUser(attr *syscall.SysProcAttr, name string) error {
+	return errors.New("running players as another user is only supported on Unix")
+}
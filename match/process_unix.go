@@ -0,0 +1,41 @@
+//go:build unix
+
+package match
+
+import (
+	"strconv"
+	"syscall"
+)
+
+// Players are started in a new process group, so that any child processes
+// they create can be killed together with them.
+func newSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killGroup kills the process and all other processes in its process group.
+func (p *Process) killGroup() {
+	if p.cmd.Process != nil {
+		syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// setUser sets process attributes that cause a new process to run as the
+// given user (given by name or id), with its primary group and no
+// supplementary groups.
+func setUser(attr *syscall.SysProcAttr, name string) error {
+	u, err := lookupUser(name)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+	attr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}
+	return nil
+}
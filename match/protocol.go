@@ -0,0 +1,502 @@
+package match
+
+import (
+	"arbiter/game"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Connection is the line-based channel between the arbiter and a player.
+type Connection struct {
+	reader    *bufio.Reader
+	writer    io.WriteCloser
+	skipBlank bool          // whether to ignore empty lines
+	timeout   time.Duration // time limit for reading a line, if positive
+	trace     *debugTrace   // records the lines exchanged, if not nil
+	clock     *Clock        // clock to send with the next move request, if not nil
+
+	// If there is a time limit, or when checking for unexpected output, lines
+	// are read by a goroutine, which sends them to lines until closed is
+	// closed. A line received by unexpected that isn't returned is kept in
+	// peeked.
+	lines  chan readResult
+	closed chan struct{}
+	peeked *readResult
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+// ProtocolViolation is returned when a player writes output that the arbiter
+// didn't ask for, such as a second move after its move.
+type ProtocolViolation struct {
+	Output string // the unexpected line
+}
+
+func (pv *ProtocolViolation) Error() string {
+	return "unexpected output: " + pv.Output
+}
+
+// ErrReadTimeout is returned when a player doesn't write a line within
+// Options.ReadTimeout.
+var ErrReadTimeout = errors.New("read timed out")
+
+func newConnection(opts *Options, r io.Reader, w io.WriteCloser, trace *debugTrace) *Connection {
+	return &Connection{reader: bufio.NewReader(r), writer: w,
+		skipBlank: opts.SkipBlankLines, timeout: opts.ReadTimeout, trace: trace}
+}
+
+// readLine reads a line, without the line ending (which may be "\n" or
+// "\r\n") and surrounding whitespace. If the connection has a time limit and
+// no line is read in time, ErrReadTimeout is returned.
+func (c *Connection) readLine() (string, error) {
+	var deadline <-chan time.Time
+	if c.timeout > 0 {
+		c.startReading()
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		var line string
+		var err error
+		if c.peeked != nil {
+			line, err = c.peeked.line, c.peeked.err
+			c.peeked = nil
+		} else if c.lines == nil {
+			line, err = c.reader.ReadString('\n')
+		} else {
+			select {
+			case r := <-c.lines:
+				line, err = r.line, r.err
+			case <-deadline:
+				return "", ErrReadTimeout
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		if c.trace != nil {
+			c.trace.received(line)
+		}
+		if line != "" || !c.skipBlank {
+			return line, nil
+		}
+	}
+}
+
+// unexpected returns a line that the player wrote before the arbiter asked
+// for one, if any. It doesn't wait for output.
+func (c *Connection) unexpected() (string, bool) {
+	c.startReading()
+	for c.peeked == nil {
+		select {
+		case r := <-c.lines:
+			if r.err != nil {
+				c.peeked = &r // report the error when the next line is read
+				break
+			}
+			line := strings.TrimSpace(r.line)
+			if c.trace != nil {
+				c.trace.received(line)
+			}
+			if line != "" || !c.skipBlank {
+				return line, true
+			}
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// startReading starts the goroutine that reads lines, if it isn't running.
+func (c *Connection) startReading() {
+	if c.lines != nil {
+		return
+	}
+	lines, closed := make(chan readResult), make(chan struct{})
+	c.lines, c.closed = lines, closed
+	go func() {
+		for {
+			line, err := c.reader.ReadString('\n')
+			select {
+			case lines <- readResult{line, err}:
+			case <-closed:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// close closes the connection for writing, and stops reading lines.
+func (c *Connection) close() {
+	c.writer.Close()
+	if c.closed != nil {
+		close(c.closed)
+		c.closed = nil
+	}
+}
+
+// takeClock returns the clock to send with the next move request, if any, and
+// clears it.
+func (c *Connection) takeClock() *Clock {
+	clock := c.clock
+	c.clock = nil
+	return clock
+}
+
+func (c *Connection) writeLine(line string) error {
+	if c.trace != nil {
+		c.trace.sent(line)
+	}
+	_, err := fmt.Fprintln(c.writer, line)
+	return err
+}
+
+// Protocol describes how moves are exchanged with a player over a Connection.
+type Protocol interface {
+	// Start is called once before the game begins, with the game's settings
+	// (see game.Announcer). first is whether the player moves at the start,
+	// which in simultaneous games is true for both players.
+	Start(c *Connection, first bool, settings []game.Setting) error
+	// NotifyMove informs the player of the move made by its opponent.
+	NotifyMove(c *Connection, move string) error
+	// GetMove asks the player for its next move, given all moves so far.
+	GetMove(c *Connection, history []string) (string, error)
+	// GetMoveView asks the player for its next move in a game with hidden
+	// information (see game.Viewer), given its view of the game state.
+	GetMoveView(c *Connection, view string) (string, error)
+	// Resume brings a restarted player up to date with the game in progress,
+	// given all moves so far and, for each move, whether it was the player's.
+	Resume(c *Connection, first bool, settings []game.Setting, history []string, own []bool) error
+	// OfferDraw forwards a draw offer by the opponent to the player, and
+	// returns whether the player accepted it.
+	OfferDraw(c *Connection) (bool, error)
+	// DrawDeclined tells the player that its draw offer was declined. The
+	// player must then make a move.
+	DrawDeclined(c *Connection) error
+	// Quit tells the player the game is over.
+	Quit(c *Connection)
+}
+
+// SessionProtocol is implemented by protocols that let a player process play
+// several games (see SessionPool).
+type SessionProtocol interface {
+	// NewGame tells the player that the previous game is over, and a new
+	// one starts, after which Start is called as usual.
+	NewGame(c *Connection) error
+}
+
+// RetryProtocol is implemented by protocols that can ask a player for another
+// move after rejecting an illegal one (see Options.IllegalMoves).
+type RetryProtocol interface {
+	// MoveRejected tells the player that its last move was rejected. The
+	// player must then make another move.
+	MoveRejected(c *Connection, move string) error
+}
+
+// Tokens players may send instead of a move.
+const resignToken = "resign"
+const drawOfferToken = "draw?"
+const swapToken = "swap"
+
+// Replies to a draw offer.
+const drawAcceptToken = "draw"
+const drawDeclineToken = "nodraw"
+
+// Reply to a move that was rejected (see RetryProtocol).
+const rejectToken = "illegal"
+
+// Protocols lists the available protocols by name.
+var Protocols = map[string]Protocol{
+	"codecup": CodeCupProtocol{},
+	"ugi":     UGIProtocol{},
+}
+
+// ProtocolNames returns a comma-separated list of protocol names.
+func ProtocolNames() string {
+	names := make([]string, 0, len(Protocols))
+	for name := range Protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// CodeCupProtocol is the plain protocol used by the CodeCup: the first
+// player receives "Start", after which players simply exchange moves, one per
+// line, until they are told to "Quit".
+//
+// If the game has settings, both players first receive a line "Setting <name>
+// <value>" for each of them.
+//
+// Instead of a move, a player may send "resign" to resign, or "draw?" to offer
+// a draw. A draw offer is forwarded to the opponent, which must reply with
+// "draw" to accept or "nodraw" to decline. If the offer is declined, the player
+// that offered the draw receives "nodraw" and must then make a move.
+//
+// If swapping is enabled (see Options.Swap), the second player may answer the
+// first move with "swap" to take over the first player's side. The first
+// player then receives "swap" and continues as the second player.
+//
+// In games where both players move at the same time, both players receive
+// "Start", after a setting "player" that is 1 or 2. Each turn, both players
+// send their move, and then receive the opponent's move.
+//
+// In games with hidden information, players don't receive their opponent's
+// moves. Instead, they receive a line "View <view>" whenever they must move.
+//
+// If clocks are sent (see Options.SendClock), players receive a line "Time
+// <own> <opponent>" with the milliseconds left on both clocks whenever they
+// must move: after "Start" or their opponent's move, or before "View".
+//
+// If illegal moves may be retried (see Options.IllegalMoves), a player whose
+// move was rejected receives "illegal", and must then make another move.
+//
+// If player processes are kept between games (see SessionPool), a player
+// receives "NewGame" instead of "Quit" after a game, followed by the settings
+// and "Start" of the next game as usual.
+type CodeCupProtocol struct{}
+
+func (CodeCupProtocol) Start(c *Connection, first bool, settings []game.Setting) error {
+	for _, s := range settings {
+		if err := c.writeLine("Setting " + s.Name + " " + s.Value); err != nil {
+			return err
+		}
+	}
+	if first {
+		return c.writeLine("Start")
+	}
+	return nil
+}
+
+// NotifyMove checks that the player didn't write anything since its last
+// move, since any extra output would be mistaken for its next move.
+func (CodeCupProtocol) NotifyMove(c *Connection, move string) error {
+	if line, ok := c.unexpected(); ok {
+		return &ProtocolViolation{line}
+	}
+	return c.writeLine(move)
+}
+
+func (cp CodeCupProtocol) GetMove(c *Connection, history []string) (string, error) {
+	if err := cp.writeClock(c); err != nil {
+		return "", err
+	}
+	return c.readLine()
+}
+
+func (cp CodeCupProtocol) GetMoveView(c *Connection, view string) (string, error) {
+	if err := cp.writeClock(c); err != nil {
+		return "", err
+	}
+	if err := c.writeLine("View " + view); err != nil {
+		return "", err
+	}
+	return c.readLine()
+}
+
+// writeClock sends the time left on the clocks, if it must be sent.
+func (CodeCupProtocol) writeClock(c *Connection) error {
+	clock := c.takeClock()
+	if clock == nil {
+		return nil
+	}
+	own, opponent := clock.Left[clock.Player], clock.Left[1-clock.Player]
+	return c.writeLine(fmt.Sprintf("Time %d %d", own.Milliseconds(), opponent.Milliseconds()))
+}
+
+// Resume replays the game to the player. Since the protocol has no way to set
+// up a position, the player is asked to play its own moves again, which must be
+// the same as before. This only works for deterministic players.
+func (cp CodeCupProtocol) Resume(c *Connection, first bool, settings []game.Setting, history []string, own []bool) error {
+	if err := cp.Start(c, first, settings); err != nil {
+		return err
+	}
+	for i, move := range history {
+		if !own[i] {
+			if err := c.writeLine(move); err != nil {
+				return err
+			}
+		} else if line, err := c.readLine(); err != nil {
+			return err
+		} else if line != move {
+			return fmt.Errorf("replayed move %s differs from original move %s", line, move)
+		}
+	}
+	return nil
+}
+
+func (CodeCupProtocol) OfferDraw(c *Connection) (bool, error) {
+	if err := c.writeLine(drawOfferToken); err != nil {
+		return false, err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch line {
+	case drawAcceptToken:
+		return true, nil
+	case drawDeclineToken:
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid reply to draw offer: %s", line)
+}
+
+func (CodeCupProtocol) DrawDeclined(c *Connection) error {
+	return c.writeLine(drawDeclineToken)
+}
+
+func (CodeCupProtocol) MoveRejected(c *Connection, move string) error {
+	return c.writeLine(rejectToken)
+}
+
+// NewGame checks that the player didn't write anything after the previous
+// game, which would be mistaken for its first move in the new game.
+func (CodeCupProtocol) NewGame(c *Connection) error {
+	if line, ok := c.unexpected(); ok {
+		return &ProtocolViolation{line}
+	}
+	return c.writeLine("NewGame")
+}
+
+func (CodeCupProtocol) Quit(c *Connection) {
+	c.writeLine("Quit")
+}
+
+// UGIProtocol implements the Universal Game Interface, a generalization of
+// the UCI chess protocol. The arbiter sends the full position before each
+// request for a move, so NotifyMove is a no-op. Game settings are sent as
+// "setoption name <name> value <value>" before the new game starts. In games
+// where both players move at the same time, both players' moves of each turn
+// are listed in the position, the first player's first. In games with hidden
+// information, the player's view is sent as "position fen <view>" instead.
+// If clocks are sent (see Options.SendClock), the go command includes the
+// milliseconds left as "p1time <ms> p2time <ms>" and the increments as "p1inc
+// <ms> p2inc <ms>".
+type UGIProtocol struct{}
+
+var errUnexpectedEOF = errors.New("unexpected end of output")
+
+// expect reads lines until one starts with the given token, and returns the
+// remaining fields of that line.
+func expect(c *Connection, token string) ([]string, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if err == io.EOF {
+				err = errUnexpectedEOF
+			}
+			return nil, err
+		}
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == token {
+			return fields[1:], nil
+		}
+	}
+}
+
+func (UGIProtocol) Start(c *Connection, first bool, settings []game.Setting) error {
+	if err := c.writeLine("ugi"); err != nil {
+		return err
+	}
+	if _, err := expect(c, "ugiok"); err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if err := c.writeLine("setoption name " + s.Name + " value " + s.Value); err != nil {
+			return err
+		}
+	}
+	if err := c.writeLine("uginewgame"); err != nil {
+		return err
+	}
+	if err := c.writeLine("isready"); err != nil {
+		return err
+	}
+	_, err := expect(c, "readyok")
+	return err
+}
+
+func (UGIProtocol) NotifyMove(c *Connection, move string) error {
+	return nil
+}
+
+func (up UGIProtocol) GetMove(c *Connection, history []string) (string, error) {
+	position := "position startpos"
+	if len(history) > 0 {
+		position += " moves " + strings.Join(history, " ")
+	}
+	return up.search(c, position)
+}
+
+func (up UGIProtocol) GetMoveView(c *Connection, view string) (string, error) {
+	return up.search(c, "position fen "+view)
+}
+
+// search sends the given position command, asks the player to search, and
+// returns the move it found.
+func (UGIProtocol) search(c *Connection, position string) (string, error) {
+	if err := c.writeLine(position); err != nil {
+		return "", err
+	}
+	goLine := "go"
+	if clock := c.takeClock(); clock != nil {
+		goLine += fmt.Sprintf(" p1time %d p2time %d p1inc %d p2inc %d",
+			clock.Left[0].Milliseconds(), clock.Left[1].Milliseconds(),
+			clock.Increment[0].Milliseconds(), clock.Increment[1].Milliseconds())
+	}
+	if err := c.writeLine(goLine); err != nil {
+		return "", err
+	}
+	args, err := expect(c, "bestmove")
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], nil
+}
+
+// Resume simply starts a new game, since the position is sent with each move
+// request anyway.
+func (up UGIProtocol) Resume(c *Connection, first bool, settings []game.Setting, history []string, own []bool) error {
+	return up.Start(c, first, settings)
+}
+
+// Draw offers are not part of UGI, so they are always declined.
+func (UGIProtocol) OfferDraw(c *Connection) (bool, error) {
+	return false, nil
+}
+
+// DrawDeclined does nothing, since the next GetMove repeats the request.
+func (UGIProtocol) DrawDeclined(c *Connection) error {
+	return nil
+}
+
+// MoveRejected does nothing, since the next GetMove repeats the request.
+func (UGIProtocol) MoveRejected(c *Connection, move string) error {
+	return nil
+}
+
+// NewGame does nothing, since Start already sends "uginewgame".
+func (UGIProtocol) NewGame(c *Connection) error {
+	return nil
+}
+
+func (UGIProtocol) Quit(c *Connection) {
+	c.writeLine("quit")
+}
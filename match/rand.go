@@ -0,0 +1,35 @@
+package match
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng is the source of randomness for fallback moves and built-in players.
+// It is seeded from the clock unless Seed is called.
+var rng = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+// Seed resets the random number generator used by the arbiter, so that runs
+// with the same seed make the same random choices.
+func Seed(seed int64) {
+	rng.Seed(seed)
+}
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (ls *lockedSource) Int63() int64 {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.src.Int63()
+}
+
+func (ls *lockedSource) Seed(seed int64) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.src.Seed(seed)
+}
@@ -0,0 +1,73 @@
+package match
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// Prefix of player commands that denote remote players.
+const remotePrefix = "tcp://"
+
+// Listeners for remote players that connect to the arbiter, by address.
+var listeners = map[string]net.Listener{}
+
+func isRemote(command string) bool {
+	return strings.HasPrefix(command, remotePrefix)
+}
+
+// connectRemote sets up a connection to a remote player. If the address
+// includes a host (e.g. "tcp://example.com:1234") the arbiter connects to it;
+// otherwise (e.g. "tcp://:1234") the arbiter listens on the given port and
+// waits for the player to connect. The same listener is reused for all games.
+// The connection is closed when ctx is done.
+func connectRemote(ctx context.Context, command string) (net.Conn, error) {
+	conn, err := dialOrAccept(ctx, command[len(remotePrefix):])
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	return remoteConn{conn, stop}, nil
+}
+
+// remoteConn is a connection that is closed when a context is done.
+type remoteConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (rc remoteConn) Close() error {
+	rc.stop()
+	return rc.Conn.Close()
+}
+
+func dialOrAccept(ctx context.Context, addr string) (net.Conn, error) {
+	if host, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, err
+	} else if host != "" {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	l := listeners[addr]
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+		listeners[addr] = l
+	}
+	// Accept with a deadline, so that waiting can be cancelled.
+	tl := l.(*net.TCPListener)
+	for {
+		tl.SetDeadline(time.Now().Add(time.Second))
+		conn, err := tl.Accept()
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return conn, err
+	}
+}
@@ -0,0 +1,103 @@
+package match
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// SessionPool keeps player processes alive between games, so that engines
+// with a slow startup don't have to start again for every game. A process
+// that finished a game without failing is kept until the next game of the
+// same engine starts with the same command line and working directory (which
+// may differ between games because of placeholders, see ExpandVars), which it
+// then plays after being told a new game starts (see SessionProtocol).
+// Processes are only kept if their protocol implements SessionProtocol.
+type SessionPool struct {
+	mu   sync.Mutex
+	idle map[string][]*ProcessPlayer // by sessionKey
+}
+
+// NewSessionPool returns an empty pool.
+func NewSessionPool() *SessionPool {
+	return &SessionPool{idle: map[string][]*ProcessPlayer{}}
+}
+
+// sessionKey identifies the processes that can play a game for the given
+// engine, with the given values of the placeholders in its command line and
+// working directory, pinned to the given CPU cores.
+func sessionKey(engine *Engine, vars map[string]string, cpus string) string {
+	key := []string{engine.Name, cpus, ExpandVars(engine.Command, vars), ExpandVars(engine.Dir, vars)}
+	for _, arg := range engine.Args {
+		key = append(key, ExpandVars(arg, vars))
+	}
+	return strings.Join(key, "\x00")
+}
+
+// keep adds a player process that finished a game to the pool, if it can
+// play another. Returns whether it was added. If sp is nil, nothing is kept.
+func (sp *SessionPool) keep(pp *ProcessPlayer) bool {
+	if _, ok := pp.protocol.(SessionProtocol); sp == nil || !ok || pp.proc == nil || pp.conn == nil {
+		return false
+	}
+	pp.proc.redirectStderr(nil, nil)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	key := sessionKey(pp.engine, pp.vars, pp.cpus)
+	sp.idle[key] = append(sp.idle[key], pp)
+	return true
+}
+
+// take removes a process that can play a game for the given engine from the
+// pool and returns it, or returns nil if there is none (see sessionKey).
+func (sp *SessionPool) take(engine *Engine, vars map[string]string, cpus string) *ProcessPlayer {
+	if sp == nil {
+		return nil
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	key := sessionKey(engine, vars, cpus)
+	players := sp.idle[key]
+	if len(players) == 0 {
+		return nil
+	}
+	pp := players[len(players)-1]
+	sp.idle[key] = players[:len(players)-1]
+	return pp
+}
+
+// Close tells the processes in the pool to quit, and waits for them to exit.
+func (sp *SessionPool) Close() {
+	sp.mu.Lock()
+	idle := sp.idle
+	sp.idle = map[string][]*ProcessPlayer{}
+	sp.mu.Unlock()
+	for _, players := range idle {
+		for _, pp := range players {
+			pp.Quit()
+		}
+	}
+}
+
+// switchWriter passes writes on to a writer that can be replaced, so that the
+// messages of a process that plays several games are written to the logs of
+// the game it's playing.
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer // nil to discard writes
+}
+
+func (sw *switchWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.w == nil {
+		return len(p), nil
+	}
+	return sw.w.Write(p)
+}
+
+func (sw *switchWriter) set(w io.Writer) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.w = w
+}
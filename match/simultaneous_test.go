@@ -0,0 +1,44 @@
+package match
+
+import (
+	"arbiter/game/tron"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestSimultaneousTurnTimeout checks that a player that never answers in a
+// turn where both players move at once fails when the turn time is up, even
+// if killing it doesn't close its output.
+func TestSimultaneousTurnTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"silent", "sh -c 'cat >/dev/null'"},
+		// The child keeps stdout open after the player is killed.
+		{"escaped child", "sh -c 'setsid sleep 5 & cat >/dev/null'"},
+	}
+	for _, tt := range tests {
+		if _, err := exec.LookPath("setsid"); err != nil && tt.name == "escaped child" {
+			t.Logf("%s: skipped: %v", tt.name, err)
+			continue
+		}
+		opts := &Options{Game: tron.Game{Cols: 4, Rows: 4}, GameName: "tron", Protocol: CodeCupProtocol{},
+			TurnTime: 200 * time.Millisecond}
+		done := make(chan Result, 1)
+		go func() {
+			done <- Run(context.Background(), opts, [2]int{0, 1},
+				[2]string{tt.command, "builtin:random"}, "", [2]string{})
+		}()
+		select {
+		case res := <-done:
+			if !res.Failed[0] || !res.TimedOut[0] || res.Code[0] != CodeTimeout || res.Failed[1] {
+				t.Errorf("%s: got %+v, want the first player to time out", tt.name, res)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: game didn't end after the turn time", tt.name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package match
+
+import (
+	"arbiter/game"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// hiddenGame is a game with hidden information for testing: players take
+// turns picking a digit from 1 to 3, twice each, and score the sum of their
+// own digits. Players only see their own digits and how many digits their
+// opponent picked.
+type hiddenGame struct{}
+
+type hiddenState struct {
+	digits [2][]int
+}
+
+type digit int
+
+func (d digit) String() string {
+	return strconv.Itoa(int(d))
+}
+
+func (hiddenGame) CreateState() game.GameState {
+	return &hiddenState{}
+}
+
+func (hiddenGame) ParseMove(s string) (interface{}, bool) {
+	d, err := strconv.Atoi(s)
+	return digit(d), err == nil
+}
+
+func (hiddenGame) PlayerView(state game.GameState, player int) string {
+	s := state.(*hiddenState)
+	own := make([]string, len(s.digits[player]))
+	for i, d := range s.digits[player] {
+		own[i] = strconv.Itoa(d)
+	}
+	return fmt.Sprintf("own=%s opponent=%d", strings.Join(own, ","), len(s.digits[1-player]))
+}
+
+func (s *hiddenState) Over() bool {
+	return len(s.digits[1]) == 2
+}
+
+func (s *hiddenState) Next() int {
+	if len(s.digits[0]) > len(s.digits[1]) {
+		return 1
+	}
+	return 0
+}
+
+func (s *hiddenState) ListMoves() []interface{} {
+	return []interface{}{digit(1), digit(2), digit(3)}
+}
+
+func (s *hiddenState) Execute(arg interface{}) bool {
+	d, ok := arg.(digit)
+	if !ok || d < 1 || d > 3 || s.Over() {
+		return false
+	}
+	p := s.Next()
+	s.digits[p] = append(s.digits[p], int(d))
+	return true
+}
+
+func (s *hiddenState) Scores() (int, int) {
+	var sums [2]int
+	for p := range sums {
+		for _, d := range s.digits[p] {
+			sums[p] += d
+		}
+	}
+	return sums[0], sums[1]
+}
+
+func (s *hiddenState) WriteLog(w io.Writer) {}
+
+// Player scripts that append every line they receive to the file given as
+// their argument, and always play 1.
+const (
+	codeCupViewScript = `while read -r line; do
+	echo "$line" >> "$1"
+	case "$line" in
+	View*) echo 1 ;;
+	Quit) exit 0 ;;
+	esac
+done
+`
+	ugiViewScript = `while read -r line; do
+	echo "$line" >> "$1"
+	case "$line" in
+	ugi) echo ugiok ;;
+	isready) echo readyok ;;
+	go*) echo bestmove 1 ;;
+	quit) exit 0 ;;
+	esac
+done
+`
+)
+
+// runViewGame plays hiddenGame between two instances of a player script, and
+// returns the lines each of them received.
+func runViewGame(t *testing.T, protocol Protocol, script string) [2][]string {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "player.sh")
+	if err := os.WriteFile(scriptPath, []byte(script), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var commands [2]string
+	for i := range commands {
+		commands[i] = fmt.Sprintf("sh %s %s", scriptPath, filepath.Join(dir, fmt.Sprintf("received%d", i+1)))
+	}
+	opts := &Options{Game: hiddenGame{}, GameName: "hidden", Protocol: protocol,
+		FailurePolicy: "random-moves", IllegalMoves: "fail"}
+	res := Run(context.Background(), opts, [2]int{0, 1}, commands, "", [2]string{})
+	if res.Failed[0] || res.Failed[1] || res.Score != [2]int{2, 2} {
+		t.Fatalf("game failed: %+v", res)
+	}
+	var received [2][]string
+	for i := range received {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("received%d", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		received[i] = strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	return received
+}
+
+func TestViewCodeCup(t *testing.T) {
+	got := runViewGame(t, CodeCupProtocol{}, codeCupViewScript)
+	want := [2][]string{
+		{"Start", "View own= opponent=0", "View own=1 opponent=1", "Quit"},
+		{"View own= opponent=1", "View own=1 opponent=2", "Quit"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("players received\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestViewUGI(t *testing.T) {
+	got := runViewGame(t, UGIProtocol{}, ugiViewScript)
+	for i, want := range [2][]string{
+		{"position fen own= opponent=0", "position fen own=1 opponent=1"},
+		{"position fen own= opponent=1", "position fen own=1 opponent=2"},
+	} {
+		var positions []string
+		for _, line := range got[i] {
+			if strings.HasPrefix(line, "position ") {
+				positions = append(positions, line)
+			}
+		}
+		if !reflect.DeepEqual(positions, want) {
+			t.Errorf("player %d received positions %q, want %q", i+1, positions, want)
+		}
+	}
+}
+
+func TestHiddenGameView(t *testing.T) {
+	state := hiddenGame{}.CreateState()
+	for _, d := range []digit{3, 1, 2} {
+		state.Execute(d)
+	}
+	for p, want := range []string{"own=3,2 opponent=1", "own=1 opponent=2"} {
+		if got := (hiddenGame{}).PlayerView(state, p); got != want {
+			t.Errorf("view of player %d is %q, want %q", p+1, got, want)
+		}
+	}
+}
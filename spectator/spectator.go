@@ -0,0 +1,259 @@
+// Package spectator streams games in progress to spectators over TCP, so that
+// an audience can follow a contest without access to the arbiter's host.
+//
+// Clients receive plain text: a line when a game starts, each move with the
+// board after it, player failures and the result of each game. Clients that
+// connect in the middle of a game first get the players and current board of
+// each game in progress. Connect with e.g. "nc <host> <port>".
+package spectator
+
+import (
+	"arbiter/game"
+	"arbiter/match"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Number of lines buffered for each client. Clients that fall further behind
+// are disconnected, so that they can't hold up the others.
+const clientBuffer = 1024
+
+// Server streams the events of a tournament to its clients, after a delay.
+type Server struct {
+	game  game.Game
+	delay time.Duration
+
+	mu      sync.Mutex
+	queue   []match.Event     // events that are not sent yet, oldest first
+	wake    chan struct{}     // signaled when an event is queued
+	games   map[int]*gameView // games in progress, by game number
+	clients map[net.Conn]chan string
+}
+
+// gameView is the state of a game in progress as seen by spectators.
+type gameView struct {
+	players []string
+	state   game.GameState
+	pending [2]interface{} // moves received so far in a simultaneous turn
+	moves   int
+}
+
+// New returns a server for games of g that sends events to its clients delay
+// after they happen, e.g. to keep players from getting help from spectators.
+func New(g game.Game, delay time.Duration) *Server {
+	return &Server{game: g, delay: delay, wake: make(chan struct{}, 1),
+		games: map[int]*gameView{}, clients: map[net.Conn]chan string{}}
+}
+
+// Handle queues an event to be sent to the clients. It is meant to be passed
+// to match.EventLog.AddHandler.
+func (s *Server) Handle(e match.Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Serve accepts clients on l and sends them events until ctx is done.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	go s.broadcast(ctx)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				err = nil
+			}
+			s.mu.Lock()
+			for conn, lines := range s.clients {
+				close(lines)
+				delete(s.clients, conn)
+			}
+			s.mu.Unlock()
+			return err
+		}
+		lines := make(chan string, clientBuffer)
+		s.mu.Lock()
+		for id, g := range s.games {
+			lines <- s.describe(id, g)
+		}
+		s.clients[conn] = lines
+		s.mu.Unlock()
+		go func() {
+			defer conn.Close()
+			for line := range lines {
+				if _, err := conn.Write([]byte(line)); err != nil {
+					s.drop(conn)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Flush waits until all queued events were sent to the clients, or ctx is
+// done, so that the end of the tournament isn't cut off by the delay.
+func (s *Server) Flush(ctx context.Context) {
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		done := len(s.queue) == 0
+		for _, lines := range s.clients {
+			done = done && len(lines) == 0
+		}
+		s.mu.Unlock()
+		if done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// drop disconnects a client.
+func (s *Server) drop(conn net.Conn) {
+	s.mu.Lock()
+	if lines, ok := s.clients[conn]; ok {
+		close(lines)
+		delete(s.clients, conn)
+	}
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// broadcast sends queued events to all clients once they are old enough,
+// until ctx is done.
+func (s *Server) broadcast(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		var wait time.Duration
+		for len(s.queue) > 0 {
+			e := s.queue[0]
+			if wait = time.Until(e.Time.Add(s.delay)); wait > 0 {
+				break
+			}
+			s.queue = s.queue[1:]
+			if text := s.apply(e); text != "" {
+				s.send(text)
+			}
+		}
+		pending := len(s.queue) > 0
+		s.mu.Unlock()
+
+		var timer <-chan time.Time
+		if pending {
+			timer = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer:
+		}
+	}
+}
+
+// send sends text to all clients, dropping those that fell too far behind.
+// Must be called with s.mu held.
+func (s *Server) send(text string) {
+	for conn, lines := range s.clients {
+		select {
+		case lines <- text:
+		default:
+			close(lines)
+			delete(s.clients, conn)
+			conn.Close()
+		}
+	}
+}
+
+// apply updates the games in progress with an event, and returns the text
+// that describes it to spectators, or "" if it's not of interest to them.
+// Must be called with s.mu held.
+func (s *Server) apply(e match.Event) string {
+	g := s.games[e.Game]
+	switch e.Type {
+	case match.GameStarted:
+		gg := game.WithDeal(s.game, e.Seed)
+		if pg, err := game.WithPosition(gg, e.Position); err == nil {
+			gg = pg
+		}
+		g = &gameView{players: e.Players, state: gg.CreateState()}
+		s.games[e.Game] = g
+		return s.describe(e.Game, g)
+	case match.MovePlayed:
+		if g == nil {
+			return ""
+		}
+		g.moves++
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "Game %d, move %d by player %d (%s): %s (%.3fs)\n",
+			e.Game, g.moves, e.Player, g.player(e.Player-1), e.Move, e.Elapsed)
+		move, ok := s.game.ParseMove(e.Move)
+		if ss, simultaneous := g.state.(game.SimultaneousState); ok && simultaneous && ss.Simultaneous() {
+			g.pending[e.Player-1] = move
+			if g.pending[0] == nil || g.pending[1] == nil {
+				return b.String()
+			}
+			ss.ExecuteBoth(g.pending)
+			g.pending = [2]interface{}{}
+		} else if ok {
+			g.state.Execute(move)
+		}
+		if game.RenderBoard(&b, g.state) {
+			b.WriteString("\n")
+		}
+		return b.String()
+	case match.PlayersSwapped:
+		if g == nil {
+			return ""
+		}
+		g.players = e.Players
+		return fmt.Sprintf("Game %d: players swapped sides; now %s vs %s\n", e.Game, g.player(0), g.player(1))
+	case match.PlayerFailed:
+		if g == nil {
+			return ""
+		}
+		return fmt.Sprintf("Game %d: player %d (%s) failed: %s\n", e.Game, e.Player, g.player(e.Player-1), e.Reason)
+	case match.GameFinished:
+		delete(s.games, e.Game)
+		if e.Result == nil {
+			return ""
+		}
+		return fmt.Sprintf("Game %d finished: %d - %d\n\n", e.Game, e.Result.Score[0], e.Result.Score[1])
+	}
+	return ""
+}
+
+// describe returns the text that introduces a game in progress to spectators:
+// its players, and its board if any moves were played.
+func (s *Server) describe(id int, g *gameView) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Game %d started: %s vs %s\n", id, g.player(0), g.player(1))
+	if g.moves > 0 {
+		fmt.Fprintf(&b, "Game %d after %d moves:\n", id, g.moves)
+	}
+	if game.RenderBoard(&b, g.state) {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// player returns the command of the i'th player, or "?" if unknown.
+func (g *gameView) player(i int) string {
+	if i < 0 || i >= len(g.players) {
+		return "?"
+	}
+	return g.players[i]
+}
@@ -0,0 +1,71 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"math"
+)
+
+// nextArenaMatch returns the next match to play in arena mode (see
+// Options.Arena) or adaptive scheduling (see Options.Adaptive), given the
+// results so far. The round of the match is the number of games the pair of
+// players played before in that order. Returns false if filter allows no
+// pairings.
+//
+// In arena mode, the pair that played the fewest games against each other in
+// that order is chosen, and of those, the pair whose ratings are the least
+// certain. With adaptive scheduling, once each pair played once in each
+// order, the pair whose relative strength is the least clear is chosen
+// instead: the pair with the likelihood of superiority closest to 50%.
+func nextArenaMatch(commands []string, filter PairingFilter, adaptive bool, results []match.Result, id int) (Match, bool) {
+	n := len(commands)
+	stats := ComputeStats(commands, results)
+	games := make([][]int, n)
+	for i := range games {
+		games[i] = make([]int, n)
+	}
+	for _, res := range results {
+		games[res.Player[0]][res.Player[1]]++
+	}
+	ratings := ComputeTrueSkill(n, results)
+
+	// priority returns the priority of a pair of players; the pair with
+	// the lowest priority is played first.
+	priority := func(i, j int) [3]float64 {
+		sigma := -(ratings[i].Sigma + ratings[j].Sigma)
+		if !adaptive || games[i][j] == 0 {
+			return [3]float64{0, float64(games[i][j]), sigma}
+		}
+		clarity := 0.0
+		if los, ok := stats.LOS(i, j); ok {
+			clarity = math.Abs(los - 0.5)
+		}
+		return [3]float64{1, clarity, float64(games[i][j])}
+	}
+	best, found := Match{}, false
+	var bestPriority [3]float64
+	for i := range commands {
+		for j := range commands {
+			if i == j || !filter.Allows(commands[i], commands[j]) {
+				continue
+			}
+			p := priority(i, j)
+			if found && !less(p, bestPriority) {
+				continue
+			}
+			best = Match{Id: id, Round: games[i][j], Players: [2]int{i, j},
+				Commands: [2]string{commands[i], commands[j]}}
+			bestPriority, found = p, true
+		}
+	}
+	return best, found
+}
+
+// less compares priorities lexicographically.
+func less(a, b [3]float64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
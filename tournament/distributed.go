@@ -0,0 +1,340 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// In distributed mode, a coordinator (see Options.Coordinator) schedules the
+// games of a tournament, and workers (see RunWorker) fetch games from the
+// coordinator, play them locally, and report the results back.
+//
+// The coordinator serves three requests:
+//
+//	GET  /job              returns the next game to play as a JSON-encoded job,
+//	                       204 No Content if all games were played, or 503
+//	                       Service Unavailable if the games that are left are
+//	                       being played by other workers.
+//	POST /renew?lease=<n>  extends the lease of a game in progress.
+//	POST /result           accepts a game as a JSON-encoded matchResult.
+//
+// All requests must carry the coordinator's token (see Options.Token) in an
+// "Authorization: Bearer <token>" header, since jobs include player commands
+// for the workers to run.
+//
+// Jobs carry the seed of each game, derived from the coordinator's seed, so
+// that a game is the same no matter which worker plays it.
+//
+// A game is leased to the worker that fetched it for leaseTime, which the
+// worker extends while the game is in progress. Games whose lease runs out,
+// e.g. because the worker crashed or lost its connection, and games that are
+// reported as interrupted are handed out again. Only the first result of each
+// game counts.
+//
+// Workers write game and message logs locally, using their own LogPath and
+// MsgPath options, and should otherwise use the same options as the
+// coordinator.
+
+// Time a game is leased to a worker before it's handed out again, unless the
+// worker renews the lease.
+const leaseTime = time.Minute
+
+// Time a worker waits before asking for a game again when all games left are
+// being played by other workers.
+const jobRetry = 10 * time.Second
+
+type job struct {
+	Lease int
+	Match Match
+}
+
+type matchResult struct {
+	Lease  int
+	Match  Match
+	Result match.Result
+}
+
+// lease is a game handed out to a worker.
+type lease struct {
+	id      int
+	expires time.Time
+}
+
+type coordinator struct {
+	mu        sync.Mutex
+	matches   map[int]Match  // scheduled games, by Match.Id
+	pending   []int          // ids of games to hand out, in order
+	leases    map[int]*lease // games handed out, by lease number
+	reported  map[int]bool   // ids of games whose result was received
+	lastLease int
+
+	results chan matchResult // has room for the results of all games
+}
+
+// expire hands out the games with expired leases again. Must be called with
+// c.mu held.
+func (c *coordinator) expire(now time.Time) {
+	for n, l := range c.leases {
+		if now.After(l.expires) {
+			slog.Warn("lease of game expired; handing it out again", "game", l.id+1, "lease", n)
+			delete(c.leases, n)
+			c.pending = append(c.pending, l.id)
+		}
+	}
+}
+
+func (c *coordinator) handleJob(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.expire(now)
+	if len(c.pending) == 0 {
+		if len(c.leases) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.Header().Set("Retry-After", strconv.Itoa(int(jobRetry.Seconds())))
+			http.Error(w, "all games left are in progress", http.StatusServiceUnavailable)
+		}
+		return
+	}
+	id := c.pending[0]
+	c.pending = c.pending[1:]
+	c.lastLease++
+	c.leases[c.lastLease] = &lease{id, now.Add(leaseTime)}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job{c.lastLease, c.matches[id]})
+}
+
+func (c *coordinator) handleRenew(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	n, err := strconv.Atoi(r.URL.Query().Get("lease"))
+	if err != nil {
+		http.Error(w, "invalid lease", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(time.Now())
+	l := c.leases[n]
+	if l == nil {
+		http.Error(w, "lease expired", http.StatusGone)
+		return
+	}
+	l.expires = time.Now().Add(leaseTime)
+}
+
+func (c *coordinator) handleResult(w http.ResponseWriter, r *http.Request) {
+	var mr matchResult
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&mr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, res := mr.Match.Id, mr.Result
+	m, ok := c.matches[id]
+	players := m.Players
+	if res.Swapped {
+		players[0], players[1] = players[1], players[0]
+	}
+	switch {
+	case !ok:
+		http.Error(w, "unknown game", http.StatusNotFound)
+	case c.reported[id]:
+		http.Error(w, "game already reported", http.StatusConflict)
+	case res.Player != players:
+		http.Error(w, "players don't match the game", http.StatusBadRequest)
+	case res.Interrupted:
+		// Hand the game out again, unless its lease already expired.
+		if l := c.leases[mr.Lease]; l != nil && l.id == id {
+			delete(c.leases, mr.Lease)
+			c.pending = append([]int{id}, c.pending...)
+		}
+	default:
+		c.reported[id] = true
+		for n, l := range c.leases {
+			if l.id == id {
+				delete(c.leases, n)
+			}
+		}
+		c.results <- matchResult{mr.Lease, m, res}
+	}
+}
+
+// runCoordinator hands out the given matches to workers connecting on
+// opts.Coordinator, and calls report for each result received, until all games
+// are played. If opts.Token is empty, a random token is generated and printed.
+func runCoordinator(ctx context.Context, opts *Options, matches []Match, report func(Match, match.Result)) error {
+	l, err := net.Listen("tcp", opts.Coordinator)
+	if err != nil {
+		return err
+	}
+	token := opts.Token
+	if token == "" {
+		token = newToken("Worker token")
+	}
+	c := &coordinator{matches: map[int]Match{}, leases: map[int]*lease{},
+		reported: map[int]bool{}, results: make(chan matchResult, len(matches))}
+	for _, m := range matches {
+		if m.Seed == nil {
+			seed := opts.Match.Seed + int64(m.Id)
+			m.Seed = &seed
+		}
+		c.matches[m.Id] = m
+		c.pending = append(c.pending, m.Id)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/job", c.handleJob)
+	mux.HandleFunc("/renew", c.handleRenew)
+	mux.HandleFunc("/result", c.handleResult)
+	srv := &http.Server{Handler: requireToken(token, false, mux)}
+	go srv.Serve(l)
+	defer srv.Close()
+	for range matches {
+		select {
+		case mr := <-c.results:
+			report(mr.Match, mr.Result)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// RunWorker plays games for the coordinator at the given URL until no games
+// are left, or ctx is done. Games that are interrupted are handed back to the
+// coordinator, to be played by another worker. Requests carry opts.Token.
+func RunWorker(ctx context.Context, opts *Options, url string) error {
+	url = strings.TrimSuffix(url, "/")
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", url+"/job", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := doWithToken(req, opts.Token)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			resp.Body.Close()
+			return nil
+		}
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			// The games left are in progress elsewhere, but may be handed
+			// out again if their workers fail.
+			resp.Body.Close()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(jobRetry):
+			}
+			continue
+		}
+		var j job
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("coordinator returned %s", resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&j)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		renewCtx, stopRenewing := context.WithCancel(ctx)
+		go renewLease(renewCtx, url, opts.Token, j.Lease)
+		res := PlayMatch(ctx, opts, j.Match)
+		stopRenewing()
+		if res.Interrupted {
+			// Hand the game back, even though ctx is done.
+			postCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err := postResult(postCtx, url, opts.Token, matchResult{j.Lease, j.Match, res})
+			cancel()
+			if err != nil {
+				slog.Warn("couldn't hand back interrupted game", "game", j.Match.Id+1, "error", err)
+			}
+			return ctx.Err()
+		}
+		if err := postResult(ctx, url, opts.Token, matchResult{j.Lease, j.Match, res}); err != nil {
+			return err
+		}
+	}
+}
+
+// doWithToken sends a request to the coordinator, authorized with token.
+func doWithToken(req *http.Request, token string) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
+// renewLease renews a lease on a game at the coordinator at the given URL,
+// until ctx is done.
+func renewLease(ctx context.Context, url, token string, n int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(leaseTime / 4):
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/renew?lease=%d", url, n), nil)
+		if err != nil {
+			return
+		}
+		resp, err := doWithToken(req, token)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("couldn't renew lease", "lease", n, "error", err)
+			}
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			slog.Warn("couldn't renew lease", "lease", n, "status", resp.Status)
+		}
+	}
+}
+
+// postResult reports a game to the coordinator at the given URL. Games that
+// were already reported, by a worker that the game was handed out to after
+// this worker's lease expired, are skipped with a warning.
+func postResult(ctx context.Context, url, token string, mr matchResult) error {
+	body, err := json.Marshal(mr)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url+"/result", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := doWithToken(req, token)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		slog.Warn("game was already reported by another worker", "game", mr.Match.Id+1)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coordinator returned %s", resp.Status)
+	}
+	return nil
+}
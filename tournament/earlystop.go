@@ -0,0 +1,52 @@
+package tournament
+
+import (
+	"arbiter/game"
+	"fmt"
+)
+
+// EarlyStop configures when a tournament stops before all scheduled games
+// are played, because the outcome is decided.
+type EarlyStop struct {
+	// Stop when the likelihood of superiority of the leader over the player
+	// ranked second is at least this (e.g. 0.95), if not 0.
+	Confidence float64
+
+	// Stop when the player with this 1-based number can no longer finish
+	// first, even by winning all its remaining games, if not 0.
+	Contender int
+}
+
+// reason returns why the tournament should stop, given the standings so far
+// and the maximum number of games each player has left (or nil if unknown),
+// or "" if it should go on.
+func (e EarlyStop) reason(g game.Game, s *Stats, remaining []int) string {
+	ranking := s.Ranking()
+	if e.Confidence > 0 && len(ranking) > 1 {
+		if los, ok := s.LOS(ranking[0], ranking[1]); ok && los >= e.Confidence {
+			return fmt.Sprintf("%s leads %s with %.1f%% likelihood of superiority",
+				s.Players[ranking[0]], s.Players[ranking[1]], 100*los)
+		}
+	}
+	if c := e.Contender - 1; c >= 0 && c < len(s.Players) && remaining != nil {
+		// A player's current points are a lower bound of its final points.
+		best := s.TotalPoints[c] + remaining[c]*game.Points(g, [2]int{1, 0}, [2]bool{})[0]
+		for p, points := range s.TotalPoints {
+			if p != c && points > best {
+				return fmt.Sprintf("%s can no longer reach first place", s.Players[c])
+			}
+		}
+	}
+	return ""
+}
+
+// remainingGames returns the number of the given matches each of n players
+// plays.
+func remainingGames(n int, matches []Match) []int {
+	remaining := make([]int, n)
+	for _, m := range matches {
+		remaining[m.Players[0]]++
+		remaining[m.Players[1]]++
+	}
+	return remaining
+}
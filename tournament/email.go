@@ -0,0 +1,92 @@
+package tournament
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// sendSummary mails the final standings and the failures of each player, with
+// the standings and win/loss matrix as a Markdown report attached (see
+// WriteMarkdown), to the recipients of an email notifier. games is the number
+// of games played, and stopReason why the tournament stopped early, if it did.
+func (n *Notifier) sendSummary(stats *Stats, games int, stopReason string) error {
+	var body bytes.Buffer
+	if stopReason != "" {
+		fmt.Fprintf(&body, "The tournament stopped early (%s) after %d games.\n\n", stopReason, games)
+	} else {
+		fmt.Fprintf(&body, "The tournament finished after %d games.\n\n", games)
+	}
+	stats.PrintStandings(&body)
+	var failures bytes.Buffer
+	stats.PrintFailures(&failures)
+	if failures.Len() > 0 {
+		body.WriteString("\n")
+		body.Write(failures.Bytes())
+	}
+	var report bytes.Buffer
+	if err := stats.WriteMarkdown(&report); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	mw := multipart.NewWriter(&msg)
+	subject := "Tournament finished"
+	if ranking := stats.Ranking(); len(ranking) > 0 && games > 0 {
+		subject += ": " + stats.Players[ranking[0]] + " wins"
+	}
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	writeBase64(part, body.Bytes())
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/markdown; charset=utf-8"},
+		"Content-Disposition":       {`attachment; filename="results.md"`},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	writeBase64(part, report.Bytes())
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.User != "" {
+		host, _, err := net.SplitHostPort(n.Server)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.User, n.Password, host)
+	}
+	return smtp.SendMail(n.Server, auth, n.From, n.To, msg.Bytes())
+}
+
+// writeBase64 writes data in base64, in lines of 76 characters as MIME
+// requires.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		fmt.Fprintf(w, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(w, "%s\r\n", encoded)
+}
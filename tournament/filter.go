@@ -0,0 +1,57 @@
+package tournament
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PairingFilter restricts which pairings of a round robin tournament are
+// played. Each pattern is either "<player>:<player>", which matches the
+// games between two players (in either order), or "<player>", which matches
+// all games of a player. Players are matched by command, in which '*'
+// matches any sequence of characters, e.g. "champ:*" or "bots/*".
+type PairingFilter struct {
+	Only    []string // if not empty, only games matching one of these are played
+	Exclude []string // games matching any of these are not played
+}
+
+// Allows returns whether the game between the players with the given commands
+// is to be played.
+func (f PairingFilter) Allows(a, b string) bool {
+	if len(f.Only) > 0 && !matchesAny(f.Only, a, b) {
+		return false
+	}
+	return !matchesAny(f.Exclude, a, b)
+}
+
+// matchesAny returns whether any of the patterns matches the game between the
+// players with the given commands. Since commands may contain colons
+// themselves, a pattern matches if it can be split at any colon into a
+// pattern for each player.
+func matchesAny(patterns []string, a, b string) bool {
+	for _, pattern := range patterns {
+		if globMatch(pattern, a) || globMatch(pattern, b) {
+			return true
+		}
+		for i := range pattern {
+			if pattern[i] != ':' {
+				continue
+			}
+			p, q := pattern[:i], pattern[i+1:]
+			if (globMatch(p, a) && globMatch(q, b)) || (globMatch(p, b) && globMatch(q, a)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// globMatch returns whether s matches pattern, in which '*' matches any
+// sequence of characters and other characters match themselves.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(s)
+}
@@ -0,0 +1,118 @@
+package tournament
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Fixture is a game to play instead of the games of the automatic schedule.
+type Fixture struct {
+	Players [2]int // 0-based indices of the first and second player
+	Seed    *int64 // seed of the game (see match.Options.GameSeed), or nil for the default
+	GameId  string // identifier of the game, or "" for the default
+}
+
+// ReadFixtures reads a list of games to play between the players given by
+// commands. Each line lists the first and the second player, by 1-based
+// number or by command (if it contains no spaces), optionally followed by the
+// seed of the game, e.g. "1 3 12345". Empty lines and lines
+// starting with '#' are ignored.
+func ReadFixtures(r io.Reader, commands []string) ([]Fixture, error) {
+	var fixtures []Fixture
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected \"<player> <player> [seed]\"", lineNo)
+		}
+		var f Fixture
+		for i := range f.Players {
+			p, err := findPlayer(commands, fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err)
+			}
+			f.Players[i] = p
+		}
+		if f.Players[0] == f.Players[1] {
+			return nil, fmt.Errorf("line %d: player %d can't play itself", lineNo, f.Players[0]+1)
+		}
+		if len(fields) > 2 {
+			seed, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid seed: %s", lineNo, fields[2])
+			}
+			f.Seed = &seed
+		}
+		fixtures = append(fixtures, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no games listed")
+	}
+	return fixtures, nil
+}
+
+// LoadFixtures reads a list of games from a file (see ReadFixtures).
+func LoadFixtures(path string, commands []string) ([]Fixture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fixtures, err := ReadFixtures(f, commands)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return fixtures, nil
+}
+
+// findPlayer returns the 0-based index of the player given by 1-based number
+// or by command.
+func findPlayer(commands []string, s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > len(commands) {
+			return 0, fmt.Errorf("no player %d", n)
+		}
+		return n - 1, nil
+	}
+	found := -1
+	for i, command := range commands {
+		if command == s {
+			if found >= 0 {
+				return 0, fmt.Errorf("player %s is ambiguous; use its number", s)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("unknown player: %s", s)
+	}
+	return found, nil
+}
+
+// ScheduleFixtures returns the list of matches to be played in a tournament
+// of the given number of rounds, in each of which the given games are played.
+func ScheduleFixtures(commands []string, fixtures []Fixture, rounds int) []Match {
+	var matches []Match
+	for r := 0; r < rounds; r++ {
+		for _, f := range fixtures {
+			i, j := f.Players[0], f.Players[1]
+			matches = append(matches, Match{Id: len(matches), Round: r, Players: f.Players,
+				Commands: [2]string{commands[i], commands[j]}, Seed: f.Seed, GameId: f.GameId})
+		}
+	}
+	for i := range matches {
+		matches[i].Games = len(matches)
+	}
+	return matches
+}
@@ -0,0 +1,142 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// PlayerSummary is the summary of a player's results that is printed by
+// PrintFormat.
+type PlayerSummary struct {
+	Rank        int
+	Player      string
+	Games       int
+	Points      int
+	Won         int
+	Tied        int
+	Lost        int
+	Failed      int
+	FailReasons string // number of failures by kind, e.g. "invalid move=2;read failed=1"
+	AvgTime     float64
+	MaxTime     float64
+	AvgCPUTime  float64
+	Elo         float64
+	Score       float64 // fraction of games won, counting ties as half
+	Margin      float64 // margin of the 95% confidence interval of Score
+	Glicko      GlickoRating
+	TrueSkill   TrueSkillRating
+}
+
+// Summary returns the summary of player p's results.
+func (s *Stats) Summary(p int) PlayerSummary {
+	rank := 0
+	for i, q := range s.Ranking() {
+		if q == p {
+			rank = i + 1
+		}
+	}
+	var reasons []string
+	for kind, n := range s.FailReasons[p] {
+		reasons = append(reasons, kind+"="+strconv.Itoa(n))
+	}
+	sort.Strings(reasons)
+	score, margin := s.ScoreRate(p)
+	return PlayerSummary{rank, s.Players[p], s.GamesPlayed[p], s.TotalPoints[p],
+		s.GamesWon[p], s.GamesTied[p], s.GamesLost[p], s.GamesFailed[p],
+		strings.Join(reasons, ";"), s.AverageTime(p), s.TimeMax[p], s.AverageCPUTime(p), s.Elo(p), score, margin, s.Glicko[p], s.TrueSkill[p]}
+}
+
+// Columns that may be selected in a Format, by name.
+var formatColumns = map[string]func(ps PlayerSummary) string{
+	"rank":    func(ps PlayerSummary) string { return strconv.Itoa(ps.Rank) },
+	"player":  func(ps PlayerSummary) string { return ps.Player },
+	"games":   func(ps PlayerSummary) string { return strconv.Itoa(ps.Games) },
+	"points":  func(ps PlayerSummary) string { return strconv.Itoa(ps.Points) },
+	"won":     func(ps PlayerSummary) string { return strconv.Itoa(ps.Won) },
+	"tied":    func(ps PlayerSummary) string { return strconv.Itoa(ps.Tied) },
+	"lost":    func(ps PlayerSummary) string { return strconv.Itoa(ps.Lost) },
+	"failed":  func(ps PlayerSummary) string { return strconv.Itoa(ps.Failed) },
+	"reasons": func(ps PlayerSummary) string { return ps.FailReasons },
+	"avgtime": func(ps PlayerSummary) string { return fmt.Sprintf("%f", ps.AvgTime) },
+	"maxtime": func(ps PlayerSummary) string { return fmt.Sprintf("%f", ps.MaxTime) },
+	"avgcpu":  func(ps PlayerSummary) string { return fmt.Sprintf("%f", ps.AvgCPUTime) },
+	"elo":     func(ps PlayerSummary) string { return fmt.Sprintf("%.1f", ps.Elo) },
+	"score":   func(ps PlayerSummary) string { return fmt.Sprintf("%.4f", ps.Score) },
+	"margin":  func(ps PlayerSummary) string { return fmt.Sprintf("%.4f", ps.Margin) },
+	"glicko":  func(ps PlayerSummary) string { return fmt.Sprintf("%.1f", ps.Glicko.Rating) },
+	"rd":      func(ps PlayerSummary) string { return fmt.Sprintf("%.1f", ps.Glicko.Deviation) },
+	"mu":      func(ps PlayerSummary) string { return fmt.Sprintf("%.2f", ps.TrueSkill.Mu) },
+	"sigma":   func(ps PlayerSummary) string { return fmt.Sprintf("%.2f", ps.TrueSkill.Sigma) },
+}
+
+// DefaultFormat is the format of PrintBrief.
+const DefaultFormat = "points,won,tied,lost,failed,avgtime,maxtime"
+
+// Format describes the output of PrintFormat: either a list of columns, or a
+// template.
+type Format struct {
+	columns []string
+	tmpl    *template.Template
+}
+
+// ParseFormat parses a format, which is either a comma-separated list of
+// column names (see FormatColumns), or a text/template that is executed with a
+// PlayerSummary for each player, e.g. "{{.Player}} {{.Points}}".
+func ParseFormat(s string) (*Format, error) {
+	if strings.Contains(s, "{{") {
+		tmpl, err := template.New("format").Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Format{tmpl: tmpl}, nil
+	}
+	columns := strings.Split(s, ",")
+	for _, c := range columns {
+		if formatColumns[c] == nil {
+			return nil, errors.New("unknown column: " + c + " (valid columns are " + FormatColumns() + ")")
+		}
+	}
+	return &Format{columns: columns}, nil
+}
+
+// FormatColumns returns a comma-separated list of the columns that may be
+// used in a Format.
+func FormatColumns() string {
+	var names []string
+	for name := range formatColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// PrintFormat writes one line per player in the given format, in the original
+// player order. Columns are separated by tabs.
+func (s *Stats) PrintFormat(w io.Writer, f *Format) error {
+	for p := range s.Players {
+		ps := s.Summary(p)
+		if f.tmpl != nil {
+			var b strings.Builder
+			if err := f.tmpl.Execute(&b, ps); err != nil {
+				return err
+			}
+			line := b.String()
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			io.WriteString(w, line)
+			continue
+		}
+		fields := make([]string, len(f.columns))
+		for i, c := range f.columns {
+			fields[i] = formatColumns[c](ps)
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+	return nil
+}
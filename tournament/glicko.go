@@ -0,0 +1,130 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"math"
+)
+
+// GlickoRating is a player's rating in the Glicko-2 system, on the Glicko
+// scale (on which a new player is rated 1500 ± 350).
+type GlickoRating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"` // rating deviation; about half the 95% confidence interval
+	Volatility float64 `json:"volatility"`
+}
+
+const (
+	glickoScale      = 173.7178 // conversion factor between the Glicko and Glicko-2 scales
+	glickoRating     = 1500     // initial rating
+	glickoDeviation  = 350      // initial rating deviation
+	glickoVolatility = 0.06     // initial volatility
+	glickoTau        = 0.5      // constrains the change in volatility over time
+	glickoEpsilon    = 1e-6     // convergence tolerance of the volatility
+)
+
+// glickoGame is the outcome of a game for one player: its opponent and its
+// score (1 for a win, 0.5 for a tie and 0 for a loss).
+type glickoGame struct {
+	opponent int
+	score    float64
+}
+
+// ComputeGlicko rates n players with the Glicko-2 system, from the results of
+// the games they played, in the order they were played. The results are split
+// into rating periods of the given number of games; all players' ratings are
+// updated at the end of each period.
+func ComputeGlicko(n int, results []match.Result, period int) []GlickoRating {
+	ratings := make([]GlickoRating, n)
+	for i := range ratings {
+		ratings[i] = GlickoRating{glickoRating, glickoDeviation, glickoVolatility}
+	}
+	if period < 1 {
+		period = 1
+	}
+	for start := 0; start < len(results); start += period {
+		games := make([][]glickoGame, n)
+		for _, result := range results[start:min(start+period, len(results))] {
+			for i := 0; i < 2; i++ {
+				score := 0.5
+				if result.Score[i] > result.Score[1-i] {
+					score = 1
+				} else if result.Score[i] < result.Score[1-i] {
+					score = 0
+				}
+				games[result.Player[i]] = append(games[result.Player[i]], glickoGame{result.Player[1-i], score})
+			}
+		}
+		updated := make([]GlickoRating, n)
+		for p := range ratings {
+			updated[p] = ratings[p].update(ratings, games[p])
+		}
+		ratings = updated
+	}
+	return ratings
+}
+
+// update returns the rating at the end of a rating period in which the given
+// games were played against opponents with the given ratings.
+func (r GlickoRating) update(ratings []GlickoRating, games []glickoGame) GlickoRating {
+	mu := (r.Rating - glickoRating) / glickoScale
+	phi := r.Deviation / glickoScale
+	sigma := r.Volatility
+	if len(games) == 0 {
+		r.Deviation = math.Sqrt(phi*phi+sigma*sigma) * glickoScale
+		return r
+	}
+
+	// Estimated variance of the rating based on game outcomes only, and the
+	// estimated improvement in rating.
+	var vInv, sum float64
+	for _, game := range games {
+		opp := ratings[game.opponent]
+		g := glickoG(opp.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-g*(mu-(opp.Rating-glickoRating)/glickoScale)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (game.score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	// New volatility, found with the Illinois algorithm.
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A, B := a, 0.0
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	sigma = math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+	return GlickoRating{mu*glickoScale + glickoRating, phi * glickoScale, sigma}
+}
+
+// glickoG reduces the impact of a game on a rating by the opponent's rating
+// deviation phi (on the Glicko-2 scale).
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
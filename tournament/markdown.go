@@ -0,0 +1,55 @@
+package tournament
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the standings and the win/loss matrix as GitHub
+// Flavored Markdown tables.
+func (s *Stats) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	ranking := s.Ranking()
+
+	fmt.Fprintln(bw, "| No | Player | Points | Won | Tied | Lost | Failed | Score | Avg time | Max time | Avg CPU time |")
+	fmt.Fprintln(bw, "|---:|:-------|-------:|----:|-----:|-----:|-------:|------:|---------:|---------:|-------------:|")
+	for i, p := range ranking {
+		rate, margin := s.ScoreRate(p)
+		fmt.Fprintf(bw, "| %d | %s | %d | %d | %d | %d | %d | %.1f%% ± %.1f%% | %.3fs | %.3fs | %.3fs |\n",
+			i+1, markdownEscape(s.Players[p]), s.TotalPoints[p], s.GamesWon[p], s.GamesTied[p], s.GamesLost[p],
+			s.GamesFailed[p], 100*rate, 100*margin, s.AverageTime(p), s.TimeMax[p], s.AverageCPUTime(p))
+	}
+
+	fmt.Fprintln(bw)
+	fmt.Fprint(bw, "| No | Player |")
+	for i := range ranking {
+		fmt.Fprintf(bw, " %d |", i+1)
+	}
+	fmt.Fprint(bw, "\n|---:|:-------|")
+	for range ranking {
+		fmt.Fprint(bw, "---:|")
+	}
+	fmt.Fprintln(bw)
+	for i, p := range ranking {
+		fmt.Fprintf(bw, "| %d | %s |", i+1, markdownEscape(s.Players[p]))
+		for _, q := range ranking {
+			if p == q {
+				fmt.Fprint(bw, " |")
+			} else {
+				fmt.Fprintf(bw, " %d |", s.WinLoss[p][q])
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "Games won by the row player against the column player.")
+	return bw.Flush()
+}
+
+// markdownEscape escapes characters that have a special meaning in Markdown
+// table cells.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
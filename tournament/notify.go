@@ -0,0 +1,283 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ChatServices maps the names of the chat services that notifiers can post to
+// to functions that return the body of a webhook request posting a message.
+var ChatServices = map[string]func(text string) interface{}{
+	"discord": func(text string) interface{} { return map[string]string{"content": text} },
+	"slack":   func(text string) interface{} { return map[string]string{"text": text} },
+}
+
+// Maximum length of a chat message, to stay within the limits of the chat
+// services (Discord allows 2000 characters).
+const maxMessage = 1900
+
+// Number of players listed in standings posted by notifiers.
+const notifyStandings = 10
+
+// Notifier posts messages about a tournament to a chat service through an
+// incoming webhook, or mails a summary of the tournament when it's finished.
+type Notifier struct {
+	Service string // name of the chat service (see ChatServices), or "email"
+	URL     string // URL of the webhook
+
+	// Mail server as "host:port", sender, recipients, and user name and
+	// password to log in with, if any (see sendSummary).
+	Server   string
+	From     string
+	To       []string
+	User     string
+	Password string
+
+	Rounds    bool // post the results of each round when it's finished
+	Upsets    bool // post games won by the lower-rated player
+	Standings bool // post the final standings
+
+	// Minimum difference in TrueSkill rating (see ComputeTrueSkill) between
+	// the players before the game for a win to count as an upset.
+	UpsetMargin float64
+}
+
+// ReadNotifiers parses notifier definitions. Each definition starts with the
+// name of a chat service in square brackets (see ChatServices), followed by
+// "key = value" lines:
+//
+//	[discord]
+//	url = https://discord.com/api/webhooks/...
+//	notify = rounds, upsets, standings
+//	upset = 3
+//
+// The "notify" key lists what to post, all three by default, and "upset" the
+// rating difference that makes a win an upset (see Notifier.UpsetMargin), 3
+// by default. An "[email]" section instead mails the final standings, the
+// failures and a Markdown report (see sendSummary):
+//
+//	[email]
+//	server = smtp.example.com:587
+//	from = arbiter@example.com
+//	to = me@example.com, you@example.com
+//	user = arbiter
+//	password = $SMTP_PASSWORD
+//
+// Environment variables in the password are expanded, so that it needn't be
+// stored in the file. Empty lines and lines starting with '#' or ';' are
+// ignored.
+func ReadNotifiers(r io.Reader) ([]*Notifier, error) {
+	var notifiers []*Notifier
+	var n *Notifier
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			service := strings.TrimSpace(line[1 : len(line)-1])
+			if service == "email" {
+				n = &Notifier{Service: service, Standings: true}
+				notifiers = append(notifiers, n)
+				continue
+			}
+			if _, ok := ChatServices[service]; !ok {
+				return nil, fmt.Errorf("line %d: unknown chat service: %s", lineNo, service)
+			}
+			n = &Notifier{Service: service, Rounds: true, Upsets: true, Standings: true, UpsetMargin: 3}
+			notifiers = append(notifiers, n)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", lineNo)
+		}
+		if n == nil {
+			return nil, fmt.Errorf("line %d: %s outside of notifier definition", lineNo, strings.TrimSpace(key))
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if n.Service == "email" {
+			switch key {
+			case "server":
+				n.Server = value
+			case "from":
+				n.From = value
+			case "to":
+				for _, to := range strings.Split(value, ",") {
+					n.To = append(n.To, strings.TrimSpace(to))
+				}
+			case "user":
+				n.User = value
+			case "password":
+				n.Password = os.ExpandEnv(value)
+			default:
+				return nil, fmt.Errorf("line %d: unknown key: %s", lineNo, key)
+			}
+			continue
+		}
+		switch key {
+		case "url":
+			n.URL = value
+		case "notify":
+			n.Rounds, n.Upsets, n.Standings = false, false, false
+			for _, what := range strings.Split(value, ",") {
+				switch strings.TrimSpace(what) {
+				case "rounds":
+					n.Rounds = true
+				case "upsets":
+					n.Upsets = true
+				case "standings":
+					n.Standings = true
+				default:
+					return nil, fmt.Errorf("line %d: expected notify = rounds, upsets and/or standings", lineNo)
+				}
+			}
+		case "upset":
+			margin, err := strconv.ParseFloat(value, 64)
+			if err != nil || margin < 0 {
+				return nil, fmt.Errorf("line %d: invalid rating difference: %s", lineNo, value)
+			}
+			n.UpsetMargin = margin
+		default:
+			return nil, fmt.Errorf("line %d: unknown key: %s", lineNo, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, n := range notifiers {
+		if n.Service == "email" && (n.Server == "" || n.From == "" || len(n.To) == 0) {
+			return nil, fmt.Errorf("server, from and to must be given for email")
+		}
+		if n.Service != "email" && n.URL == "" {
+			return nil, fmt.Errorf("no url given for %s", n.Service)
+		}
+	}
+	return notifiers, nil
+}
+
+// LoadNotifiers reads notifier definitions from a file (see ReadNotifiers).
+func LoadNotifiers(path string) ([]*Notifier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	notifiers, err := ReadNotifiers(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return notifiers, nil
+}
+
+// post posts a message to the chat service. Like postWebhook, it only reports
+// errors.
+func (n *Notifier) post(text string) {
+	if len(text) > maxMessage {
+		text = text[:maxMessage] + "\n..."
+	}
+	postJSON(n.URL, ChatServices[n.Service](text))
+}
+
+// notifications keeps track of the progress of a tournament for notifiers.
+type notifications struct {
+	notifiers []*Notifier
+	commands  []string
+	left      map[int]int      // number of games left in each round, if known
+	games     map[int][]string // results of the games of each round
+}
+
+// newNotifications returns the notifications for a tournament between the
+// players with the given commands, in which the given matches are scheduled
+// (or none, if matches are scheduled as games finish).
+func newNotifications(notifiers []*Notifier, commands []string, matches []Match) *notifications {
+	nn := &notifications{notifiers: notifiers, commands: commands,
+		left: map[int]int{}, games: map[int][]string{}}
+	for _, m := range matches {
+		nn.left[m.Round]++
+	}
+	return nn
+}
+
+// gameFinished posts about a game that was added to results, if it's an upset
+// or finishes a round. m gives the players by the sides they played.
+func (nn *notifications) gameFinished(m Match, res match.Result, results []match.Result) {
+	if res.NotPlayed {
+		return
+	}
+	w, l := 0, 1 // sides of the winner and the loser
+	if res.Score[1] > res.Score[0] {
+		w, l = 1, 0
+	}
+	if res.Score[w] != res.Score[l] {
+		ratings := ComputeTrueSkill(len(nn.commands), results[:len(results)-1])
+		winner, loser := ratings[res.Player[w]], ratings[res.Player[l]]
+		for _, n := range nn.notifiers {
+			if n.Upsets && loser.Mu-winner.Mu >= n.UpsetMargin {
+				n.post(fmt.Sprintf("Upset in game %d: %s (rated %.1f) beat %s (rated %.1f), %d - %d",
+					m.Id+1, m.Commands[w], winner.Mu, m.Commands[l], loser.Mu, res.Score[0], res.Score[1]))
+			}
+		}
+	}
+
+	nn.games[m.Round] = append(nn.games[m.Round], fmt.Sprintf("Game %d: %s vs %s, %d - %d",
+		m.Id+1, m.Commands[0], m.Commands[1], res.Score[0], res.Score[1]))
+	if nn.left[m.Round] == 0 {
+		return // not scheduled in advance
+	}
+	if nn.left[m.Round]--; nn.left[m.Round] > 0 {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Round %d finished:\n", m.Round+1)
+	for _, g := range nn.games[m.Round] {
+		b.WriteString(g + "\n")
+	}
+	delete(nn.games, m.Round)
+	b.WriteString("\n" + standingsText(ComputeStats(nn.commands, results)))
+	for _, n := range nn.notifiers {
+		if n.Rounds {
+			n.post(b.String())
+		}
+	}
+}
+
+// finished posts the final standings, and mails the summary of the
+// tournament.
+func (nn *notifications) finished(results []match.Result, stopReason string) {
+	stats := ComputeStats(nn.commands, results)
+	text := "Final standings:\n"
+	if stopReason != "" {
+		text = "Tournament stopped early (" + stopReason + "). Standings:\n"
+	}
+	text += standingsText(stats)
+	for _, n := range nn.notifiers {
+		if n.Service == "email" {
+			if err := n.sendSummary(stats, len(results), stopReason); err != nil {
+				slog.Warn("couldn't mail summary", "server", n.Server, "error", err)
+			}
+		} else if n.Standings {
+			n.post(text)
+		}
+	}
+}
+
+// standingsText returns the top of the standings as lines of text.
+func standingsText(stats *Stats) string {
+	var b strings.Builder
+	for i, s := range stats.Standings() {
+		if i == notifyStandings {
+			fmt.Fprintf(&b, "(%d more)\n", len(stats.Players)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s: %d points (%d won, %d tied, %d lost)\n", s.Rank, s.Player, s.Points, s.Won, s.Tied, s.Lost)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WritePGN writes the results of a tournament between the given players as
+// PGN games without moves, which is the format read by rating tools such as
+// Ordo and BayesElo. The first player of each game is listed as White.
+// Players with the same command are told apart by their player number, and
+// interrupted games are written with the unknown result "*". Games that were
+// not played are left out.
+func WritePGN(w io.Writer, event string, players []string, results []match.Result) error {
+	names := pgnNames(players)
+	bw := bufio.NewWriter(w)
+	for i, res := range results {
+		if res.NotPlayed {
+			continue
+		}
+		result := "*"
+		if !res.Interrupted {
+			switch {
+			case res.Score[0] > res.Score[1]:
+				result = "1-0"
+			case res.Score[0] < res.Score[1]:
+				result = "0-1"
+			default:
+				result = "1/2-1/2"
+			}
+		}
+		fmt.Fprintf(bw, "[Event %s]\n", pgnString(event))
+		fmt.Fprintf(bw, "[Site \"?\"]\n")
+		fmt.Fprintf(bw, "[Date \"????.??.??\"]\n")
+		fmt.Fprintf(bw, "[Round \"%d\"]\n", i+1)
+		fmt.Fprintf(bw, "[White %s]\n", pgnString(names[res.Player[0]]))
+		fmt.Fprintf(bw, "[Black %s]\n", pgnString(names[res.Player[1]]))
+		fmt.Fprintf(bw, "[Result \"%s\"]\n", result)
+		fmt.Fprintf(bw, "\n%s\n\n", result)
+	}
+	return bw.Flush()
+}
+
+// pgnNames returns the names of the players in PGN output: their commands,
+// followed by their 1-based player number if the same command occurs twice.
+func pgnNames(players []string) []string {
+	count := map[string]int{}
+	for _, p := range players {
+		count[p]++
+	}
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p
+		if count[p] > 1 {
+			names[i] = fmt.Sprintf("%s #%d", p, i+1)
+		}
+	}
+	return names
+}
+
+// pgnString quotes s as a PGN string token.
+func pgnString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
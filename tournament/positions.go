@@ -0,0 +1,78 @@
+package tournament
+
+import (
+	"arbiter/game"
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ReadPositions reads a list of positions of game g to start games from (see
+// game.Positioner), one per line. Empty lines and lines starting with '#' are
+// ignored, and so are positions equivalent to an earlier one under the
+// symmetries of the board (see game.Symmetric), since playing from them would
+// only repeat games.
+func ReadPositions(r io.Reader, g game.Game) ([]string, error) {
+	var positions []string
+	seen := map[string]int{} // line number of each canonical position
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pg, err := game.WithPosition(g, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		canonical := game.CanonicalPosition(pg, pg.CreateState())
+		if prev, ok := seen[canonical]; ok {
+			slog.Warn("skipping duplicate position", "line", lineNo, "same_as", prev)
+			continue
+		}
+		seen[canonical] = lineNo
+		positions = append(positions, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("no positions listed")
+	}
+	return positions, nil
+}
+
+// LoadPositions reads a list of positions from a file (see ReadPositions).
+func LoadPositions(path string, g game.Game) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	positions, err := ReadPositions(f, g)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return positions, nil
+}
+
+// SchedulePositions returns the list of matches to be played to play each of
+// the given matches from each of the given positions: first all matches from
+// the first position, then all matches from the second, and so on.
+func SchedulePositions(matches []Match, positions []string) []Match {
+	var scheduled []Match
+	for _, position := range positions {
+		for _, m := range matches {
+			m.Id = len(scheduled)
+			m.Position = position
+			scheduled = append(scheduled, m)
+		}
+	}
+	for i := range scheduled {
+		scheduled[i].Games = len(scheduled)
+	}
+	return scheduled
+}
@@ -0,0 +1,42 @@
+package tournament
+
+import (
+	"fmt"
+	"time"
+)
+
+// Progress describes how far a tournament has progressed.
+type Progress struct {
+	Done      int     `json:"done"`      // number of games finished
+	Total     int     `json:"total"`     // number of games scheduled, or 0 if not known in advance
+	PerGame   float64 `json:"per_game"`  // average time per game in seconds
+	Remaining float64 `json:"remaining"` // estimated time left in seconds
+}
+
+// newProgress estimates the progress of a tournament in which done out of
+// total games were finished in the given time since the tournament started.
+// The time per game is measured by the clock, so when games are played in
+// parallel, it is less than the time each game takes.
+func newProgress(done, total int, elapsed time.Duration) Progress {
+	p := Progress{Done: done, Total: total}
+	if done > 0 {
+		p.PerGame = elapsed.Seconds() / float64(done)
+	}
+	if total > 0 {
+		p.Remaining = p.PerGame * float64(total-done)
+	}
+	return p
+}
+
+// String describes the progress on one line, e.g.
+// "12/200 games, 3.4s per game, ETA 10m40s".
+func (p Progress) String() string {
+	if p.Total == 0 {
+		return fmt.Sprintf("%d games, %.1fs per game", p.Done, p.PerGame)
+	}
+	if p.Done == 0 {
+		return fmt.Sprintf("%d/%d games", p.Done, p.Total)
+	}
+	return fmt.Sprintf("%d/%d games, %.1fs per game, ETA %s", p.Done, p.Total,
+		p.PerGame, time.Duration(p.Remaining*float64(time.Second)).Round(time.Second))
+}
@@ -0,0 +1,386 @@
+package tournament
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences used to color terminal output:
+const (
+	bold   = "\x1b[1m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	reset  = "\x1b[0m"
+)
+
+func colorize(s, color string) string {
+	return color + s + reset
+}
+
+func toYesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+func shorten(in string, n int) string {
+	if len(in) <= n {
+		return in
+	}
+	if n < 5 {
+		return in[0:n]
+	}
+	a, b := (n-2)/2, (n-2)-(n-2)/2
+	return in[0:a] + ".." + in[len(in)-b:]
+}
+
+// PrintBrief writes one line of tab-separated statistics per player, in the
+// original player order, with the columns of DefaultFormat.
+func (s *Stats) PrintBrief(w io.Writer) {
+	f, _ := ParseFormat(DefaultFormat)
+	s.PrintFormat(w, f)
+}
+
+// PrintStandings writes the ranking ordered by CodeCup total game points,
+// with each player's score rate and its 95% confidence interval, and the
+// likelihood of superiority (LOS) of each player over the next one.
+func (s *Stats) PrintStandings(w io.Writer) {
+	fmt.Fprintln(w, "No Player                         Points  Won Tied Lost Fail Avg Time Max Time  Avg CPU  Score    ±95%    LOS")
+	fmt.Fprintln(w, "-- ------------------------------ ------ ---- ---- ---- ---- -------- -------- -------- ------ ------- ------")
+	ranking := s.Ranking()
+	for i, p := range ranking {
+		rate, margin := s.ScoreRate(p)
+		los := "     -"
+		if i+1 < len(ranking) {
+			if l, ok := s.LOS(p, ranking[i+1]); ok {
+				los = fmt.Sprintf("%5.1f%%", 100*l)
+			}
+		}
+		fmt.Fprintf(w, "%2d %-30s %6d %4d %4d %4d %4d %7.3fs %7.3fs %7.3fs %5.1f%% ±%5.1f%% %s\n",
+			i+1, shorten(s.Players[p], 30), s.TotalPoints[p], s.GamesWon[p], s.GamesTied[p], s.GamesLost[p],
+			s.GamesFailed[p], s.AverageTime(p), s.TimeMax[p], s.AverageCPUTime(p), 100*rate, 100*margin, los)
+	}
+	fmt.Fprintln(w, "-- ------------------------------ ------ ---- ---- ---- ---- -------- -------- -------- ------ ------- ------")
+}
+
+// PrintGlicko writes the players' Glicko-2 ratings, ordered by rating.
+func (s *Stats) PrintGlicko(w io.Writer) {
+	order := make([]int, len(s.Players))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.Glicko[order[i]].Rating > s.Glicko[order[j]].Rating
+	})
+	fmt.Fprintln(w, "No Player                         Rating     RD Volatility")
+	fmt.Fprintln(w, "-- ------------------------------ ------ ------ ----------")
+	for i, p := range order {
+		r := s.Glicko[p]
+		fmt.Fprintf(w, "%2d %-30s %6.0f %6.1f %10.5f\n", i+1, shorten(s.Players[p], 30), r.Rating, r.Deviation, r.Volatility)
+	}
+	fmt.Fprintln(w, "-- ------------------------------ ------ ------ ----------")
+}
+
+// PrintTrueSkill writes the players' TrueSkill ratings, ordered by their
+// conservative skill estimate.
+func (s *Stats) PrintTrueSkill(w io.Writer) {
+	order := make([]int, len(s.Players))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.TrueSkill[order[i]].Conservative() > s.TrueSkill[order[j]].Conservative()
+	})
+	fmt.Fprintln(w, "No Player                         Skill     Mu  Sigma")
+	fmt.Fprintln(w, "-- ------------------------------ ----- ------ ------")
+	for i, p := range order {
+		r := s.TrueSkill[p]
+		fmt.Fprintf(w, "%2d %-30s %5.1f %6.2f %6.2f\n", i+1, shorten(s.Players[p], 30), r.Conservative(), r.Mu, r.Sigma)
+	}
+	fmt.Fprintln(w, "-- ------------------------------ ----- ------ ------")
+	fmt.Fprintln(w, "Skill is Mu - 3 Sigma")
+}
+
+// PrintSides writes how often the first player won, and the results of each
+// player when playing first and when playing second.
+func (s *Stats) PrintSides(w io.Writer) {
+	won, tied, lost := s.FirstPlayerResults()
+	if games := won + tied + lost; games > 0 {
+		fmt.Fprintf(w, "First player won %d, tied %d and lost %d of %d games (score %.1f%%)\n",
+			won, tied, lost, games, 100*(float64(won)+float64(tied)/2)/float64(games))
+	}
+	fmt.Fprintln(w, "                                   Playing first         Playing second")
+	fmt.Fprintln(w, "No Player                          Won Tied Lost  Score   Won Tied Lost  Score")
+	fmt.Fprintln(w, "-- ------------------------------ ---- ---- ---- ------  ---- ---- ---- ------")
+	for i, p := range s.Ranking() {
+		fmt.Fprintf(w, "%2d %-30s", i+1, shorten(s.Players[p], 30))
+		for side := 0; side < 2; side++ {
+			if side > 0 {
+				fmt.Fprint(w, " ")
+			}
+			won, tied, lost := s.SideWon[p][side], s.SideTied[p][side], s.SideLost[p][side]
+			score := "     -"
+			if games := won + tied + lost; games > 0 {
+				score = fmt.Sprintf("%5.1f%%", 100*(float64(won)+float64(tied)/2)/float64(games))
+			}
+			fmt.Fprintf(w, " %4d %4d %4d %s", won, tied, lost, score)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "-- ------------------------------ ---- ---- ---- ------  ---- ---- ---- ------")
+}
+
+// PrintLengths writes the average, median, minimum and maximum length of the
+// games played, in moves and in seconds, overall and for each pair of players.
+func (s *Stats) PrintLengths(w io.Writer) {
+	const format = "%-61s %5s  %10s %6s %4s %4s  %12s %8s %8s %8s\n"
+	fmt.Fprintf(w, format, "Players", "Games", "Moves: Avg", "Median", "Min", "Max", "Seconds: Avg", "Median", "Min", "Max")
+	separator := fmt.Sprintf(format, strings.Repeat("-", 61), "-----", "----------", "------", "----", "----",
+		"------------", "--------", "--------", "--------")
+	fmt.Fprint(w, separator)
+	printLength := func(name string, ls LengthSummary) {
+		fmt.Fprintf(w, "%-61s %5d  %10.1f %6.1f %4d %4d  %12.3f %8.3f %8.3f %8.3f\n", name, ls.Games,
+			ls.AvgMoves, ls.MedianMoves, ls.MinMoves, ls.MaxMoves,
+			ls.AvgSeconds, ls.MedianSeconds, ls.MinSeconds, ls.MaxSeconds)
+	}
+	printLength("All games", s.LengthSummary(-1, -1))
+	ranking := s.Ranking()
+	for i, p := range ranking {
+		for j, q := range ranking[i+1:] {
+			if ls := s.LengthSummary(p, q); ls.Games > 0 {
+				printLength(fmt.Sprintf("%2d %-27s %2d %s", i+1, shorten(s.Players[p], 27), i+j+2, shorten(s.Players[q], 27)), ls)
+			}
+		}
+	}
+	fmt.Fprint(w, separator)
+}
+
+// Players whose time per move increases by at least slowdownFactor over a
+// tournament are flagged by PrintMoveTimes, unless they take less than
+// slowdownMinTime seconds per move, where the noise is too large.
+const (
+	slowdownFactor  = 1.5
+	slowdownMinTime = 0.01
+)
+
+// PrintMoveTimes writes the average, median and 95th percentile of the time
+// each player took per move, and the trend in time per move (see
+// MoveTimeTrend), flagging players that slow down.
+func (s *Stats) PrintMoveTimes(w io.Writer) {
+	fmt.Fprintln(w, "No Player                          Average   Median  95th pct  Trend")
+	fmt.Fprintln(w, "-- ------------------------------ -------- -------- --------- ------")
+	for i, p := range s.Ranking() {
+		avg, median, p95 := s.MoveTimeSummary(p)
+		trend, flag := "     -", ""
+		if t := s.MoveTimeTrend(p); t > 0 {
+			trend = fmt.Sprintf("%5.2fx", t)
+			if t >= slowdownFactor && avg >= slowdownMinTime {
+				flag = " slowing down!"
+			}
+		}
+		fmt.Fprintf(w, "%2d %-30s %7.3fs %7.3fs %8.3fs %s%s\n", i+1, shorten(s.Players[p], 30), avg, median, p95, trend, flag)
+	}
+	fmt.Fprintln(w, "-- ------------------------------ -------- -------- --------- ------")
+	fmt.Fprintln(w, "Time per move. Trend is the time per move in the second half of the games relative to the first.")
+}
+
+// PrintDuplicates writes how many games between each pair of players were
+// exact duplicates of earlier games, which happens when deterministic players
+// play each other repeatedly.
+func (s *Stats) PrintDuplicates(w io.Writer) {
+	total := 0
+	ranking := s.Ranking()
+	for i, p := range ranking {
+		for j, q := range ranking[i+1:] {
+			if n := s.Duplicates[p][q] + s.Duplicates[q][p]; n > 0 {
+				fmt.Fprintf(w, "%2d %-30s vs %2d %-30s %4d of %4d games were duplicates\n",
+					i+1, shorten(s.Players[p], 30), i+j+2, shorten(s.Players[q], 30), n, s.PairGames[p][q])
+				total += n
+			}
+		}
+	}
+	if total == 0 {
+		fmt.Fprintln(w, "No duplicate games.")
+	}
+}
+
+// PrintFailures writes how often each player failed or resigned, by reason
+// (see match.Result.Code), and how many of its games were not played.
+func (s *Stats) PrintFailures(w io.Writer) {
+	notPlayed := 0
+	for _, p := range s.Ranking() {
+		if len(s.FailReasons[p]) == 0 && s.NotPlayed[p] == 0 {
+			continue
+		}
+		var codes []string
+		for code := range s.FailReasons[p] {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Fprintf(w, "%-30s", shorten(s.Players[p], 30))
+		for _, code := range codes {
+			fmt.Fprintf(w, " %s=%d", code, s.FailReasons[p][code])
+		}
+		if s.NotPlayed[p] > 0 {
+			fmt.Fprintf(w, " not_played=%d", s.NotPlayed[p])
+			notPlayed += s.NotPlayed[p]
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Failures and resignations by reason.")
+	if notPlayed > 0 {
+		fmt.Fprintln(w, "Games not played because a player failed to start are left out of all other statistics.")
+	}
+}
+
+// HasFailures returns whether any player failed or resigned, or any game was
+// not played.
+func (s *Stats) HasFailures() bool {
+	for p, reasons := range s.FailReasons {
+		if len(reasons) > 0 || s.NotPlayed[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Number of most common openings listed by PrintOpenings.
+const commonOpenings = 3
+
+// PrintOpenings writes how many different openings were played, overall and
+// between each pair of players, and to how many different positions they led
+// up to symmetry, with the most common ones.
+func (s *Stats) PrintOpenings(w io.Writer) {
+	printOpenings := func(name string, counts []OpeningCount, positions int) {
+		games := 0
+		for _, c := range counts {
+			games += c.Games
+		}
+		fmt.Fprintf(w, "%-61s %4d openings in %4d games", name, len(counts), games)
+		if positions > 0 {
+			fmt.Fprintf(w, " (%d distinct positions)", positions)
+		}
+		for i, c := range counts[:min(commonOpenings, len(counts))] {
+			if i == 0 {
+				fmt.Fprint(w, "; most common: ")
+			} else {
+				fmt.Fprint(w, ", ")
+			}
+			fmt.Fprintf(w, "%s (%d)", c.Moves, c.Games)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(s.Openings) == 0 {
+		return
+	}
+	printOpenings("All games", s.OpeningCounts(-1, -1), s.OpeningPositions(-1, -1))
+	ranking := s.Ranking()
+	for i, p := range ranking {
+		for j, q := range ranking[i+1:] {
+			if counts := s.OpeningCounts(p, q); len(counts) > 0 {
+				printOpenings(fmt.Sprintf("%2d %-27s %2d %s", i+1, shorten(s.Players[p], 27), i+j+2, shorten(s.Players[q], 27)), counts, s.OpeningPositions(p, q))
+			}
+		}
+	}
+}
+
+// PrintPositions writes the games won, tied and lost by each player from each
+// position that games started from (see Options.Positions), so that positions
+// in which a player does badly stand out.
+func (s *Stats) PrintPositions(w io.Writer) {
+	ranking := s.Ranking()
+	for i, position := range s.Positions {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Position %d: %s\n", i+1, position)
+		for j, p := range ranking {
+			res := s.PosResults[i][p]
+			if games := res[0] + res[1] + res[2]; games > 0 {
+				fmt.Fprintf(w, "%2d %-30s %4d won %4d tied %4d lost  %5.1f%%\n", j+1, shorten(s.Players[p], 30),
+					res[0], res[1], res[2], 100*(float64(res[0])+float64(res[1])/2)/float64(games))
+			}
+		}
+	}
+}
+
+// PrintWinLoss writes the matrix of games won by each player against each
+// other player.
+func (s *Stats) PrintWinLoss(w io.Writer) {
+	ranking := s.Ranking()
+	fmt.Fprintf(w, "%34s", "")
+	for i := range s.Players {
+		fmt.Fprintf(w, " %2d ", i+1)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%34s", "")
+	for range s.Players {
+		fmt.Fprintf(w, " ---")
+	}
+	fmt.Fprintln(w)
+	for i, p := range ranking {
+		fmt.Fprintf(w, "%2d %30s ", i+1, shorten(s.Players[p], 30))
+		for _, q := range ranking {
+			if p == q {
+				fmt.Fprintf(w, "    ")
+			} else {
+				fmt.Fprintf(w, " %3d", s.WinLoss[p][q])
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Win count of player 1 (row) against player 2 (column)")
+}
+
+// PrintScoreDifference writes the average difference in score for each player
+// against each opponent.
+func (s *Stats) PrintScoreDifference(w io.Writer) {
+	ranking := s.Ranking()
+	fmt.Fprintf(w, "%34s", "")
+	for i := range s.Players {
+		fmt.Fprintf(w, " %4d  ", i+1)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%34s", "")
+	for range s.Players {
+		fmt.Fprintf(w, " ------")
+	}
+	fmt.Fprintln(w)
+	for i, p := range ranking {
+		fmt.Fprintf(w, "%2d %30s ", i+1, shorten(s.Players[p], 30))
+		for _, q := range ranking {
+			if p == q || s.PairGames[p][q] == 0 {
+				fmt.Fprintf(w, "       ")
+			} else {
+				diff := float64(s.PairScore[p][q] - s.PairScore[q][p])
+				games := float64(s.PairGames[p][q])
+				fmt.Fprintf(w, " %6.2f", diff/games)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Average score difference between players.")
+}
+
+// WritePairingsCSV writes the results of each player against each opponent
+// (see Pairings) as CSV, with a header row. Players are identified by their
+// 1-based player number and their command.
+func (s *Stats) WritePairingsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"player", "player_name", "opponent", "opponent_name",
+		"games", "won", "tied", "lost", "score", "opponent_score"})
+	for _, pr := range s.Pairings() {
+		cw.Write([]string{strconv.Itoa(pr.Player + 1), s.Players[pr.Player],
+			strconv.Itoa(pr.Opponent + 1), s.Players[pr.Opponent],
+			strconv.Itoa(pr.Games), strconv.Itoa(pr.Won), strconv.Itoa(pr.Tied), strconv.Itoa(pr.Lost),
+			strconv.Itoa(pr.Score), strconv.Itoa(pr.OpponentScore)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
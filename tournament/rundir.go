@@ -0,0 +1,103 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// RunDir is a directory that holds everything recorded during one run of the
+// arbiter:
+//
+//	games/          game logs, named after the game number (e.g. 0001.log)
+//	messages/       player message logs (e.g. 0001.1.log, 0001.2.log)
+//	results.json    the result of each game, and the final standings
+//	manifest.json   how the run was started (see Manifest)
+type RunDir struct {
+	Path     string
+	Manifest Manifest
+
+	games []GameSummary
+}
+
+// Manifest describes a run of the arbiter.
+type Manifest struct {
+	RunId    string     `json:"run_id"`
+	Args     []string   `json:"args"` // command line arguments of the arbiter
+	Game     string     `json:"game"`
+	Players  []string   `json:"players"`
+	Rounds   int        `json:"rounds"`
+	Seed     int64      `json:"seed"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// RunResults is the contents of results.json in a run directory.
+type RunResults struct {
+	Games     []GameSummary `json:"games"`
+	Standings []Standing    `json:"standings"`
+	Pairings  []Pairing     `json:"pairings"`
+}
+
+// NewRunDir creates a run directory, and writes its manifest. The path of the
+// directory is given by template, which may contain the placeholders {date}
+// and {time} (when the run started, as YYYY-MM-DD and HHMMSS), {run} (the
+// run identifier), {seed} and {game}. The directory must not exist yet.
+func NewRunDir(template string, manifest Manifest) (*RunDir, error) {
+	started := manifest.Started
+	path := match.ExpandVars(template, map[string]string{
+		"date": started.Format("2006-01-02"),
+		"time": started.Format("150405"),
+		"run":  manifest.RunId,
+		"seed": strconv.FormatInt(manifest.Seed, 10),
+		"game": manifest.Game,
+	})
+	if _, err := os.Stat(path); err == nil {
+		return nil, errors.New("output directory already exists: " + path)
+	}
+	for _, dir := range []string{"games", "messages"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0777); err != nil {
+			return nil, err
+		}
+	}
+	rd := &RunDir{Path: path, Manifest: manifest}
+	return rd, rd.writeJSON("manifest.json", rd.Manifest)
+}
+
+// Configure makes a tournament write its logs to the run directory, and
+// record the results of its games for results.json.
+func (rd *RunDir) Configure(opts *Options) {
+	opts.LogPath = filepath.Join(rd.Path, "games") + string(filepath.Separator)
+	opts.MsgPath = filepath.Join(rd.Path, "messages") + string(filepath.Separator)
+	onResult := opts.OnResult
+	opts.OnResult = func(m Match, res match.Result) {
+		rd.games = append(rd.games, GameSummary{m.Id + 1, m.Commands, res})
+		if onResult != nil {
+			onResult(m, res)
+		}
+	}
+}
+
+// Finish writes results.json with the results of the games played and the
+// standings and pairings from stats, and records the end of the run in the
+// manifest.
+func (rd *RunDir) Finish(stats *Stats) error {
+	if err := rd.writeJSON("results.json", RunResults{rd.games, stats.Standings(), stats.Pairings()}); err != nil {
+		return err
+	}
+	finished := time.Now()
+	rd.Manifest.Finished = &finished
+	return rd.writeJSON("manifest.json", rd.Manifest)
+}
+
+func (rd *RunDir) writeJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(rd.Path, name), append(data, '\n'), 0666)
+}
@@ -0,0 +1,415 @@
+package tournament
+
+import (
+	"arbiter/game"
+	"arbiter/gamelog"
+	"arbiter/match"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// In server mode (see Serve), the arbiter runs as a long-lived service with
+// a REST API for registering engines, running tournaments between them, and
+// inspecting the results:
+//
+//	GET  /engines                         lists registered engines.
+//	POST /engines                         registers an engine, given as a JSON
+//	                                      object {"name": ..., "command": ...}.
+//	GET  /tournaments                     lists tournaments.
+//	POST /tournaments                     starts a tournament, given as a JSON
+//	                                      object {"engines": [...], "rounds": n}.
+//	GET  /tournaments/{id}                returns a tournament's status, games
+//	                                      played so far and standings.
+//	GET  /tournaments/{id}/games/{game}   returns the log of a single game.
+//	GET  /tournaments/{id}/games/{game}/board.svg
+//	                                      draws the final position of a game,
+//	                                      or the one after move n with
+//	                                      ?move=n, as an SVG image.
+//
+// Tournaments are played one at a time, in the order they were submitted.
+//
+// Registered engines are run as commands on the server, so requests other than
+// GET must be authorized with the server's token, in an "Authorization: Bearer
+// <token>" header.
+
+// Engine is a named player command registered with the server.
+type Engine struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+// TournamentRequest is the body of a request to start a tournament.
+type TournamentRequest struct {
+	Engines []string `json:"engines"`
+	Rounds  int      `json:"rounds"`
+}
+
+// GameSummary describes a finished game of a tournament.
+type GameSummary struct {
+	Game    int          `json:"game"`
+	Players [2]string    `json:"players"`
+	Result  match.Result `json:"result"`
+}
+
+// TournamentStatus describes a tournament submitted to the server.
+type TournamentStatus struct {
+	Id        int           `json:"id"`
+	State     string        `json:"state"` // "queued", "running", "finished" or "interrupted"
+	Engines   []string      `json:"engines"`
+	Rounds    int           `json:"rounds"`
+	Games     []GameSummary `json:"games"`
+	Standings []Standing    `json:"standings"`
+	Pairings  []Pairing     `json:"pairings"`
+}
+
+type server struct {
+	opts        *Options
+	logDir      string
+	queue       chan *TournamentStatus
+	mu          sync.Mutex
+	engines     map[string]Engine
+	engineNames []string
+	tournaments []*TournamentStatus
+}
+
+// newToken returns a random token, which is printed so that it can be passed
+// on to clients, with the given description.
+func newToken(what string) string {
+	var b [16]byte
+	rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+	fmt.Fprintln(os.Stderr, what+":", token)
+	return token
+}
+
+// requireToken passes requests on to next if they carry the given token in an
+// "Authorization: Bearer <token>" header, or if they're GET requests and
+// onlyChanges is set.
+func requireToken(token string, onlyChanges bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onlyChanges && (r.Method == "GET" || r.Method == "HEAD") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "valid token required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) handleListEngines(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	engines := []Engine{}
+	for _, name := range s.engineNames {
+		engines = append(engines, s.engines[name])
+	}
+	writeJSON(w, http.StatusOK, engines)
+}
+
+func (s *server) handleAddEngine(w http.ResponseWriter, r *http.Request) {
+	var e Engine
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if e.Name == "" || e.Command == "" {
+		http.Error(w, "name and command required", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.engines[e.Name]; !ok {
+		s.engineNames = append(s.engineNames, e.Name)
+	}
+	s.engines[e.Name] = e
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (s *server) handleListTournaments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []TournamentStatus{}
+	for _, t := range s.tournaments {
+		list = append(list, TournamentStatus{Id: t.Id, State: t.State,
+			Engines: t.Engines, Rounds: t.Rounds})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *server) handleStartTournament(w http.ResponseWriter, r *http.Request) {
+	var req TournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Rounds == 0 {
+		req.Rounds = 1
+	}
+	if len(req.Engines) < 2 {
+		http.Error(w, "at least two engines required", http.StatusBadRequest)
+		return
+	}
+	if req.Rounds < 1 {
+		http.Error(w, "invalid number of rounds", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range req.Engines {
+		if _, ok := s.engines[name]; !ok {
+			http.Error(w, "unknown engine: "+name, http.StatusBadRequest)
+			return
+		}
+	}
+	t := &TournamentStatus{Id: len(s.tournaments) + 1, State: "queued",
+		Engines: req.Engines, Rounds: req.Rounds,
+		Games: []GameSummary{}, Standings: []Standing{}, Pairings: []Pairing{}}
+	select {
+	case s.queue <- t:
+	default:
+		http.Error(w, "too many tournaments queued", http.StatusServiceUnavailable)
+		return
+	}
+	s.tournaments = append(s.tournaments, t)
+	writeJSON(w, http.StatusAccepted, t)
+}
+
+// tournament returns the tournament with the given id, or nil if there is no
+// such tournament.
+func (s *server) tournament(id string) *TournamentStatus {
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 || i > len(s.tournaments) {
+		return nil
+	}
+	return s.tournaments[i-1]
+}
+
+func (s *server) handleGetTournament(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.tournament(id); t == nil {
+		http.NotFound(w, r)
+	} else {
+		writeJSON(w, http.StatusOK, t)
+	}
+}
+
+func (s *server) handleGetGameLog(w http.ResponseWriter, r *http.Request, id, game string) {
+	s.mu.Lock()
+	t := s.tournament(id)
+	s.mu.Unlock()
+	n, err := strconv.Atoi(game)
+	if t == nil || err != nil || n < 1 {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := gamelog.OpenLog(fmt.Sprintf("%s%04d", s.logPath(t), n) + s.opts.logSuffix())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.Copy(w, f)
+}
+
+func (s *server) handleGetBoard(w http.ResponseWriter, r *http.Request, id, n string) {
+	s.mu.Lock()
+	t := s.tournament(id)
+	s.mu.Unlock()
+	i, err := strconv.Atoi(n)
+	if t == nil || err != nil || i < 1 {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := gamelog.OpenLog(fmt.Sprintf("%s%04d", s.logPath(t), i) + s.opts.logSuffix())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	gl, err := gamelog.ReadLog(s.opts.Match.Game, f)
+	f.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	moves := len(gl.Moves)
+	if m := r.URL.Query().Get("move"); m != "" {
+		if moves, err = strconv.Atoi(m); err != nil || moves < 0 || moves > len(gl.Moves) {
+			http.Error(w, "invalid move number", http.StatusBadRequest)
+			return
+		}
+	}
+	g, err := game.WithHandicap(s.opts.Match.Game, gl.Handicap)
+	if err == nil {
+		g, err = game.WithPosition(g, gl.Position)
+	}
+	var state game.GameState
+	if err == nil {
+		state, err = match.Replay(game.WithDeal(g, gl.Deal), gl.Moves[:moves])
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if !game.RenderSVG(w, state) {
+		http.Error(w, "SVG images are not supported for this game", http.StatusNotImplemented)
+	}
+}
+
+func (s *server) handleEngines(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.handleListEngines(w, r)
+	case "POST":
+		s.handleAddEngine(w, r)
+	default:
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) handleTournaments(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/tournaments"), "/"), "/")
+	if path[0] == "" {
+		switch r.Method {
+		case "GET":
+			s.handleListTournaments(w, r)
+		case "POST":
+			s.handleStartTournament(w, r)
+		default:
+			http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+		}
+	} else if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+	} else if len(path) == 1 {
+		s.handleGetTournament(w, r, path[0])
+	} else if len(path) == 3 && path[1] == "games" {
+		s.handleGetGameLog(w, r, path[0], path[2])
+	} else if len(path) == 4 && path[1] == "games" && path[3] == "board.svg" {
+		s.handleGetBoard(w, r, path[0], path[2])
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// logPath returns the prefix of game log files for the given tournament.
+func (s *server) logPath(t *TournamentStatus) string {
+	return filepath.Join(s.logDir, strconv.Itoa(t.Id)) + "-"
+}
+
+// play runs queued tournaments one at a time until ctx is done.
+func (s *server) play(ctx context.Context) {
+	for {
+		var t *TournamentStatus
+		select {
+		case t = <-s.queue:
+		case <-ctx.Done():
+			return
+		}
+		s.mu.Lock()
+		t.State = "running"
+		var commands []string
+		for _, name := range t.Engines {
+			commands = append(commands, s.engines[name].Command)
+		}
+		s.mu.Unlock()
+
+		opts := *s.opts
+		opts.Quiet = true
+		opts.Coordinator = ""
+		opts.LogPath = s.logPath(t)
+		if opts.MsgPath != "" && opts.MsgPath != "-" {
+			opts.MsgPath = fmt.Sprintf("%s%d-", opts.MsgPath, t.Id)
+		}
+		var results []match.Result
+		opts.OnResult = func(m Match, res match.Result) {
+			results = append(results, res)
+			stats := ComputeStats(t.Engines, results)
+			standings, pairings := stats.Standings(), stats.Pairings()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			t.Games = append(t.Games, GameSummary{m.Id + 1,
+				[2]string{t.Engines[m.Players[0]], t.Engines[m.Players[1]]}, res})
+			t.Standings = standings
+			t.Pairings = pairings
+		}
+		Run(ctx, &opts, commands, t.Rounds, false)
+
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			t.State = "interrupted"
+		} else {
+			t.State = "finished"
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Serve runs the arbiter as a tournament server listening on addr, until ctx
+// is done. If addr has no host (e.g. ":8080"), the server only listens on
+// localhost. Requests that change anything must carry opts.Token; if it is
+// empty, a random token is generated and printed. Game logs are written under
+// opts.LogPath, or to a temporary directory if it is empty.
+func Serve(ctx context.Context, opts *Options, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		addr = net.JoinHostPort("localhost", port)
+	}
+	token := opts.Token
+	if token == "" {
+		token = newToken("API token")
+	}
+	logDir := opts.LogPath
+	if logDir == "" {
+		dir, err := os.MkdirTemp("", "arbiter")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		logDir = dir
+	} else if err := os.MkdirAll(logDir, 0777); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := &server{opts: opts, logDir: logDir,
+		queue: make(chan *TournamentStatus, 100), engines: map[string]Engine{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/engines", s.handleEngines)
+	mux.HandleFunc("/tournaments", s.handleTournaments)
+	mux.HandleFunc("/tournaments/", s.handleTournaments)
+	srv := &http.Server{Handler: requireToken(token, true, mux)}
+	go srv.Serve(l)
+	defer srv.Close()
+	s.play(ctx)
+	return nil
+}
@@ -0,0 +1,481 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Stats summarizes the results of a tournament for each player.
+type Stats struct {
+	Players     []string         // player commands
+	GamesPlayed []int            // number of games played
+	TotalPoints []int            // total CodeCup-style points
+	GamesWon    []int            // number of games won
+	GamesTied   []int            // number of games tied
+	GamesLost   []int            // number of games lost
+	GamesFailed []int            // number of games in which the player failed
+	SideWon     [][2]int         // number of games won playing first and second
+	SideTied    [][2]int         // number of games tied playing first and second
+	SideLost    [][2]int         // number of games lost playing first and second
+	FailReasons []map[string]int // number of failures and resignations by code (e.g. match.CodeCrash)
+	NotPlayed   []int            // number of games not played because a player failed to start
+	TimeUsed    []float64        // total time used
+	TimeMax     []float64        // maximum time used in a single game
+	CPUTimeUsed []float64        // total CPU time used
+	WinLoss     [][]int          // games won by the row player against the column player
+	PairScore   [][]int          // total score of the row player against the column player
+	PairGames   [][]int          // number of games between the row and column player
+	MoveTimes   [][][]float64    // time taken for each move, by game
+	Lengths     []GameLength     // lengths of the games that were not interrupted
+	Duplicates  [][]int          // games of the row player (first) against the column player that repeated an earlier game
+	Openings    []GameOpening    // openings of the games that were not interrupted
+	Positions   []string         // positions games started from, if not the usual one, in order of first use
+	PosResults  [][][3]int       // games won, tied and lost from each of Positions, by player
+	Glicko      []GlickoRating   // Glicko-2 ratings
+	TrueSkill   []TrueSkillRating
+}
+
+// ComputeStats collects statistics for the given players from the results of
+// the games they played.
+func ComputeStats(players []string, results []match.Result) *Stats {
+	n := len(players)
+	s := &Stats{
+		Players:     players,
+		GamesPlayed: make([]int, n),
+		TotalPoints: make([]int, n),
+		GamesWon:    make([]int, n),
+		GamesTied:   make([]int, n),
+		GamesLost:   make([]int, n),
+		GamesFailed: make([]int, n),
+		SideWon:     make([][2]int, n),
+		SideTied:    make([][2]int, n),
+		SideLost:    make([][2]int, n),
+		FailReasons: make([]map[string]int, n),
+		NotPlayed:   make([]int, n),
+		TimeUsed:    make([]float64, n),
+		TimeMax:     make([]float64, n),
+		CPUTimeUsed: make([]float64, n),
+		MoveTimes:   make([][][]float64, n),
+		WinLoss:     make([][]int, n),
+		PairScore:   make([][]int, n),
+		PairGames:   make([][]int, n),
+		Duplicates:  make([][]int, n),
+	}
+	for i := range players {
+		s.WinLoss[i] = make([]int, n)
+		s.PairScore[i] = make([]int, n)
+		s.PairGames[i] = make([]int, n)
+		s.Duplicates[i] = make([]int, n)
+		s.FailReasons[i] = map[string]int{}
+	}
+	type game struct {
+		players [2]int
+		hash    string
+	}
+	seen := map[game]bool{}
+	var played []match.Result
+	for _, result := range results {
+		if result.NotPlayed {
+			// Left out of all other statistics.
+			s.NotPlayed[result.Player[0]]++
+			s.NotPlayed[result.Player[1]]++
+			continue
+		}
+		played = append(played, result)
+		if result.Position != "" {
+			pos := -1
+			for i, position := range s.Positions {
+				if position == result.Position {
+					pos = i
+				}
+			}
+			if pos < 0 {
+				pos = len(s.Positions)
+				s.Positions = append(s.Positions, result.Position)
+				s.PosResults = append(s.PosResults, make([][3]int, n))
+			}
+			for i, player := range result.Player {
+				switch {
+				case result.Score[i] > result.Score[1-i]:
+					s.PosResults[pos][player][0]++
+				case result.Score[i] == result.Score[1-i]:
+					s.PosResults[pos][player][1]++
+				default:
+					s.PosResults[pos][player][2]++
+				}
+			}
+		}
+		if !result.Interrupted {
+			s.Lengths = append(s.Lengths, GameLength{result.Player, result.Moves, result.Duration})
+			if len(result.Opening) > 0 {
+				s.Openings = append(s.Openings, GameOpening{result.Player, strings.Join(result.Opening, " "), result.OpeningPosition})
+			}
+			if result.MoveHash != "" {
+				g := game{result.Player, result.MoveHash}
+				if seen[g] {
+					s.Duplicates[result.Player[0]][result.Player[1]]++
+				}
+				seen[g] = true
+			}
+		}
+		for i := 0; i < 2; i++ {
+			player := result.Player[i]
+			opponent := result.Player[1-i]
+			s.GamesPlayed[player]++
+			s.TotalPoints[player] += result.Points[i]
+			s.PairScore[player][opponent] += result.Score[i]
+			s.PairGames[player][opponent]++
+			if result.Failed[i] {
+				s.GamesFailed[player]++
+				code := result.Code[i]
+				if code == "" {
+					code, _, _ = strings.Cut(result.Reason[i], ":")
+				}
+				s.FailReasons[player][code]++
+			} else if result.Resigned[i] {
+				s.FailReasons[player][match.CodeResigned]++
+			}
+			if result.Score[i] > result.Score[1-i] {
+				s.GamesWon[player]++
+				s.SideWon[player][i]++
+				s.WinLoss[player][opponent]++
+			}
+			if result.Score[i] == result.Score[1-i] {
+				s.GamesTied[player]++
+				s.SideTied[player][i]++
+			}
+			if result.Score[i] < result.Score[1-i] {
+				s.GamesLost[player]++
+				s.SideLost[player][i]++
+			}
+			if len(result.MoveTime[i]) > 0 {
+				s.MoveTimes[player] = append(s.MoveTimes[player], result.MoveTime[i])
+			}
+			s.TimeUsed[player] += result.Time[i]
+			s.CPUTimeUsed[player] += result.CPUTime[i]
+			if result.Time[i] > s.TimeMax[player] {
+				s.TimeMax[player] = result.Time[i]
+			}
+		}
+	}
+	// One rating period per round of a round robin tournament.
+	s.Glicko = ComputeGlicko(n, played, n*(n-1))
+	s.TrueSkill = ComputeTrueSkill(n, played)
+	return s
+}
+
+// ScoreRate returns the fraction of games won by player p, counting ties as
+// half a win, together with the margin of its 95% confidence interval (which
+// is rate ± margin), based on the variance of the player's game results.
+func (s *Stats) ScoreRate(p int) (rate, margin float64) {
+	n := float64(s.GamesPlayed[p])
+	if n == 0 {
+		return 0, 0
+	}
+	won, tied, lost := float64(s.GamesWon[p]), float64(s.GamesTied[p]), float64(s.GamesLost[p])
+	rate = (won + tied/2) / n
+	variance := (won*(1-rate)*(1-rate) + tied*(0.5-rate)*(0.5-rate) + lost*rate*rate) / n
+	return rate, 1.96 * math.Sqrt(variance/n)
+}
+
+// GameLength is the length of a game between two players.
+type GameLength struct {
+	Players [2]int
+	Moves   int
+	Seconds float64
+}
+
+// LengthSummary summarizes the lengths of a number of games.
+type LengthSummary struct {
+	Games                     int
+	AvgMoves, MedianMoves     float64
+	MinMoves, MaxMoves        int
+	AvgSeconds, MedianSeconds float64
+	MinSeconds, MaxSeconds    float64
+}
+
+// LengthSummary summarizes the lengths of the games between players p and q,
+// in either order, or of all games if p is negative.
+func (s *Stats) LengthSummary(p, q int) LengthSummary {
+	var moves, seconds []float64
+	for _, l := range s.Lengths {
+		if p < 0 || (l.Players == [2]int{p, q} || l.Players == [2]int{q, p}) {
+			moves = append(moves, float64(l.Moves))
+			seconds = append(seconds, l.Seconds)
+		}
+	}
+	var ls LengthSummary
+	ls.Games = len(moves)
+	if ls.Games == 0 {
+		return ls
+	}
+	var minMoves, maxMoves float64
+	ls.AvgMoves, ls.MedianMoves, minMoves, maxMoves = summarize(moves)
+	ls.MinMoves, ls.MaxMoves = int(minMoves), int(maxMoves)
+	ls.AvgSeconds, ls.MedianSeconds, ls.MinSeconds, ls.MaxSeconds = summarize(seconds)
+	return ls
+}
+
+// summarize returns the average, median, minimum and maximum of a non-empty
+// list of values, which it sorts.
+func summarize(values []float64) (avg, median, min, max float64) {
+	sort.Float64s(values)
+	for _, v := range values {
+		avg += v
+	}
+	avg /= float64(len(values))
+	n := len(values)
+	median = (values[(n-1)/2] + values[n/2]) / 2
+	return avg, median, values[0], values[n-1]
+}
+
+// MoveTimeSummary returns the average, median and 95th percentile of the
+// time player p took per move.
+func (s *Stats) MoveTimeSummary(p int) (avg, median, p95 float64) {
+	var times []float64
+	for _, game := range s.MoveTimes[p] {
+		times = append(times, game...)
+	}
+	if len(times) == 0 {
+		return 0, 0, 0
+	}
+	avg, median, _, _ = summarize(times)
+	return avg, median, times[(len(times)*95+99)/100-1]
+}
+
+// MoveTimeTrend returns the average time per move taken by player p in the
+// second half of its games, relative to the first half, or 0 if it played
+// fewer than 4 games. A value well above 1 suggests that the player slows down
+// over time, e.g. because it leaks memory or manages its time badly.
+func (s *Stats) MoveTimeTrend(p int) float64 {
+	games := s.MoveTimes[p]
+	if len(games) < 4 {
+		return 0
+	}
+	first, second := averageOf(games[:len(games)/2]), averageOf(games[len(games)/2:])
+	if first == 0 {
+		return 0
+	}
+	return second / first
+}
+
+// averageOf returns the average of the values in a list of lists.
+func averageOf(lists [][]float64) float64 {
+	var sum float64
+	var n int
+	for _, list := range lists {
+		for _, v := range list {
+			sum += v
+		}
+		n += len(list)
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// GameOpening is the opening of a game between two players: its first moves,
+// separated by spaces, and the position they lead to, up to symmetry (see
+// match.Result.OpeningPosition).
+type GameOpening struct {
+	Players  [2]int
+	Moves    string
+	Position string
+}
+
+// OpeningCount is the number of games that started with an opening.
+type OpeningCount struct {
+	Moves string
+	Games int
+}
+
+// OpeningCounts returns the number of games between players p and q, in
+// either order, that started with each opening, or of all games if p is
+// negative, from most to least common.
+func (s *Stats) OpeningCounts(p, q int) []OpeningCount {
+	count := map[string]int{}
+	for _, o := range s.Openings {
+		if p < 0 || o.Players == [2]int{p, q} || o.Players == [2]int{q, p} {
+			count[o.Moves]++
+		}
+	}
+	var counts []OpeningCount
+	for moves, games := range count {
+		counts = append(counts, OpeningCount{moves, games})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Games != counts[j].Games {
+			return counts[i].Games > counts[j].Games
+		}
+		return counts[i].Moves < counts[j].Moves
+	})
+	return counts
+}
+
+// OpeningPositions returns the number of different positions reached by the
+// openings of games between players p and q, in either order, or of all games
+// if p is negative. Openings that differ only by the order of moves or by a
+// symmetry of the board count once. Returns 0 if the game has no positions
+// (see game.Positioner).
+func (s *Stats) OpeningPositions(p, q int) int {
+	seen := map[string]bool{}
+	for _, o := range s.Openings {
+		if o.Position != "" && (p < 0 || o.Players == [2]int{p, q} || o.Players == [2]int{q, p}) {
+			seen[o.Position] = true
+		}
+	}
+	return len(seen)
+}
+
+// FirstPlayerResults returns the number of games won, tied and lost by the
+// player that moved first.
+func (s *Stats) FirstPlayerResults() (won, tied, lost int) {
+	for p := range s.Players {
+		won += s.SideWon[p][0]
+		tied += s.SideTied[p][0]
+		lost += s.SideLost[p][0]
+	}
+	return won, tied, lost
+}
+
+// LOS returns the likelihood of superiority of player p over player q: the
+// probability that p is the stronger player, judging from the games they won
+// against each other (ties are ignored). It returns false if neither won a
+// game against the other.
+func (s *Stats) LOS(p, q int) (float64, bool) {
+	wins, losses := float64(s.WinLoss[p][q]), float64(s.WinLoss[q][p])
+	if wins+losses == 0 {
+		return 0, false
+	}
+	return 0.5 * (1 + math.Erf((wins-losses)/math.Sqrt(2*(wins+losses)))), true
+}
+
+// Elo returns the performance rating of player p relative to its opponents,
+// on the Elo scale, from the fraction of games it won (counting ties as half a
+// win). Half a win and half a loss are added to keep the rating finite.
+func (s *Stats) Elo(p int) float64 {
+	wins := float64(s.GamesWon[p]) + float64(s.GamesTied[p])/2 + 0.5
+	return 400 * math.Log10(wins/(float64(s.GamesPlayed[p])+1-wins))
+}
+
+// AverageTime returns the average time used by player p per game.
+func (s *Stats) AverageTime(p int) float64 {
+	if s.GamesPlayed[p] == 0 {
+		return 0
+	}
+	return s.TimeUsed[p] / float64(s.GamesPlayed[p])
+}
+
+// AverageCPUTime returns the average CPU time used by player p per game.
+func (s *Stats) AverageCPUTime(p int) float64 {
+	if s.GamesPlayed[p] == 0 {
+		return 0
+	}
+	return s.CPUTimeUsed[p] / float64(s.GamesPlayed[p])
+}
+
+type IntPair struct {
+	first, second int
+}
+
+type IntPairSlice []IntPair
+
+// Functions needed to satisfy sort.Interface:
+func (ips IntPairSlice) Len() int {
+	return len(ips)
+}
+func (ips IntPairSlice) Less(i, j int) bool {
+	return ips[i].first < ips[j].first ||
+		(ips[i].first == ips[j].first && ips[i].second < ips[j].second)
+}
+func (ips IntPairSlice) Swap(i, j int) {
+	ips[i], ips[j] = ips[j], ips[i]
+}
+
+func (ips IntPairSlice) Reverse() {
+	for i, j := 0, len(ips)-1; i < j; i, j = i+1, j-1 {
+		ips.Swap(i, j)
+	}
+}
+
+// Ranking returns the indices of players ordered by total points (highest
+// first). Players with equal points are ordered by index.
+func (s *Stats) Ranking() []int {
+	pointsPlayers := make(IntPairSlice, len(s.Players))
+	for i := range pointsPlayers {
+		pointsPlayers[i] = IntPair{s.TotalPoints[i], -i}
+	}
+	sort.Sort(pointsPlayers)
+	pointsPlayers.Reverse()
+	ranking := make([]int, len(pointsPlayers))
+	for i, ip := range pointsPlayers {
+		ranking[i] = -ip.second
+	}
+	return ranking
+}
+
+// Standing is a player's entry in the tournament standings.
+type Standing struct {
+	Rank   int     `json:"rank"`
+	Player string  `json:"player"`
+	Points int     `json:"points"`
+	Won    int     `json:"won"`
+	Tied   int     `json:"tied"`
+	Lost   int     `json:"lost"`
+	Failed int     `json:"failed"`
+	Score  float64 `json:"score"`  // fraction of games won, counting ties as half
+	Margin float64 `json:"margin"` // margin of the 95% confidence interval of Score
+
+	// Likelihood of superiority over the player ranked next, if they won any
+	// games against each other.
+	LOS *float64 `json:"los,omitempty"`
+}
+
+// Pairing summarizes the games between a player and one of its opponents.
+type Pairing struct {
+	Player        int `json:"player"`   // 0-based player index
+	Opponent      int `json:"opponent"` // 0-based player index
+	Games         int `json:"games"`
+	Won           int `json:"won"`
+	Tied          int `json:"tied"`
+	Lost          int `json:"lost"`
+	Score         int `json:"score"`          // total score of the player
+	OpponentScore int `json:"opponent_score"` // total score of the opponent
+}
+
+// Pairings returns the results of each player against each opponent it
+// played, in player order.
+func (s *Stats) Pairings() []Pairing {
+	pairings := []Pairing{}
+	for p := range s.Players {
+		for q := range s.Players {
+			if games := s.PairGames[p][q]; p != q && games > 0 {
+				won, lost := s.WinLoss[p][q], s.WinLoss[q][p]
+				pairings = append(pairings, Pairing{p, q, games, won, games - won - lost, lost,
+					s.PairScore[p][q], s.PairScore[q][p]})
+			}
+		}
+	}
+	return pairings
+}
+
+// Standings returns the players' standings, ordered by rank.
+func (s *Stats) Standings() []Standing {
+	var standings []Standing
+	ranking := s.Ranking()
+	for i, p := range ranking {
+		st := Standing{Rank: i + 1, Player: s.Players[p], Points: s.TotalPoints[p],
+			Won: s.GamesWon[p], Tied: s.GamesTied[p], Lost: s.GamesLost[p], Failed: s.GamesFailed[p]}
+		st.Score, st.Margin = s.ScoreRate(p)
+		if i+1 < len(ranking) {
+			if los, ok := s.LOS(p, ranking[i+1]); ok {
+				st.LOS = &los
+			}
+		}
+		standings = append(standings, st)
+	}
+	return standings
+}
@@ -0,0 +1,465 @@
+// Package tournament schedules and plays tournaments between several players,
+// and summarizes their results.
+package tournament
+
+import (
+	"arbiter/game"
+	"arbiter/match"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options controls how a tournament is played.
+type Options struct {
+	Match match.Options
+
+	LogPath     string // prefix of game log files, if not empty
+	MsgPath     string // prefix of player message files, or "-" for stderr
+	DebugPath   string // prefix of debug log files (see match.Options.DebugPath), if not empty
+	Compress    bool   // compress game and message logs with gzip
+	LogJSON     bool   // write the result of each game as JSON next to its game log (see GameSummary)
+	Quiet       bool   // don't print results of individual games
+	Color       bool   // highlight results of individual games with ANSI colors
+	Progress    bool   // print the progress of the tournament to stderr
+	RunId       string // identifier of the run, which prefixes game identifiers
+	Coordinator string // address to listen on for workers, if not empty
+	Token       string // token that workers and API clients authenticate with (see RunWorker and Serve)
+	Webhook     string // URL to post results to, if not empty
+
+	// Chat services to post round summaries, upsets and the final standings
+	// to (see ReadNotifiers).
+	Notifiers []*Notifier
+
+	// Games to play instead of the automatic schedule, if not nil.
+	Fixtures []Fixture
+
+	// Restricts which pairings of the automatic schedule are played.
+	Pairings PairingFilter
+
+	// Positions to start games from (see game.Positioner), if not empty. The
+	// scheduled games are played from each of them (see SchedulePositions).
+	Positions []string
+
+	// In arena mode, games are scheduled one at a time, until ctx is done or
+	// ArenaTime (if not 0) has passed, instead of playing a fixed number of
+	// rounds (see nextArenaMatch).
+	Arena     bool
+	ArenaTime time.Duration
+
+	// With adaptive scheduling, the scheduled number of games is played one
+	// at a time, like in arena mode, but between the players whose relative
+	// strength is the least clear (see nextArenaMatch).
+	Adaptive bool
+
+	// Stops the tournament early once its outcome is decided.
+	EarlyStop EarlyStop
+
+	// What happens when a player fails to start (see StartFailurePolicies):
+	// with "play" (or ""), the game is played with random moves for it. With
+	// "skip-pairing", the game and the remaining games between the same
+	// players are not played. With "abort", the game is not played, and the
+	// tournament stops.
+	StartFailure string
+
+	// OnResult, if not nil, is called with the result of each finished game.
+	OnResult func(m Match, res match.Result)
+}
+
+// StartFailurePolicies lists the valid values of Options.StartFailure.
+var StartFailurePolicies = []string{"play", "skip-pairing", "abort"}
+
+// ValidStartFailurePolicy returns whether policy is a valid start failure
+// policy.
+func ValidStartFailurePolicy(policy string) bool {
+	for _, p := range StartFailurePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Match describes a single game to be played as part of a tournament.
+type Match struct {
+	Id       int       // 0-based game index
+	Round    int       // 0-based round index
+	Players  [2]int    // 0-based player indices
+	Commands [2]string // player commands
+	GameId   string    // unique identifier of the game (see Options.RunId)
+	Games    int       // number of games in the tournament, or 0 if unknown (in arena mode)
+	Seed     *int64    // seed of the game (see match.Options.GameSeed), or nil for the run's seed plus Id
+	Position string    // position the game starts from (see Options.Positions), or "" for the usual one
+}
+
+// Schedule returns the list of matches to be played in a tournament, leaving
+// out the pairings that filter doesn't allow.
+func Schedule(commands []string, rounds int, firstOnly bool, filter PairingFilter) []Match {
+	var matches []Match
+schedule:
+	for r := 0; r < rounds; r++ {
+		for i := range commands {
+			for j := range commands {
+				if i != j && filter.Allows(commands[i], commands[j]) {
+					matches = append(matches, Match{Id: len(matches), Round: r,
+						Players: [2]int{i, j}, Commands: [2]string{commands[i], commands[j]}})
+					if firstOnly {
+						break schedule
+					}
+				}
+			}
+		}
+	}
+	for i := range matches {
+		matches[i].Games = len(matches)
+	}
+	return matches
+}
+
+// Vars returns the variables that can be used in player commands and log paths
+// for the match (see match.ExpandVars), for each of the players:
+//
+//	{game}      1-based game number
+//	{games}     number of games in the tournament, if known
+//	{round}     1-based round number
+//	{seed}      random seed of the run
+//	{color}     "first" or "second"
+//	{opponent}  command or engine name of the opponent
+func (m Match) Vars(opts *Options) [2]map[string]string {
+	var vars [2]map[string]string
+	for i := range vars {
+		vars[i] = map[string]string{
+			"game":     strconv.Itoa(m.Id + 1),
+			"round":    strconv.Itoa(m.Round + 1),
+			"seed":     strconv.FormatInt(opts.Match.Seed, 10),
+			"color":    [2]string{"first", "second"}[i],
+			"opponent": m.Commands[1-i],
+		}
+		if m.Games > 0 {
+			vars[i]["games"] = strconv.Itoa(m.Games)
+		}
+	}
+	return vars
+}
+
+// logFile returns the path of a log file, given a path prefix that may
+// contain variables, and the suffix to append to it. The directory containing
+// the file is created if the prefix contained variables.
+func logFile(prefix string, vars map[string]string, suffix string) string {
+	path := match.ExpandVars(prefix, vars) + suffix
+	if path != prefix+suffix {
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			slog.Error("couldn't create log directory", "error", err)
+		}
+	}
+	return path
+}
+
+// logSuffix returns the file name extension of game and message logs.
+func (opts *Options) logSuffix() string {
+	if opts.Compress {
+		return ".log.gz"
+	}
+	return ".log"
+}
+
+// PlayMatch plays a scheduled match, writing game, message and debug logs if
+// desired. The log paths may contain the variables returned by Match.Vars; the
+// game and debug log paths can only use those that are the same for both
+// players.
+func PlayMatch(ctx context.Context, opts *Options, m Match) match.Result {
+	vars := m.Vars(opts)
+	gameVars := map[string]string{"game": vars[0]["game"], "games": vars[0]["games"],
+		"round": vars[0]["round"], "seed": vars[0]["seed"]}
+	logFilePath := ""
+	if opts.LogPath != "" {
+		logFilePath = logFile(opts.LogPath, gameVars, fmt.Sprintf("%04d", m.Id+1)+opts.logSuffix())
+	}
+	msgFilePath := [2]string{}
+	if opts.MsgPath != "" {
+		if opts.MsgPath == "-" {
+			msgFilePath[0] = "-"
+			msgFilePath[1] = "-"
+		} else {
+			msgFilePath[0] = logFile(opts.MsgPath, vars[0], fmt.Sprintf("%04d.1", m.Id+1)+opts.logSuffix())
+			msgFilePath[1] = logFile(opts.MsgPath, vars[1], fmt.Sprintf("%04d.2", m.Id+1)+opts.logSuffix())
+		}
+	}
+	matchOpts := opts.Match
+	matchOpts.Vars = vars
+	matchOpts.GameSeed = opts.Match.Seed + int64(m.Id)
+	if m.Seed != nil {
+		matchOpts.GameSeed = *m.Seed
+	}
+	matchOpts.GameId = m.GameId
+	matchOpts.GameNumber, matchOpts.Games = m.Id+1, m.Games
+	matchOpts.SkipUnstarted = opts.StartFailure == "skip-pairing" || opts.StartFailure == "abort"
+	if opts.DebugPath != "" {
+		matchOpts.DebugPath = logFile(opts.DebugPath, gameVars, fmt.Sprintf("%04d", m.Id+1)+opts.logSuffix())
+	}
+	matchOpts.Events = opts.Match.Events.WithGame(m.Id+1, m.GameId)
+	if m.Position != "" {
+		g, err := game.WithPosition(opts.Match.Game, m.Position)
+		if err != nil {
+			slog.Error("couldn't set up position", "position", m.Position, "error", err)
+		} else {
+			matchOpts.Game = g
+		}
+	}
+	res := match.Run(ctx, &matchOpts, m.Players, m.Commands, logFilePath, msgFilePath)
+	res.Position = m.Position
+	if opts.LogPath != "" && opts.LogJSON {
+		path := logFile(opts.LogPath, gameVars, fmt.Sprintf("%04d.json", m.Id+1))
+		if err := writeResultJSON(path, m, res); err != nil {
+			slog.Error("couldn't write game result", "error", err)
+		}
+	}
+	return res
+}
+
+// writeResultJSON writes the result of a match to a file as a GameSummary,
+// with the players listed by the sides they ended up playing.
+func writeResultJSON(path string, m Match, res match.Result) error {
+	if res.Swapped {
+		m.Commands[0], m.Commands[1] = m.Commands[1], m.Commands[0]
+	}
+	data, err := json.MarshalIndent(GameSummary{m.Id + 1, m.Commands, res}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0666)
+}
+
+// printResult prints a line of the results table. The winner is shown in
+// upper case, or in bold green if color is set, in which case failures are
+// shown in red, or in yellow if the player exceeded a time limit.
+func printResult(m Match, res match.Result, color bool) {
+	var player, failed [2]string
+	var reasons []string
+	for i := range player {
+		player[i] = fmt.Sprintf("%-30s", shorten(m.Commands[i], 30))
+		failed[i] = fmt.Sprintf("%-3s", toYesNo(res.Failed[i]))
+		if res.Score[i] > res.Score[1-i] {
+			if color {
+				player[i] = colorize(player[i], bold+green)
+			} else {
+				player[i] = strings.ToUpper(player[i])
+			}
+		}
+		if res.Code[i] != "" {
+			reasons = append(reasons, fmt.Sprintf("P%d %s", i+1, res.Code[i]))
+		}
+		if color && res.TimedOut[i] {
+			failed[i] = colorize(failed[i], yellow)
+		} else if color && res.Failed[i] {
+			failed[i] = colorize(failed[i], red)
+		}
+	}
+	if res.NotPlayed {
+		reasons = append(reasons, "not played")
+	}
+	fmt.Printf(
+		"%4d %s %s  %2d %2d  %3d %3d  %s %s  %7.3fs %7.3fs  %s\n",
+		m.Id+1, player[0], player[1],
+		res.Score[0], res.Score[1],
+		res.Points[0], res.Points[1],
+		failed[0], failed[1],
+		res.Time[0], res.Time[1],
+		strings.Join(reasons, ", "))
+}
+
+// NewRunId returns a random identifier for a run of the arbiter, which is
+// unlikely to be the same as that of any other run.
+func NewRunId() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Run plays a tournament of the given number of rounds between the players
+// given by commands, in which each player plays each other player twice per
+// round (once as the first player and once as the second player). If firstOnly
+// is true, only the first game is played. If opts.Fixtures is set, the given
+// games are played in each round instead, and if opts.Arena is set, games are
+// played until the tournament is stopped. With opts.Adaptive, the scheduled
+// number of games is played, but between the players whose relative strength
+// is the least clear. Returns the results of all games
+// that were finished, which are fewer than scheduled if ctx is done before the
+// tournament ends.
+func Run(ctx context.Context, opts *Options, commands []string, rounds int, firstOnly bool) []match.Result {
+	if !opts.Quiet {
+		fmt.Printf(" Id             Player 1                       Player 2             Score   Points  Failed       Time used     Reason\n")
+		fmt.Printf("---- ------------------------------ ------------------------------  -----  -------  -------  -----------------  ------\n")
+	}
+
+	var matches []Match
+	switch {
+	case opts.Arena:
+		// Matches are scheduled as games finish.
+	case opts.Fixtures != nil:
+		matches = ScheduleFixtures(commands, opts.Fixtures, rounds)
+	default:
+		matches = Schedule(commands, rounds, firstOnly, opts.Pairings)
+	}
+	if len(opts.Positions) > 0 {
+		matches = SchedulePositions(matches, opts.Positions)
+	}
+	runId := opts.RunId
+	if runId == "" {
+		runId = NewRunId()
+	}
+	for i := range matches {
+		if matches[i].GameId == "" {
+			matches[i].GameId = fmt.Sprintf("%s-%04d", runId, matches[i].Id+1)
+		}
+	}
+	var results []match.Result
+	start := time.Now()
+	var notify *notifications
+	if len(opts.Notifiers) > 0 {
+		scheduled := matches
+		if opts.Adaptive {
+			scheduled = nil // games are scheduled one at a time, not in rounds
+		}
+		notify = newNotifications(opts.Notifiers, commands, scheduled)
+	}
+	report := func(m Match, res match.Result) {
+		if res.Interrupted {
+			return
+		}
+		if res.Swapped {
+			// Report the players by the sides they ended up playing.
+			m.Players[0], m.Players[1] = m.Players[1], m.Players[0]
+			m.Commands[0], m.Commands[1] = m.Commands[1], m.Commands[0]
+		}
+		if !opts.Quiet {
+			printResult(m, res, opts.Color)
+		}
+		results = append(results, res)
+		if opts.OnResult != nil {
+			opts.OnResult(m, res)
+		}
+		progress := newProgress(len(results), len(matches), time.Since(start))
+		if opts.Progress {
+			fmt.Fprintln(os.Stderr, "Progress:", progress)
+		}
+		if opts.Match.Events != nil {
+			stats := ComputeStats(commands, results)
+			opts.Match.Events.Emit(match.Event{Type: match.StandingsUpdated,
+				Game: m.Id + 1, GameId: m.GameId, Standings: stats.Standings(),
+				Pairings: stats.Pairings(), Progress: progress})
+		}
+		if opts.Webhook != "" {
+			postWebhook(opts.Webhook, WebhookPayload{Event: "game_finished",
+				Game: m.Id + 1, GameId: m.GameId, Players: m.Commands[:], Result: &res})
+		}
+		if notify != nil {
+			notify.gameFinished(m, res, results)
+		}
+	}
+	// decided returns whether the tournament can stop early, given the
+	// maximum number of games each player has left, or nil if unknown.
+	stopReason := ""
+	decided := func(remaining []int) bool {
+		if opts.EarlyStop == (EarlyStop{}) {
+			return false
+		}
+		stopReason = opts.EarlyStop.reason(opts.Match.Game, ComputeStats(commands, results), remaining)
+		return stopReason != ""
+	}
+	// Pairings that are not played anymore, because a player failed to
+	// start, by player indices in either order (see Options.StartFailure).
+	skipped := map[[2]int]bool{}
+
+	// play plays and reports m, unless its pairing is skipped. Returns whether
+	// the tournament must stop because a player failed to start.
+	play := func(m Match) bool {
+		var res match.Result
+		if skipped[m.Players] {
+			res = match.Result{GameId: m.GameId, Player: m.Players, NotPlayed: true}
+		} else {
+			res = PlayMatch(ctx, opts, m)
+		}
+		report(m, res)
+		if res.NotPlayed && opts.StartFailure == "skip-pairing" {
+			skipped[m.Players] = true
+			skipped[[2]int{m.Players[1], m.Players[0]}] = true
+		}
+		if res.NotPlayed && opts.StartFailure == "abort" {
+			stopReason = "a player failed to start"
+			return true
+		}
+		return false
+	}
+
+	if opts.Arena || opts.Adaptive {
+		var deadline time.Time
+		if opts.Arena && opts.ArenaTime > 0 {
+			deadline = start.Add(opts.ArenaTime)
+		}
+		for id := 0; ctx.Err() == nil && (opts.Arena || id < len(matches)) &&
+			(deadline.IsZero() || time.Now().Before(deadline)); id++ {
+			m, ok := nextArenaMatch(commands, opts.Pairings, opts.Adaptive, results, id)
+			if !ok {
+				break
+			}
+			if !opts.Arena {
+				m.Games = len(matches)
+			}
+			m.GameId = fmt.Sprintf("%s-%04d", runId, m.Id+1)
+			if play(m) {
+				break
+			}
+			var remaining []int
+			if !opts.Arena {
+				remaining = make([]int, len(commands))
+				for i := range remaining {
+					remaining[i] = len(matches) - id - 1
+				}
+			}
+			if decided(remaining) {
+				break
+			}
+		}
+	} else if opts.Coordinator != "" {
+		if err := runCoordinator(ctx, opts, matches, report); err != nil {
+			slog.Error("coordinator failed", "error", err)
+		}
+	} else {
+		for i, m := range matches {
+			if ctx.Err() != nil {
+				break
+			}
+			if play(m) || decided(remainingGames(len(commands), matches[i+1:])) {
+				break
+			}
+		}
+	}
+	if !opts.Quiet {
+		fmt.Printf("---- ------------------------------ ------------------------------  -----  -------  -------  -----------------  ------\n")
+	}
+	if stopReason != "" {
+		// The standings are only based on the games played so far.
+		slog.Warn("stopped early; results are partial", "reason", stopReason,
+			"games", len(results), "scheduled", len(matches))
+	}
+	if opts.Webhook != "" {
+		stats := ComputeStats(commands, results)
+		postWebhook(opts.Webhook, WebhookPayload{Event: "tournament_finished",
+			Players: commands, Standings: stats.Standings(), Pairings: stats.Pairings()})
+	}
+	if notify != nil {
+		notify.finished(results, stopReason)
+	}
+	return results
+}
@@ -0,0 +1,107 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"math"
+)
+
+// TrueSkillRating is a player's rating in the TrueSkill system: the skill is
+// estimated to be Mu, with standard deviation Sigma.
+type TrueSkillRating struct {
+	Mu    float64 `json:"mu"`
+	Sigma float64 `json:"sigma"`
+}
+
+// Conservative returns a conservative estimate of the skill, which is lower
+// than the actual skill with 99% confidence. Ranking players by this estimate
+// keeps players that played few games from ranking high by luck.
+func (r TrueSkillRating) Conservative() float64 {
+	return r.Mu - 3*r.Sigma
+}
+
+const (
+	trueSkillMu      = 25.0                 // initial skill
+	trueSkillSigma   = trueSkillMu / 3      // initial standard deviation
+	trueSkillBeta    = trueSkillSigma / 2   // skill difference that gives a 76% chance of winning
+	trueSkillTau     = trueSkillSigma / 100 // dynamic factor, which keeps sigma from reaching 0
+	trueSkillDrawing = 0.10                 // probability of a tie between equal players
+)
+
+// ComputeTrueSkill rates n players with the TrueSkill system, by updating
+// their ratings after each of the given games, in the order they were played.
+// Because each game only changes the ratings of the players involved, based
+// on the uncertainty of both, this works well even if not every player plays
+// every other player.
+func ComputeTrueSkill(n int, results []match.Result) []TrueSkillRating {
+	ratings := make([]TrueSkillRating, n)
+	for i := range ratings {
+		ratings[i] = TrueSkillRating{trueSkillMu, trueSkillSigma}
+	}
+	margin := normalQuantile((trueSkillDrawing+1)/2) * math.Sqrt2 * trueSkillBeta
+	for _, result := range results {
+		a, b := &ratings[result.Player[0]], &ratings[result.Player[1]]
+		if result.Score[1] > result.Score[0] {
+			a, b = b, a // a is the winner
+		}
+		varA := a.Sigma*a.Sigma + trueSkillTau*trueSkillTau
+		varB := b.Sigma*b.Sigma + trueSkillTau*trueSkillTau
+		c := math.Sqrt(2*trueSkillBeta*trueSkillBeta + varA + varB)
+		t, e := (a.Mu-b.Mu)/c, margin/c
+		var v, w float64
+		if result.Score[0] == result.Score[1] {
+			v, w = trueSkillDraw(t, e)
+		} else {
+			v, w = trueSkillWin(t, e)
+		}
+		a.Mu += varA / c * v
+		b.Mu -= varB / c * v
+		a.Sigma = math.Sqrt(varA * (1 - varA/(c*c)*w))
+		b.Sigma = math.Sqrt(varB * (1 - varB/(c*c)*w))
+	}
+	return ratings
+}
+
+// trueSkillWin returns the factors by which the mean and variance of the
+// ratings change after a win, given the difference in skill t and the draw
+// margin e, both relative to the total uncertainty.
+func trueSkillWin(t, e float64) (v, w float64) {
+	x := t - e
+	if d := normalCDF(x); d > 0 {
+		v = normalPDF(x) / d
+	} else {
+		v = -x
+	}
+	return v, clamp(v*(v+x), 0, 1)
+}
+
+// trueSkillDraw is like trueSkillWin, but for a tie.
+func trueSkillDraw(t, e float64) (v, w float64) {
+	sign := 1.0
+	if t < 0 {
+		t, sign = -t, -1
+	}
+	a, b := e-t, -e-t
+	d := normalCDF(a) - normalCDF(b)
+	if d <= 0 {
+		return a * sign, 1
+	}
+	v = (normalPDF(b) - normalPDF(a)) / d
+	w = v*v + (a*normalPDF(a)-b*normalPDF(b))/d
+	return v * sign, clamp(w, 0, 1)
+}
+
+func normalPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normalCDF(x float64) float64 {
+	return math.Erfc(-x/math.Sqrt2) / 2
+}
+
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+func clamp(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, x))
+}
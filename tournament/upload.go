@@ -0,0 +1,236 @@
+package tournament
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Uploader uploads run directories to object storage with the S3 API, which
+// Amazon S3, MinIO and Google Cloud Storage (with HMAC keys) support, so that
+// game records survive machines with ephemeral disks.
+type Uploader struct {
+	Endpoint string // URL of the storage service, e.g. "https://storage.googleapis.com"
+	Region   string // region of the bucket, e.g. "us-east-1"
+	Bucket   string
+	Prefix   string // prefix of the object names, without trailing '/'
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // for temporary credentials, if not empty
+}
+
+// Number of times uploading a file is tried before giving up.
+const uploadAttempts = 3
+
+var uploadClient = http.Client{Timeout: 5 * time.Minute}
+
+// NewUploader returns an uploader for a destination given as
+// "s3://<bucket>/<prefix>" or "gs://<bucket>/<prefix>". Credentials and the
+// region are taken from the environment variables AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION, like the AWS
+// tools do, and the endpoint from AWS_ENDPOINT_URL (e.g. for MinIO), which
+// defaults to Amazon S3 for s3:// and to Google Cloud Storage for gs://.
+func NewUploader(dest string) (*Uploader, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Host == "" || (u.Scheme != "s3" && u.Scheme != "gs") {
+		return nil, fmt.Errorf("invalid upload destination: %s (expected s3://<bucket>/<prefix> or gs://<bucket>/<prefix>)", dest)
+	}
+	up := &Uploader{
+		Region:       os.Getenv("AWS_REGION"),
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:     strings.TrimSuffix(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+	}
+	if up.Region == "" {
+		up.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if up.Region == "" {
+		up.Region = "us-east-1"
+		if u.Scheme == "gs" {
+			up.Region = "auto"
+		}
+	}
+	if up.Endpoint == "" {
+		up.Endpoint = "https://s3." + up.Region + ".amazonaws.com"
+		if u.Scheme == "gs" {
+			up.Endpoint = "https://storage.googleapis.com"
+		}
+	}
+	if up.AccessKey == "" || up.SecretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to upload")
+	}
+	return up, nil
+}
+
+// UploadDir uploads all files in dir, under the uploader's prefix followed by
+// the name of dir, e.g. "runs/2024-05-01-120000/games/0001.log". It stops at
+// the first file that can't be uploaded.
+func (up *Uploader) UploadDir(ctx context.Context, dir string) error {
+	base := filepath.Base(filepath.Clean(dir))
+	files := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := path.Join(up.Prefix, base, filepath.ToSlash(rel))
+		if err := up.uploadFile(ctx, p, key); err != nil {
+			return fmt.Errorf("%s: %s", p, err)
+		}
+		files++
+		return nil
+	})
+	if err == nil {
+		slog.Info("uploaded run directory", "dir", dir, "files", files,
+			"dest", up.Endpoint+"/"+up.Bucket+"/"+path.Join(up.Prefix, base))
+	}
+	return err
+}
+
+// uploadFile uploads a file as the object with the given name, retrying if
+// it fails.
+func (up *Uploader) uploadFile(ctx context.Context, name, key string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err = up.put(ctx, key, data, contentType(name))
+		if err == nil || attempt == uploadAttempts || ctx.Err() != nil {
+			return err
+		}
+		slog.Warn("upload failed; retrying", "file", name, "error", err)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+// put stores data as the object with the given name.
+func (up *Uploader) put(ctx context.Context, key string, data []byte, ctype string) error {
+	req, err := http.NewRequestWithContext(ctx, "PUT", up.Endpoint+"/"+up.Bucket+"/"+uriEncode(key, false), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ctype)
+	if up.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", up.SessionToken)
+	}
+	sum := sha256.Sum256(data)
+	signV4(req, hex.EncodeToString(sum[:]), up.AccessKey, up.SecretKey, up.Region, time.Now())
+	resp, err := uploadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(body.String()))
+	}
+	return nil
+}
+
+// contentType returns the MIME type of the files in run directories.
+func contentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(name, ".json"):
+		return "application/json"
+	case strings.HasSuffix(name, ".html"):
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// signV4 signs an S3 request with AWS Signature Version 4, given the SHA-256
+// hash of the payload in hex. All headers of the request are signed.
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	var query []string
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			query = append(query, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(query)
+	canonicalPath := req.URL.EscapedPath()
+	if canonicalPath == "" {
+		canonicalPath = "/"
+	}
+	canonicalRequest := strings.Join([]string{req.Method, canonicalPath, strings.Join(query, "&"),
+		canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode percent-encodes s as AWS requires: all bytes except letters,
+// digits and "-._~" are encoded, and so is '/' if encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
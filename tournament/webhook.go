@@ -0,0 +1,49 @@
+package tournament
+
+import (
+	"arbiter/match"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WebhookPayload is the body of the requests posted to Options.Webhook.
+type WebhookPayload struct {
+	Event     string        `json:"event"` // "game_finished" or "tournament_finished"
+	Game      int           `json:"game,omitempty"`
+	GameId    string        `json:"game_id,omitempty"`
+	Players   []string      `json:"players,omitempty"`
+	Result    *match.Result `json:"result,omitempty"`
+	Standings []Standing    `json:"standings,omitempty"`
+	Pairings  []Pairing     `json:"pairings,omitempty"`
+}
+
+var webhookClient = http.Client{Timeout: 10 * time.Second}
+
+// postWebhook posts the payload as JSON to the given URL. Errors are reported
+// but otherwise ignored, so that an unavailable server doesn't disrupt the
+// tournament.
+func postWebhook(url string, payload WebhookPayload) {
+	postJSON(url, payload)
+}
+
+// postJSON posts v as JSON to the given URL, reporting errors like
+// postWebhook.
+func postJSON(url string, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't encode webhook payload", "error", err)
+		return
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("webhook failed", "url", url, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		slog.Warn("webhook failed", "url", url, "status", resp.Status)
+	}
+}
@@ -0,0 +1,211 @@
+// Package tui implements a live terminal viewer for tournaments, which shows
+// the board, clocks and move list of the game in progress, and the standings
+// between games.
+package tui
+
+import (
+	"arbiter/game"
+	"arbiter/match"
+	"arbiter/tournament"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI escape sequences used to control the terminal:
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // switch to alternate screen, hide cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l" // show cursor, switch back
+	clearScreen = "\x1b[H\x1b[2J"
+	bold        = "\x1b[1m"
+	reset       = "\x1b[0m"
+)
+
+// maxMoveRows is the number of rows of the move list that are displayed.
+const maxMoveRows = 20
+
+// Viewer renders tournament events to a terminal.
+type Viewer struct {
+	w    io.Writer
+	game game.Game
+
+	mu        sync.Mutex
+	playing   bool
+	gameId    int
+	players   []string
+	state     game.GameState
+	pending   [2]interface{} // moves received so far in a simultaneous turn
+	moves     []string
+	clocks    [2]float64
+	moveStart time.Time // when the player to move started thinking
+	failed    [2]bool
+	last      string // one-line summary of the last finished game
+	standings []tournament.Standing
+	progress  string // progress of the tournament, if known
+}
+
+// New returns a viewer for games of g that writes to w.
+func New(w io.Writer, g game.Game) *Viewer {
+	return &Viewer{w: w, game: g}
+}
+
+// Handle updates the viewer with an event. It is meant to be passed to
+// match.EventLog.AddHandler.
+func (v *Viewer) Handle(e match.Event) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch e.Type {
+	case match.GameStarted:
+		v.playing = true
+		v.gameId = e.Game
+		v.players = e.Players
+		g := game.WithDeal(v.game, e.Seed)
+		if pg, err := game.WithPosition(g, e.Position); err == nil {
+			g = pg
+		}
+		v.state = g.CreateState()
+		v.pending = [2]interface{}{}
+		v.moves = nil
+		v.clocks = [2]float64{}
+		v.failed = [2]bool{}
+		v.moveStart = e.Time
+	case match.MovePlayed:
+		move, ok := v.game.ParseMove(e.Move)
+		if ss, simultaneous := v.state.(game.SimultaneousState); ok && simultaneous && ss.Simultaneous() {
+			v.pending[e.Player-1] = move
+			if v.pending[0] != nil && v.pending[1] != nil {
+				ss.ExecuteBoth(v.pending)
+				v.pending = [2]interface{}{}
+			}
+		} else if ok && v.state != nil {
+			v.state.Execute(move)
+		}
+		v.moves = append(v.moves, e.Move)
+		v.clocks[e.Player-1] += e.Elapsed
+		v.moveStart = e.Time
+	case match.PlayersSwapped:
+		v.players = e.Players
+		v.clocks[0], v.clocks[1] = v.clocks[1], v.clocks[0]
+		v.failed[0], v.failed[1] = v.failed[1], v.failed[0]
+	case match.PlayerFailed:
+		v.failed[e.Player-1] = true
+	case match.GameFinished:
+		v.playing = false
+		if e.Result != nil && len(v.players) == 2 {
+			v.last = fmt.Sprintf("Game %d: %s vs %s, %d - %d", v.gameId,
+				v.players[0], v.players[1], e.Result.Score[0], e.Result.Score[1])
+		}
+	case match.StandingsUpdated:
+		if standings, ok := e.Standings.([]tournament.Standing); ok {
+			v.standings = standings
+		}
+		if progress, ok := e.Progress.(tournament.Progress); ok {
+			v.progress = progress.String()
+		}
+	}
+}
+
+// Run redraws the screen periodically until ctx is done, then restores the
+// terminal.
+func (v *Viewer) Run(ctx context.Context) {
+	fmt.Fprint(v.w, enterScreen)
+	defer fmt.Fprint(v.w, leaveScreen)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		v.draw()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (v *Viewer) draw() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var b bytes.Buffer
+	b.WriteString(clearScreen)
+	if v.playing {
+		v.drawGame(&b)
+	} else {
+		v.drawStandings(&b)
+	}
+	v.w.Write(b.Bytes())
+}
+
+func (v *Viewer) drawGame(b *bytes.Buffer) {
+	fmt.Fprintf(b, "%sGame %d%s\r\n", bold, v.gameId, reset)
+	if v.progress != "" {
+		b.WriteString("Finished: " + v.progress + "\r\n")
+	}
+	b.WriteString("\r\n")
+	var moving [2]bool // whether each player is thinking
+	if v.state != nil && game.IsSimultaneous(v.state) {
+		moving = [2]bool{v.pending[0] == nil, v.pending[1] == nil}
+	} else if v.state != nil && !v.state.Over() {
+		moving[v.state.Next()] = true
+	}
+	for i, player := range v.players {
+		clock := v.clocks[i]
+		marker := " "
+		if i < 2 && moving[i] {
+			clock += time.Since(v.moveStart).Seconds()
+			marker = ">"
+		}
+		status := ""
+		if v.failed[i] {
+			status = " (failed)"
+		}
+		fmt.Fprintf(b, "%s Player %d: %-40s %8.3fs%s\r\n", marker, i+1, player, clock, status)
+	}
+	b.WriteString("\r\n")
+
+	var board bytes.Buffer
+	if v.state == nil || !game.RenderBoard(&board, v.state) {
+		board.WriteString("(board display not supported for this game)\n")
+	}
+	b.WriteString(strings.ReplaceAll(strings.TrimRight(board.String(), "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n\r\n")
+
+	fmt.Fprintf(b, "Moves (%d):\r\n", len(v.moves))
+	first := 0
+	if rows := (len(v.moves) + 1) / 2; rows > maxMoveRows {
+		first = 2 * (rows - maxMoveRows)
+	}
+	for i := first; i < len(v.moves); i += 2 {
+		fmt.Fprintf(b, "%4d. %-12s", i/2+1, v.moves[i])
+		if i+1 < len(v.moves) {
+			b.WriteString(v.moves[i+1])
+		}
+		b.WriteString("\r\n")
+	}
+}
+
+func (v *Viewer) drawStandings(b *bytes.Buffer) {
+	fmt.Fprintf(b, "%sStandings%s\r\n\r\n", bold, reset)
+	if v.last != "" {
+		b.WriteString(v.last + "\r\n")
+	}
+	if v.progress != "" {
+		b.WriteString("Finished: " + v.progress + "\r\n")
+	}
+	if v.last != "" || v.progress != "" {
+		b.WriteString("\r\n")
+	}
+	b.WriteString("No Player                         Points  Won Tied Lost Fail\r\n")
+	b.WriteString("-- ------------------------------ ------ ---- ---- ---- ----\r\n")
+	for _, s := range v.standings {
+		player := s.Player
+		if len(player) > 30 {
+			player = player[:28] + ".."
+		}
+		fmt.Fprintf(b, "%2d %-30s %6d %4d %4d %4d %4d\r\n",
+			s.Rank, player, s.Points, s.Won, s.Tied, s.Lost, s.Failed)
+	}
+}